@@ -1,44 +1,51 @@
 package main
 
 import (
+	"flag"
+	"go-todo/internal/billing"
 	"go-todo/internal/cli"
+	"go-todo/internal/config"
 	"go-todo/internal/db"
+	"go-todo/internal/mailer"
+	"go-todo/internal/migrations"
 	"go-todo/internal/repositories"
-	"go-todo/internal/server/cache"
+	"go-todo/internal/server/sessionstore"
 	"go-todo/internal/services"
 	"log"
-	"time"
-
-	"github.com/joho/godotenv"
+	"os"
 )
 
 func main() {
-	// Load environment variables from .env file
-	err := godotenv.Load("./configs/.env")
+	opts := cli.RegisterFlags(flag.CommandLine)
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
 	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-		// You can choose to handle the error here or exit the program.
+		log.Fatal(err)
 	}
 
-	db, err := db.Connect()
+	db, err := db.Connect(cfg.Database.Path)
 	if err != nil {
 		log.Fatalf("Failed to connect to datbase %v", err)
 	}
 	defer db.Close()
 
-	defaultExpiration := 5 * time.Minute
-	cleanupInterval := 10 * time.Minute
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations %v", err)
+	}
 
-	userCache := cache.NewUserCache(defaultExpiration, cleanupInterval)
-	todoCache := cache.NewTodoCache(defaultExpiration, cleanupInterval)
+	mail, err := mailer.FromConfig(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to configure mailer %v", err)
+	}
 
-	caches := &cache.Caches{
-		UserCache: userCache,
-		TodoCache: todoCache,
+	store, err := sessionstore.New(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to open session store %v", err)
 	}
 
-	command := cli.New(services.NewService(repositories.NewRepository(db), caches))
-	err = command.Execute()
+	command := cli.New(services.NewService(repositories.NewRepository(db), mail, billing.NewStripe(cfg.Stripe, nil), cfg), migrator, store, cfg)
+	err = command.Execute(opts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -1,38 +1,34 @@
 package main
 
 import (
-	"go-todo/internal/db"
+	"flag"
+	"fmt"
+	"go-todo/internal/billing"
+	"go-todo/internal/config"
+	database "go-todo/internal/db"
 	"go-todo/internal/handlers"
 	"go-todo/internal/logger"
+	"go-todo/internal/mailer"
+	"go-todo/internal/migrations"
 	"go-todo/internal/repositories"
 	"go-todo/internal/router"
 	"go-todo/internal/server"
-	"go-todo/internal/server/cache"
 	"go-todo/internal/server/renderer"
 	"go-todo/internal/server/sessionstore"
 	"go-todo/internal/services"
 	"html/template"
 	"log"
 	"os"
-	"time"
-
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load environment variables from .env file
-	err := godotenv.Load(".env")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
 	if err != nil {
-		log.Fatal("Error loading .env file:", err)
-		// You can choose to handle the error here or exit the program.
-	}
-
-	if os.Getenv("ENV") == "" || os.Getenv("PORT") == "" {
-		log.Fatal("Expected a PORT and ENV var")
+		log.Fatal(err)
 	}
 
 	var logLevel logger.LogLevel = 0
-	if os.Getenv("ENV") == "prod" {
+	if cfg.IsProd() {
 		logLevel = 1
 		logFile, err := logger.SetOutputToFile()
 		if err != nil {
@@ -43,36 +39,66 @@ func main() {
 
 	logr := logger.NewLogger(logLevel)
 
-	db, err := db.Connect()
+	db, err := database.Connect(cfg.Database.Path)
 	if err != nil {
 		log.Fatalf("could not connect to databse %v", err)
 	}
 	defer db.Close()
 
-	useSecureSession := os.Getenv("ENV") == "prod"
-	store, err := sessionstore.GetSessionStore(useSecureSession)
+	if !database.SupportsFTS5(db) {
+		log.Fatal("sqlite3 was built without FTS5, rebuild with -tags sqlite_fts5")
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("could not load migrations %v", err)
+	}
+
+	// refuse to run an older binary against a newer schema
+	if err = migrator.Check(); err != nil {
+		log.Fatal(err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		log.Fatalf("could not migrate database %v", err)
+	}
+	if applied > 0 {
+		logr.Info(fmt.Sprintf("Applied %d database migration(s)", applied))
+	}
+
+	store, err := sessionstore.New(cfg, db)
 	if err != nil {
 		log.Fatalf("could not connnect to session store %v", err)
 	}
 
+	stopSessionCleanup := sessionstore.StartCleanup(store, cfg.Sessions.CleanupInterval, logr)
+	defer stopSessionCleanup()
+
 	templateGlobPath := "./web/templates/**/*.html"
 	tmpl := template.Must(template.ParseGlob(templateGlobPath))
 
-	defaultExpiration := 5 * time.Minute
-	cleanupInterval := 10 * time.Minute
-
-	userCache := cache.NewUserCache(defaultExpiration, cleanupInterval)
-	todoCache := cache.NewTodoCache(defaultExpiration, cleanupInterval)
-
-	caches := &cache.Caches{UserCache: userCache, TodoCache: todoCache}
+	mail, err := mailer.FromConfig(cfg.Mail)
+	if err != nil {
+		log.Fatalf("could not configure mailer %v", err)
+	}
 
 	repository := repositories.NewRepository(db)
-	service := services.NewService(repository, caches)
+	service := services.NewService(repository, mail, billing.NewStripe(cfg.Stripe, nil), cfg)
+	stopStripeEventRetries := service.StartStripeEventRetries(cfg.Stripe.EventRetryInterval, logr)
+	defer stopStripeEventRetries()
+	stopDunning := service.StartDunning(cfg.Dunning.Interval, logr)
+	defer stopDunning()
+	stopOutbox := service.StartOutbox(cfg.Mail.OutboxInterval, logr)
+	defer stopOutbox()
+	stopTodoReminders := service.StartTodoReminders(cfg.Reminders.Interval, logr)
+	defer stopTodoReminders()
+
 	renderer := renderer.NewRenderer(tmpl)
-	handler := handlers.NewHandler(service, store, renderer, logr)
+	handler := handlers.NewHandler(service, store, renderer, logr, cfg)
 
 	r := router.NewRouter(handler)
-	if err = server.NewServer(r, logr).Serve(os.Getenv("PORT")); err != nil {
+	if err = server.NewServer(r, logr).Serve(cfg.Port); err != nil {
 		log.Fatal(err)
 	}
 }
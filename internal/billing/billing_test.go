@@ -0,0 +1,121 @@
+package billing
+
+import (
+	"encoding/json"
+	"go-todo/internal/config"
+	"go-todo/internal/models"
+	"strings"
+	"testing"
+)
+
+func newTestStripe(t *testing.T) (*Stripe, *Stub) {
+	stub := NewStub()
+	t.Cleanup(stub.Close)
+
+	return NewStripe(config.Stripe{APIKey: "sk_test_key", WebhookSecret: FakeWebhookSecret}, stub.Backends()), stub
+}
+
+func TestStripeCheckoutAndPortal(t *testing.T) {
+	s, stub := newTestStripe(t)
+
+	checkoutSession, err := s.NewCheckoutSession("user-1", "user@email.com", "price_test", 0, "http://localhost/success", "http://localhost/cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkoutSession.ID == "" || checkoutSession.URL == "" || checkoutSession.IsPaid() {
+		t.Fatalf("expected a new unpaid checkout session, got %+v", checkoutSession)
+	}
+
+	if _, err := stub.Fake.PayCheckoutSession(checkoutSession.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	paid, err := s.GetCheckoutSession(checkoutSession.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !paid.IsPaid() || paid.CustomerID == "" || paid.SubscriptionID == "" || paid.CustomerEmail != "user@email.com" || paid.UserID != "user-1" {
+		t.Fatalf("expected the paid checkout session with its customer and subscription, got %+v", paid)
+	}
+
+	trial, err := s.NewCheckoutSession("user-2", "trial@email.com", "price_test", 14, "http://localhost/success", "http://localhost/cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trial, err = stub.Fake.PayCheckoutSession(trial.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscription, err := stub.Fake.UpdateSubscription(trial.SubscriptionID, func(*models.Subscription) {})
+	if err != nil || subscription.Status != models.SubscriptionTrialing || subscription.UserID != "user-2" {
+		t.Errorf("expected the trial to reach stripe and start a trialing subscription, got %+v %v", subscription, err)
+	}
+
+	missing, err := s.GetCheckoutSession("cs_missing")
+	if err != nil || missing != nil {
+		t.Errorf("expected no checkout session for an unknown id, got %+v %v", missing, err)
+	}
+
+	portalURL, err := s.NewPortalSession(paid.CustomerID, "http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(portalURL, "return_url=http%3A%2F%2Flocalhost") {
+		t.Errorf("expected the portal to return to the site, got %s", portalURL)
+	}
+
+	if _, err := s.NewPortalSession("cus_missing", "http://localhost"); err == nil {
+		t.Error("expected a portal session for an unknown customer to fail")
+	}
+
+	subscriptions, err := s.ListSubscriptions(paid.CustomerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 1 || subscriptions[0].ID != paid.SubscriptionID || subscriptions[0].Status != models.SubscriptionActive ||
+		subscriptions[0].UserID != "user-1" || subscriptions[0].PriceID != "price_test" || subscriptions[0].CustomerID != paid.CustomerID {
+		t.Errorf("expected the customer's subscription, got %+v", subscriptions)
+	}
+}
+
+func TestStripeNeedsAPIKey(t *testing.T) {
+	stub := NewStub()
+	defer stub.Close()
+
+	s := NewStripe(config.Stripe{}, stub.Backends())
+	if _, err := s.NewCheckoutSession("user-1", "user@email.com", "price_test", 0, "http://localhost/success", "http://localhost/cancel"); err == nil {
+		t.Error("expected billing without an api key to fail")
+	}
+
+	s = NewStripe(config.Stripe{APIKey: "rk_wrong"}, stub.Backends())
+	if _, err := s.GetCheckoutSession("cs_test_1"); err == nil {
+		t.Error("expected the stub to refuse a key it does not know")
+	}
+}
+
+func TestConstructEventVerifiesSignature(t *testing.T) {
+	s, stub := newTestStripe(t)
+
+	payload, signature, err := stub.Fake.Event("customer.subscription.deleted", map[string]any{"id": "sub_1", "customer": "cus_1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := s.ConstructEvent(payload, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "customer.subscription.deleted" || event.ID == "" {
+		t.Errorf("expected the event back, got %+v", event)
+	}
+
+	var subscription struct{ Customer string }
+	if err := json.Unmarshal(event.Data, &subscription); err != nil || subscription.Customer != "cus_1" {
+		t.Errorf("expected the event's object as its data, got %s", event.Data)
+	}
+
+	tampered := []byte(strings.Replace(string(payload), "cus_1", "cus_2", 1))
+	if _, err := s.ConstructEvent(tampered, signature); err == nil {
+		t.Error("expected a tampered event to be refused")
+	}
+}
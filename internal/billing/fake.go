@@ -0,0 +1,233 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-todo/internal/models"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-go/v75"
+	"github.com/stripe/stripe-go/v75/webhook"
+)
+
+// FakeWebhookSecret is the secret Fake signs its webhook events with.
+const FakeWebhookSecret = "whsec_fake"
+
+// Fake is an in memory stand in for Stripe. Checkouts are paid for with
+// PayCheckoutSession, which starts a subscription, and webhooks are made
+// with Event and SubscriptionEvent, so the whole billing flow can run in
+// tests without a network.
+type Fake struct {
+	mu               sync.Mutex
+	nextID           int
+	checkoutSessions map[string]models.CheckoutSession
+	checkoutPrices   map[string]string // checkout session id to price id
+	checkoutTrials   map[string]int    // checkout session id to trial days
+	customers        map[string]string // customer id to email
+	subscriptions    map[string]models.Subscription
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		checkoutSessions: map[string]models.CheckoutSession{},
+		checkoutPrices:   map[string]string{},
+		checkoutTrials:   map[string]int{},
+		customers:        map[string]string{},
+		subscriptions:    map[string]models.Subscription{},
+	}
+}
+
+// newID returns an id like Stripe's with prefix, e.g. cs_test_1.
+func (f *Fake) newID(prefix string) string {
+	f.nextID++
+	return fmt.Sprintf("%s_test_%d", prefix, f.nextID)
+}
+
+func (f *Fake) NewCheckoutSession(userID, customerEmail, priceID string, trialDays int, successURL, cancelURL string) (*models.CheckoutSession, error) {
+	if customerEmail == "" || priceID == "" || successURL == "" || cancelURL == "" {
+		return nil, errors.New("a checkout session needs a customer email, price and urls")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := f.newID("cs")
+	checkoutSession := models.CheckoutSession{
+		ID:            id,
+		URL:           "https://checkout.stripe.test/pay/" + id,
+		UserID:        userID,
+		CustomerEmail: customerEmail,
+		PaymentStatus: models.PaymentStatusUnpaid,
+	}
+	f.checkoutSessions[id] = checkoutSession
+	f.checkoutPrices[id] = priceID
+	f.checkoutTrials[id] = trialDays
+	return &checkoutSession, nil
+}
+
+func (f *Fake) GetCheckoutSession(checkoutSessionID string) (*models.CheckoutSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkoutSession, ok := f.checkoutSessions[checkoutSessionID]
+	if !ok {
+		return nil, nil
+	}
+	return &checkoutSession, nil
+}
+
+func (f *Fake) NewPortalSession(customerID, returnURL string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.customers[customerID]; !ok {
+		return "", fmt.Errorf("No such customer: '%s'", customerID)
+	}
+	return "https://billing.stripe.test/session/" + f.newID("bps") + "?return_url=" + url.QueryEscape(returnURL), nil
+}
+
+func (f *Fake) ConstructEvent(payload []byte, signature string) (*models.BillingEvent, error) {
+	return constructEvent(payload, signature, FakeWebhookSecret)
+}
+
+func (f *Fake) ListSubscriptions(customerID string) ([]*models.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.customers[customerID]; !ok {
+		return nil, fmt.Errorf("No such customer: '%s'", customerID)
+	}
+
+	subscriptions := []*models.Subscription{}
+	for _, subscription := range f.subscriptions {
+		if subscription.CustomerID == customerID {
+			subscription := subscription
+			subscriptions = append(subscriptions, &subscription)
+		}
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if !subscriptions[i].CreatedAt.Equal(subscriptions[j].CreatedAt) {
+			return subscriptions[i].CreatedAt.After(subscriptions[j].CreatedAt)
+		}
+		return subscriptions[i].ID > subscriptions[j].ID
+	})
+	return subscriptions, nil
+}
+
+// PayCheckoutSession pays for a checkout session as its customer would,
+// creating the customer and a subscription, which is trialing when the
+// checkout has a trial and active otherwise. A trial needs no payment, as
+// at Stripe. It returns the paid session.
+func (f *Fake) PayCheckoutSession(checkoutSessionID string) (*models.CheckoutSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkoutSession, ok := f.checkoutSessions[checkoutSessionID]
+	if !ok {
+		return nil, fmt.Errorf("No such checkout session: '%s'", checkoutSessionID)
+	}
+
+	if checkoutSession.CustomerID == "" {
+		checkoutSession.CustomerID = f.newID("cus")
+		f.customers[checkoutSession.CustomerID] = checkoutSession.CustomerEmail
+	}
+	checkoutSession.PaymentStatus = models.PaymentStatusPaid
+	if f.checkoutTrials[checkoutSessionID] > 0 {
+		checkoutSession.PaymentStatus = models.PaymentStatusNoPaymentRequired
+	}
+	if checkoutSession.SubscriptionID == "" {
+		checkoutSession.SubscriptionID = f.newID("sub")
+		now := time.Now().UTC().Truncate(time.Second)
+		status, periodEnd := models.SubscriptionActive, now.AddDate(0, 1, 0)
+		if trialDays := f.checkoutTrials[checkoutSessionID]; trialDays > 0 {
+			status, periodEnd = models.SubscriptionTrialing, now.AddDate(0, 0, trialDays)
+		}
+		subscription := models.NewSubscription(checkoutSession.SubscriptionID, checkoutSession.UserID, checkoutSession.CustomerID,
+			f.checkoutPrices[checkoutSessionID], status, periodEnd, false)
+		subscription.CreatedAt = now
+		f.subscriptions[subscription.ID] = subscription
+	}
+	f.checkoutSessions[checkoutSessionID] = checkoutSession
+	return &checkoutSession, nil
+}
+
+// Event returns a webhook event of eventType about object, which is
+// marshalled to JSON, and the Stripe-Signature header it is sent with.
+func (f *Fake) Event(eventType string, object any) ([]byte, string, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, "", err
+	}
+
+	f.mu.Lock()
+	id := f.newID("evt")
+	f.mu.Unlock()
+
+	payload, err := json.Marshal(map[string]any{
+		"id":          id,
+		"object":      "event",
+		"type":        eventType,
+		"api_version": stripe.APIVersion,
+		"created":     time.Now().Unix(),
+		"data":        map[string]json.RawMessage{"object": data},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: FakeWebhookSecret})
+	return payload, signed.Header, nil
+}
+
+// UpdateSubscription changes a subscription as Stripe would, e.g. when a
+// payment fails or the customer cancels, and returns it. Send the matching
+// SubscriptionEvent to tell the app.
+func (f *Fake) UpdateSubscription(subscriptionID string, update func(*models.Subscription)) (*models.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	subscription, ok := f.subscriptions[subscriptionID]
+	if !ok {
+		return nil, fmt.Errorf("No such subscription: '%s'", subscriptionID)
+	}
+	update(&subscription)
+	f.subscriptions[subscriptionID] = subscription
+	return &subscription, nil
+}
+
+// SubscriptionEvent returns a customer.subscription.* webhook event of
+// eventType about the subscription as it is now, and its Stripe-Signature
+// header.
+func (f *Fake) SubscriptionEvent(eventType, subscriptionID string) ([]byte, string, error) {
+	f.mu.Lock()
+	subscription, ok := f.subscriptions[subscriptionID]
+	f.mu.Unlock()
+	if !ok {
+		return nil, "", fmt.Errorf("No such subscription: '%s'", subscriptionID)
+	}
+	return f.Event(eventType, subscriptionObject(&subscription))
+}
+
+// subscriptionObject is subscription as the Stripe API returns it.
+func subscriptionObject(subscription *models.Subscription) map[string]any {
+	return map[string]any{
+		"id":                   subscription.ID,
+		"object":               "subscription",
+		"customer":             subscription.CustomerID,
+		"status":               subscription.Status,
+		"created":              subscription.CreatedAt.Unix(),
+		"current_period_end":   subscription.CurrentPeriodEnd.Unix(),
+		"cancel_at_period_end": subscription.CancelAtPeriodEnd,
+		"metadata":             map[string]string{models.UserIDMetadataKey: subscription.UserID},
+		"items": map[string]any{
+			"object": "list",
+			"data": []map[string]any{
+				{"object": "subscription_item", "price": map[string]any{"id": subscription.PriceID, "object": "price"}},
+			},
+		},
+	}
+}
@@ -0,0 +1,172 @@
+// Package billing implements services.Billing. Stripe talks to the Stripe
+// API, Fake and Stub stand in for it in tests.
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"go-todo/internal/config"
+	"go-todo/internal/models"
+	"time"
+
+	"github.com/stripe/stripe-go/v75"
+	"github.com/stripe/stripe-go/v75/client"
+	"github.com/stripe/stripe-go/v75/webhook"
+)
+
+// Stripe bills through Stripe. Each instance has its own key rather than
+// setting the global stripe.Key, so it is safe to use from any request.
+type Stripe struct {
+	api           *client.API
+	apiKey        string
+	webhookSecret string
+}
+
+// NewStripe returns a client for the Stripe API. backends is nil for the
+// real API, tests pass Stub.Backends. A blank key is allowed while
+// developing, billing calls then fail.
+func NewStripe(config config.Stripe, backends *stripe.Backends) *Stripe {
+	return &Stripe{
+		api:           client.New(config.APIKey, backends),
+		apiKey:        config.APIKey,
+		webhookSecret: config.WebhookSecret,
+	}
+}
+
+func (s *Stripe) NewCheckoutSession(userID, customerEmail, priceID string, trialDays int, successURL, cancelURL string) (*models.CheckoutSession, error) {
+	if s.apiKey == "" {
+		return nil, errors.New("STRIPE_API_KEY is not configured")
+	}
+
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(userID),
+		CustomerEmail:     stripe.String(customerEmail),
+		SubscriptionData: &stripe.CheckoutSessionSubscriptionDataParams{
+			Metadata: map[string]string{models.UserIDMetadataKey: userID},
+		},
+		SuccessURL: stripe.String(successURL),
+		CancelURL:  stripe.String(cancelURL),
+		Mode:       stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				Price: stripe.String(priceID),
+				// For metered billing, do not pass quantity
+				Quantity: stripe.Int64(1),
+			},
+		},
+	}
+	if trialDays > 0 {
+		params.SubscriptionData.TrialPeriodDays = stripe.Int64(int64(trialDays))
+	}
+
+	checkoutSession, err := s.api.CheckoutSessions.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating stripe checkout session. %w", err)
+	}
+	return newCheckoutSession(checkoutSession), nil
+}
+
+// GetCheckoutSession returns nil when there is no such checkout session.
+func (s *Stripe) GetCheckoutSession(checkoutSessionID string) (*models.CheckoutSession, error) {
+	if s.apiKey == "" {
+		return nil, errors.New("STRIPE_API_KEY is not configured")
+	}
+
+	checkoutSession, err := s.api.CheckoutSessions.Get(checkoutSessionID, nil)
+	var stripeErr *stripe.Error
+	if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeResourceMissing {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error getting stripe checkout session. %w", err)
+	}
+	return newCheckoutSession(checkoutSession), nil
+}
+
+func (s *Stripe) NewPortalSession(customerID, returnURL string) (string, error) {
+	if s.apiKey == "" {
+		return "", errors.New("STRIPE_API_KEY is not configured")
+	}
+
+	params := &stripe.BillingPortalSessionParams{
+		Customer:  stripe.String(customerID),
+		ReturnURL: stripe.String(returnURL),
+	}
+
+	portalSession, err := s.api.BillingPortalSessions.New(params)
+	if err != nil {
+		return "", fmt.Errorf("Error creating stripe billing portal session. %w", err)
+	}
+	return portalSession.URL, nil
+}
+
+func (s *Stripe) ListSubscriptions(customerID string) ([]*models.Subscription, error) {
+	if s.apiKey == "" {
+		return nil, errors.New("STRIPE_API_KEY is not configured")
+	}
+
+	params := &stripe.SubscriptionListParams{
+		Customer: stripe.String(customerID),
+		// canceled subscriptions are left out otherwise
+		Status: stripe.String("all"),
+	}
+
+	subscriptions := []*models.Subscription{}
+	iter := s.api.Subscriptions.List(params)
+	for iter.Next() {
+		subscriptions = append(subscriptions, newSubscription(iter.Subscription()))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing stripe subscriptions. %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (s *Stripe) ConstructEvent(payload []byte, signature string) (*models.BillingEvent, error) {
+	if s.webhookSecret == "" {
+		return nil, errors.New("STRIPE_WEBHOOK_SECRET is not configured")
+	}
+	return constructEvent(payload, signature, s.webhookSecret)
+}
+
+func constructEvent(payload []byte, signature, webhookSecret string) (*models.BillingEvent, error) {
+	event, err := webhook.ConstructEvent(payload, signature, webhookSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BillingEvent{ID: event.ID, Type: string(event.Type), Created: time.Unix(event.Created, 0).UTC(), Data: event.Data.Raw}, nil
+}
+
+func newSubscription(s *stripe.Subscription) *models.Subscription {
+	var customerID, priceID string
+	if s.Customer != nil {
+		customerID = s.Customer.ID
+	}
+	if s.Items != nil && len(s.Items.Data) > 0 && s.Items.Data[0].Price != nil {
+		priceID = s.Items.Data[0].Price.ID
+	}
+	subscription := models.NewSubscription(s.ID, s.Metadata[models.UserIDMetadataKey], customerID, priceID, string(s.Status),
+		time.Unix(s.CurrentPeriodEnd, 0).UTC(), s.CancelAtPeriodEnd)
+	subscription.CreatedAt = time.Unix(s.Created, 0).UTC()
+	return &subscription
+}
+
+func newCheckoutSession(s *stripe.CheckoutSession) *models.CheckoutSession {
+	checkoutSession := &models.CheckoutSession{
+		ID:            s.ID,
+		URL:           s.URL,
+		UserID:        s.ClientReferenceID,
+		CustomerEmail: s.CustomerEmail,
+		PaymentStatus: string(s.PaymentStatus),
+	}
+	if s.Customer != nil {
+		checkoutSession.CustomerID = s.Customer.ID
+	}
+	if s.Subscription != nil {
+		checkoutSession.SubscriptionID = s.Subscription.ID
+	}
+	if checkoutSession.CustomerEmail == "" && s.CustomerDetails != nil {
+		checkoutSession.CustomerEmail = s.CustomerDetails.Email
+	}
+	return checkoutSession
+}
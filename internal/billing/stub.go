@@ -0,0 +1,162 @@
+package billing
+
+import (
+	"encoding/json"
+	"go-todo/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/stripe/stripe-go/v75"
+)
+
+// Stub serves the parts of the Stripe API the app uses from a Fake, so
+// Stripe itself can be tested without a network. Point it at the server
+// with NewStripe(config, stub.Backends()).
+type Stub struct {
+	*httptest.Server
+	Fake *Fake
+}
+
+func NewStub() *Stub {
+	stub := &Stub{Fake: NewFake()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/checkout/sessions", stub.createCheckoutSession)
+	mux.HandleFunc("GET /v1/checkout/sessions/{id}", stub.getCheckoutSession)
+	mux.HandleFunc("POST /v1/billing_portal/sessions", stub.createPortalSession)
+	mux.HandleFunc("GET /v1/subscriptions", stub.listSubscriptions)
+
+	stub.Server = httptest.NewServer(requireAPIKey(mux))
+	return stub
+}
+
+// Backends sends Stripe API requests to the stub.
+func (s *Stub) Backends() *stripe.Backends {
+	return stripe.NewBackendsWithConfig(&stripe.BackendConfig{
+		URL:               stripe.String(s.URL),
+		MaxNetworkRetries: stripe.Int64(0),
+		LeveledLogger:     &stripe.LeveledLogger{Level: stripe.LevelNull},
+	})
+}
+
+func requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer sk_") {
+			writeStripeError(w, http.StatusUnauthorized, "", "Invalid API Key provided")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Stub) createCheckoutSession(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeStripeError(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	userID := r.PostForm.Get("client_reference_id")
+	if metadataUserID := r.PostForm.Get("subscription_data[metadata][" + models.UserIDMetadataKey + "]"); metadataUserID != userID {
+		writeStripeError(w, http.StatusBadRequest, "", "the subscription metadata does not match the client reference id")
+		return
+	}
+
+	trialDays, _ := strconv.Atoi(r.PostForm.Get("subscription_data[trial_period_days]"))
+
+	checkoutSession, err := s.Fake.NewCheckoutSession(userID, r.PostForm.Get("customer_email"), r.PostForm.Get("line_items[0][price]"), trialDays, r.PostForm.Get("success_url"), r.PostForm.Get("cancel_url"))
+	if err != nil {
+		writeStripeError(w, http.StatusBadRequest, string(stripe.ErrorCodeParameterMissing), err.Error())
+		return
+	}
+	writeCheckoutSession(w, checkoutSession.ID, s.Fake)
+}
+
+func (s *Stub) getCheckoutSession(w http.ResponseWriter, r *http.Request) {
+	writeCheckoutSession(w, r.PathValue("id"), s.Fake)
+}
+
+func writeCheckoutSession(w http.ResponseWriter, id string, fake *Fake) {
+	checkoutSession, _ := fake.GetCheckoutSession(id)
+	if checkoutSession == nil {
+		writeStripeError(w, http.StatusNotFound, string(stripe.ErrorCodeResourceMissing), "No such checkout.session: '"+id+"'")
+		return
+	}
+
+	var customer, subscription any
+	if checkoutSession.CustomerID != "" {
+		customer = checkoutSession.CustomerID
+	}
+	if checkoutSession.SubscriptionID != "" {
+		subscription = checkoutSession.SubscriptionID
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":                  checkoutSession.ID,
+		"object":              "checkout.session",
+		"url":                 checkoutSession.URL,
+		"client_reference_id": checkoutSession.UserID,
+		"customer":            customer,
+		"customer_email":      checkoutSession.CustomerEmail,
+		"subscription":        subscription,
+		"payment_status":      checkoutSession.PaymentStatus,
+		"mode":                "subscription",
+	})
+}
+
+func (s *Stub) createPortalSession(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeStripeError(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	url, err := s.Fake.NewPortalSession(r.PostForm.Get("customer"), r.PostForm.Get("return_url"))
+	if err != nil {
+		writeStripeError(w, http.StatusBadRequest, string(stripe.ErrorCodeResourceMissing), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "billing_portal.session",
+		"url":    url,
+	})
+}
+
+func (s *Stub) listSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("status") != "all" {
+		writeStripeError(w, http.StatusBadRequest, "", "the stub only lists subscriptions of every status")
+		return
+	}
+
+	subscriptions, err := s.Fake.ListSubscriptions(r.URL.Query().Get("customer"))
+	if err != nil {
+		writeStripeError(w, http.StatusNotFound, string(stripe.ErrorCodeResourceMissing), err.Error())
+		return
+	}
+
+	data := []map[string]any{}
+	for _, subscription := range subscriptions {
+		data = append(data, subscriptionObject(subscription))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object":   "list",
+		"url":      "/v1/subscriptions",
+		"has_more": false,
+		"data":     data,
+	})
+}
+
+func writeStripeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]string{
+			"type":    string(stripe.ErrorTypeInvalidRequest),
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -3,40 +3,120 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"go-todo/internal/config"
+	"go-todo/internal/migrations"
+	"go-todo/internal/models"
+	"go-todo/internal/server/sessionstore"
 	"go-todo/internal/services"
+	"os"
+	"strings"
+	"time"
 )
 
 type cli struct {
-	s *services.Service
+	s        *services.Service
+	migrator *migrations.Migrator
+	store    sessionstore.Store
+	config   *config.Config
 }
 
-func New(s *services.Service) *cli {
-	return &cli{s}
+func New(s *services.Service, migrator *migrations.Migrator, store sessionstore.Store, config *config.Config) *cli {
+	return &cli{s, migrator, store, config}
 }
 
-func (cli *cli) Execute() error {
-
-	resource := flag.String("resource", "", "todo, user")
-	action := flag.String("action", "", "tidy,...")
+// Options are the command line flags picked out by the flag set passed to
+// RegisterFlags once it is parsed.
+type Options struct {
+	resource string
+	action   string
+	name     string
+	steps    int
+	dir      string
+	user     string
+	role     string
+	id       string
+	status   string
+	limit    int
+	apply    bool
+}
 
-	flag.Parse()
+// RegisterFlags adds the cli flags to fs. They are registered before the
+// config is loaded, which parses fs along with its own flags.
+func RegisterFlags(fs *flag.FlagSet) *Options {
+	opts := &Options{}
+	fs.StringVar(&opts.resource, "resource", "", "todo, user, migrate, roles, sessions, config, stripe-events, billing")
+	fs.StringVar(&opts.action, "action", "", "tidy,...")
+	fs.StringVar(&opts.name, "name", "", "name of the migration to create")
+	fs.IntVar(&opts.steps, "steps", 1, "number of migrations to roll back")
+	fs.StringVar(&opts.dir, "dir", migrations.DefaultDir, "directory new migrations are written to")
+	fs.StringVar(&opts.user, "user", "", "email or id of the user to act on")
+	fs.StringVar(&opts.role, "role", "", "name of the role to grant or revoke")
+	fs.StringVar(&opts.id, "id", "", "id of the stripe event to replay")
+	fs.StringVar(&opts.status, "status", "", "only list stripe events with this status: pending, processed, failed")
+	fs.IntVar(&opts.limit, "limit", 50, "most stripe events to list")
+	fs.BoolVar(&opts.apply, "apply", false, "fix the differences billing reconcile finds, otherwise they are only printed")
+	return opts
+}
 
-	switch *resource {
+func (cli *cli) Execute(opts *Options) error {
+	switch opts.resource {
 	case "users":
-		return cli.UserActions(*action)
+		return cli.UserActions(opts.action, *opts)
 	case "todos":
-		return cli.TodoActions(*action)
+		return cli.TodoActions(opts.action)
+	case "migrate":
+		return cli.MigrateActions(opts.action, *opts)
+	case "roles":
+		return cli.RoleActions(opts.action, *opts)
+	case "sessions":
+		return cli.SessionActions(opts.action)
+	case "config":
+		return cli.ConfigActions(opts.action)
+	case "stripe-events":
+		return cli.StripeEventActions(opts.action, *opts)
+	case "billing":
+		return cli.BillingActions(opts.action, *opts)
 	default:
 		return fmt.Errorf("need to supply a valid resource")
 	}
 
 }
 
-func (cli *cli) UserActions(action string) error {
+func (cli *cli) UserActions(action string, opts Options) error {
 	switch action {
-
+	case "disable-2fa":
+		// for users locked out without their authenticator or recovery codes
+		user, err := cli.findUser(opts.user)
+		if err != nil {
+			return err
+		}
+		if !user.TwoFactorEnabled() {
+			return fmt.Errorf("%s does not have two factor authentication enabled", user.Email)
+		}
+		err = cli.s.ResetTwoFactor(user.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Disabled two factor authentication for %s (%s)\n", user.Email, user.ID)
+		return nil
+	case "unlock":
+		// lockouts are by email, so addresses without an account can be
+		// unlocked too
+		email := opts.user
+		user, err := cli.findUser(opts.user)
+		if err == nil {
+			email = user.Email
+		} else if !strings.Contains(opts.user, "@") {
+			return err
+		}
+		err = cli.s.UnlockEmail(email)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Unlocked logins for %s\n", email)
+		return nil
 	default:
-		return fmt.Errorf("Please supply a valid user action")
+		return fmt.Errorf("Please supply a valid user action (disable-2fa, unlock)")
 	}
 }
 
@@ -48,3 +128,215 @@ func (cli *cli) TodoActions(action string) error {
 		return fmt.Errorf("Please supply a valid todo action")
 	}
 }
+
+func (cli *cli) SessionActions(action string) error {
+	switch action {
+	case "clean":
+		count, err := cli.store.DeleteExpired()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("deleted %d expired session(s)\n", count)
+		return nil
+	default:
+		return fmt.Errorf("Please supply a valid session action")
+	}
+}
+
+func (cli *cli) ConfigActions(action string) error {
+	switch action {
+	case "print":
+		// secrets are redacted so the output can be pasted into a bug report
+		return cli.config.Print(os.Stdout)
+	default:
+		return fmt.Errorf("Please supply a valid config action (print)")
+	}
+}
+
+func (cli *cli) StripeEventActions(action string, opts Options) error {
+	switch action {
+	case "list":
+		events, err := cli.s.GetStripeEvents(opts.status, opts.limit)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			fmt.Printf("%-30s %-35s %-10s %2d attempt(s)  received %s  %s\n", event.ID, event.Type, event.Status, event.Attempts, event.ReceivedAt.Format("2006-01-02 15:04:05"), event.LastError)
+		}
+		return nil
+	case "replay":
+		if opts.id == "" {
+			return fmt.Errorf("Please supply a stripe event with -id")
+		}
+		event, clientError, err := cli.s.ReplayStripeEvent(opts.id)
+		if err != nil {
+			return err
+		}
+		if clientError != nil {
+			return fmt.Errorf("%s", clientError.Message)
+		}
+		if event.Status != models.StripeEventProcessed {
+			return fmt.Errorf("Stripe event %s failed again, it is %s. %s", event.ID, event.Status, event.LastError)
+		}
+		fmt.Printf("Replayed stripe event %s %s\n", event.ID, event.Type)
+		return nil
+	default:
+		return fmt.Errorf("Please supply a valid stripe-events action (list, replay)")
+	}
+}
+
+func (cli *cli) BillingActions(action string, opts Options) error {
+	switch action {
+	case "reconcile":
+		// stripe is the truth, nothing changes here without -apply
+		reconciliation, err := cli.s.ReconcileBilling(opts.apply, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, difference := range reconciliation.Differences {
+			fmt.Printf("%-30s %-40s %s -> %s\n", difference.Email, difference.Field, difference.Local, difference.Remote)
+		}
+		for _, failure := range reconciliation.Failures {
+			fmt.Printf("%-30s could not be checked: %s\n", failure.Email, failure.Err)
+		}
+		switch {
+		case len(reconciliation.Differences) == 0:
+			fmt.Printf("Checked %d user(s), billing matches stripe\n", reconciliation.Users)
+		case reconciliation.Applied:
+			fixed := 0
+			for _, difference := range reconciliation.Differences {
+				if difference.Fixed {
+					fixed++
+				}
+			}
+			fmt.Printf("Checked %d user(s), fixed %d of %d difference(s)\n", reconciliation.Users, fixed, len(reconciliation.Differences))
+		default:
+			fmt.Printf("Checked %d user(s), found %d difference(s), run again with -apply to fix them\n", reconciliation.Users, len(reconciliation.Differences))
+		}
+		if len(reconciliation.Failures) > 0 {
+			return fmt.Errorf("Could not check %d user(s)", len(reconciliation.Failures))
+		}
+		return nil
+	default:
+		return fmt.Errorf("Please supply a valid billing action (reconcile)")
+	}
+}
+
+func (cli *cli) MigrateActions(action string, opts Options) error {
+	switch action {
+	case "up":
+		count, err := cli.migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", count)
+		return nil
+	case "down":
+		count, err := cli.migrator.Down(opts.steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d migration(s)\n", count)
+		return nil
+	case "status":
+		statuses, err := cli.migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", status.Version, status.Name, state)
+		}
+		return cli.migrator.Check()
+	case "create":
+		if opts.name == "" {
+			return fmt.Errorf("Please supply a migration name with -name")
+		}
+		upPath, downPath, err := migrations.Create(opts.dir, opts.name)
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", upPath)
+		fmt.Println("created", downPath)
+		return nil
+	default:
+		return fmt.Errorf("Please supply a valid migrate action (up, down, status, create)")
+	}
+}
+
+// findUser looks a user up by email, falling back to their id.
+func (cli *cli) findUser(emailOrID string) (*models.User, error) {
+	if emailOrID == "" {
+		return nil, fmt.Errorf("Please supply a user with -user")
+	}
+
+	userID := emailOrID
+
+	user, err := cli.s.GetUserByEmail(emailOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		userID = user.ID
+	}
+
+	// only lookups by id load the user's roles
+	user, err = cli.s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("No user found with email or id %s", emailOrID)
+	}
+	return user, nil
+}
+
+func (cli *cli) RoleActions(action string, opts Options) error {
+	switch action {
+	case "list":
+		roles, err := cli.s.GetRoles()
+		if err != nil {
+			return err
+		}
+		for _, role := range roles {
+			fmt.Printf("%-10s %-50s %s\n", role.Name, role.Description, strings.Join(role.Permissions, ", "))
+		}
+		return nil
+	case "grant", "revoke":
+		if opts.role == "" {
+			return fmt.Errorf("Please supply a role with -role")
+		}
+		user, err := cli.findUser(opts.user)
+		if err != nil {
+			return err
+		}
+		verb := "Granted"
+		if action == "grant" {
+			err = cli.s.GrantRole(user.ID, opts.role)
+		} else {
+			verb = "Revoked"
+			err = cli.s.RevokeRole(user.ID, opts.role)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s role %s for %s (%s)\n", verb, opts.role, user.Email, user.ID)
+		return nil
+	case "show":
+		user, err := cli.findUser(opts.user)
+		if err != nil {
+			return err
+		}
+		for name := range user.Roles {
+			fmt.Println(name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("Please supply a valid roles action (list, grant, revoke, show)")
+	}
+}
@@ -0,0 +1,530 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go-todo/internal/models"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is everything the server and CLI are configured with. Each
+// setting's env tag names both its environment variable and its key in the
+// config file, its flag is the same name lower cased with dashes, e.g.
+// SESSION_MAX_AGE and -session-max-age.
+type Config struct {
+	Env    string `env:"ENV" default:"dev" usage:"dev or prod"`
+	Port   string `env:"PORT" default:"8080" usage:"port the server listens on"`
+	Domain string `env:"DOMAIN" usage:"url the site is served from, used in links and redirects"`
+	// signs sessions and email links
+	SecretKey string `env:"SECRET_KEY" secret:"true" usage:"key sessions and email links are signed with"`
+	// actions users who have not verified their email can not take, empty
+	// restricts nothing
+	UnverifiedEmailRestrictions []string `env:"UNVERIFIED_EMAIL_RESTRICTIONS" default:"checkout" usage:"comma separated actions unverified users can not take: checkout, tokens"`
+
+	Database  Database
+	Sessions  Sessions
+	Cache     Cache
+	Mail      Mail
+	Stripe    Stripe
+	Plans     Plans
+	Dunning   Dunning
+	Reminders Reminders
+}
+
+type Database struct {
+	Path string `env:"DB_PATH" default:"data/main.db" usage:"main SQLite database file"`
+}
+
+type Sessions struct {
+	Store           string        `env:"SESSION_STORE" default:"sqlite" usage:"sqlite, database, cookie or memory"`
+	Path            string        `env:"SESSION_DB_PATH" default:"data/sessions.db" usage:"database file for the sqlite session store"`
+	Table           string        `env:"SESSION_TABLE" default:"sessions" usage:"table for the sqlite session store, the database store always uses sessions"`
+	MaxAge          time.Duration `env:"SESSION_MAX_AGE" default:"1h" usage:"how long a session lasts unless remembered"`
+	CleanupInterval time.Duration `env:"SESSION_CLEANUP_INTERVAL" default:"1h" usage:"how often expired sessions are deleted"`
+}
+
+type Cache struct {
+	DefaultExpiration time.Duration `env:"CACHE_TTL" default:"5m" usage:"how long users and todos are cached"`
+	CleanupInterval   time.Duration `env:"CACHE_CLEANUP_INTERVAL" default:"10m" usage:"how often expired cache entries are removed"`
+}
+
+type Mail struct {
+	Mailer       string `env:"MAILER" default:"file" usage:"file or smtp"`
+	Dir          string `env:"MAIL_DIR" default:"data/mail" usage:"where the file mailer writes messages"`
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     string `env:"SMTP_PORT" default:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD" secret:"true"`
+	From         string `env:"MAIL_FROM" usage:"address email is sent from"`
+	// email is saved to an outbox and sent from there, failed sends are
+	// retried with a growing delay
+	OutboxInterval    time.Duration `env:"MAIL_OUTBOX_INTERVAL" default:"10s" usage:"how often the outbox is checked for email to send"`
+	OutboxMaxAttempts int           `env:"MAIL_OUTBOX_MAX_ATTEMPTS" default:"8" usage:"attempts before an email is given up on"`
+}
+
+type Stripe struct {
+	APIKey        string `env:"STRIPE_API_KEY" secret:"true"`
+	WebhookSecret string `env:"STRIPE_WEBHOOK_SECRET" secret:"true"`
+	PriceID       string `env:"STRIPE_PRICE_ID" default:"price_1NlpMHJ6hGciURAFUvHsGcdM" usage:"price of the paid plan"`
+	// webhook events that fail are retried with a growing delay
+	EventRetryInterval time.Duration `env:"STRIPE_EVENT_RETRY_INTERVAL" default:"1m" usage:"how often failed webhook events are checked for retries"`
+	EventMaxAttempts   int           `env:"STRIPE_EVENT_MAX_ATTEMPTS" default:"8" usage:"attempts before a webhook event is given up on"`
+}
+
+// Plans is the plan catalogue. PLANS_FILE names a JSON array of plans like
+//
+//	[{"id": "free", "name": "Free", "entitlements": {"max_todos": 10, "max_lists": 3, "api_access": true}},
+//	 {"id": "pro", "name": "Pro", "price": "$5 / month", "price_id": "price_...", "entitlements": {"api_access": true}}]
+//
+// Without one there is a free plan with the FREE_ limits and an unlimited
+// pro plan bought through STRIPE_PRICE_ID.
+type Plans struct {
+	File          string `env:"PLANS_FILE" usage:"JSON file holding the plan catalogue, blank for a free and a pro plan"`
+	FreeTodoLimit int    `env:"FREE_TODO_LIMIT" default:"10" usage:"todo limit of the free plan when there is no PLANS_FILE"`
+	FreeListLimit int    `env:"FREE_LIST_LIMIT" default:"3" usage:"list limit of the free plan when there is no PLANS_FILE"`
+	TrialDays     int    `env:"TRIAL_DAYS" default:"0" usage:"days of free trial on the pro plan when there is no PLANS_FILE"`
+	// built from the settings above when the config is loaded
+	Catalogue models.PlanCatalogue `env:"-"`
+}
+
+// Dunning is how failed payments are chased. A past due subscription
+// keeps its plan for GracePeriod, and a reminder to pay is sent each of
+// Reminders after it became past due.
+type Dunning struct {
+	GracePeriod time.Duration   `env:"DUNNING_GRACE_PERIOD" default:"168h" usage:"how long users whose payment failed keep their plan"`
+	Reminders   []time.Duration `env:"DUNNING_REMINDERS" default:"0s,72h,144h" usage:"comma separated times after a payment fails to remind the user to pay"`
+	Interval    time.Duration   `env:"DUNNING_INTERVAL" default:"1h" usage:"how often past due subscriptions are checked"`
+}
+
+// Reminders is when users are emailed about todos coming due.
+type Reminders struct {
+	Before   time.Duration `env:"TODO_REMINDER_BEFORE" default:"24h" usage:"how long before a todo is due to remind its user"`
+	Interval time.Duration `env:"TODO_REMINDER_INTERVAL" default:"15m" usage:"how often todos coming due are checked"`
+}
+
+// actions UnverifiedEmailRestrictions can restrict
+const (
+	RestrictCheckout     = "checkout"
+	RestrictAccessTokens = "tokens"
+)
+
+// session stores Sessions.Store can choose
+var sessionStores = []string{"sqlite", "database", "cookie", "memory"}
+
+// DefaultFile is the config file read when neither -config nor
+// CONFIG_FILE name one. It is fine for it not to exist.
+const DefaultFile = ".env"
+
+// session tables are put into queries as they are
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (c *Config) IsProd() bool {
+	return c.Env == "prod"
+}
+
+// UnverifiedEmailRestricts reports whether action is closed to users who
+// have not verified their email.
+func (c *Config) UnverifiedEmailRestricts(action string) bool {
+	return slices.Contains(c.UnverifiedEmailRestrictions, action)
+}
+
+// setting is one field of Config.
+type setting struct {
+	env    string
+	flag   string
+	usage  string
+	value  reflect.Value
+	def    string
+	secret bool
+}
+
+func settings(c *Config) []setting {
+	return collectSettings(reflect.ValueOf(c).Elem(), nil)
+}
+
+func collectSettings(v reflect.Value, all []setting) []setting {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			all = collectSettings(v.Field(i), all)
+			continue
+		}
+
+		env := field.Tag.Get("env")
+		if env == "-" {
+			continue
+		}
+		all = append(all, setting{
+			env:    env,
+			flag:   strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+			usage:  field.Tag.Get("usage"),
+			value:  v.Field(i),
+			def:    field.Tag.Get("default"),
+			secret: field.Tag.Get("secret") == "true",
+		})
+	}
+	return all
+}
+
+func (s setting) set(raw string) error {
+	switch s.value.Interface().(type) {
+	case string:
+		s.value.SetString(strings.TrimSpace(raw))
+	case int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number, got %q", s.env, raw)
+		}
+		s.value.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s must be a duration like 90s, 30m or 1h, got %q", s.env, raw)
+		}
+		s.value.SetInt(int64(d))
+	case []string:
+		list := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(list))
+	case []time.Duration:
+		list := []time.Duration{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			d, err := time.ParseDuration(item)
+			if err != nil {
+				return fmt.Errorf("%s must be comma separated durations like 90s, 30m or 1h, got %q", s.env, raw)
+			}
+			list = append(list, d)
+		}
+		s.value.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("%s has a type config can not set", s.env)
+	}
+	return nil
+}
+
+func (s setting) String() string {
+	switch value := s.value.Interface().(type) {
+	case []string:
+		return strings.Join(value, ",")
+	case []time.Duration:
+		list := []string{}
+		for _, d := range value {
+			list = append(list, d.String())
+		}
+		return strings.Join(list, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Defaults returns a config with every setting at its default. It is not
+// validated, DOMAIN and SECRET_KEY have no default.
+func Defaults() *Config {
+	c := &Config{}
+	for _, s := range settings(c) {
+		if err := s.set(s.def); err != nil {
+			panic(err)
+		}
+	}
+	c.Plans.Catalogue = c.defaultCatalogue()
+	return c
+}
+
+// defaultCatalogue is the plan catalogue when there is no PLANS_FILE.
+func (c *Config) defaultCatalogue() models.PlanCatalogue {
+	return models.PlanCatalogue{
+		{
+			ID:          "free",
+			Name:        "Free",
+			Description: "For getting started",
+			Entitlements: models.Entitlements{
+				MaxTodos:  c.Plans.FreeTodoLimit,
+				MaxLists:  c.Plans.FreeListLimit,
+				APIAccess: true,
+			},
+		},
+		{
+			ID:          "pro",
+			Name:        "Pro",
+			Description: "For getting everything done",
+			PriceID:     c.Stripe.PriceID,
+			TrialDays:   c.Plans.TrialDays,
+			Entitlements: models.Entitlements{
+				APIAccess: true,
+			},
+		},
+	}
+}
+
+// loadCatalogue reads the plan catalogue from PLANS_FILE, or returns the
+// default one when it is blank.
+func (c *Config) loadCatalogue() (models.PlanCatalogue, error) {
+	if c.Plans.File == "" {
+		return c.defaultCatalogue(), nil
+	}
+
+	data, err := os.ReadFile(c.Plans.File)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read PLANS_FILE. %w", err)
+	}
+
+	catalogue := models.PlanCatalogue{}
+	err = json.Unmarshal(data, &catalogue)
+	if err != nil {
+		return nil, fmt.Errorf("PLANS_FILE %s is not a JSON array of plans. %w", c.Plans.File, err)
+	}
+	return catalogue, nil
+}
+
+// Load adds the config flags, and -config naming the config file, to fs
+// and parses args with it. The config is built from, later ones winning,
+// the defaults, the config file, environment variables and flags, then
+// validated.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	c := Defaults()
+	all := settings(c)
+
+	configFile := fs.String("config", "", "config file of KEY=value lines (default "+DefaultFile+", or CONFIG_FILE)")
+	flags := map[string]*string{}
+	for _, s := range all {
+		usage := s.usage
+		if usage == "" {
+			usage = "sets " + s.env
+		}
+		flags[s.flag] = fs.String(s.flag, "", usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path, required := *configFile, true
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		path, required = DefaultFile, false
+	}
+
+	fileValues, err := godotenv.Read(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		fileValues = map[string]string{}
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not read config file %s. %w", path, err)
+	}
+
+	setFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	errs := []error{}
+	for _, s := range all {
+		raw, ok := fileValues[s.env]
+		if envValue, inEnv := os.LookupEnv(s.env); inEnv {
+			raw, ok = envValue, true
+		}
+		if setFlags[s.flag] {
+			raw, ok = *flags[s.flag], true
+		}
+		if !ok {
+			continue
+		}
+		if err := s.set(raw); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	c.Domain = strings.TrimSuffix(c.Domain, "/")
+
+	if len(errs) == 0 {
+		c.Plans.Catalogue, err = c.loadCatalogue()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		errs = c.validate()
+	}
+	if len(errs) > 0 {
+		problems := []string{}
+		for _, err := range errs {
+			problems = append(problems, err.Error())
+		}
+		return nil, fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return c, nil
+}
+
+// validate returns everything wrong with the config.
+func (c *Config) validate() []error {
+	errs := []error{}
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Env != "dev" && c.Env != "prod" {
+		fail("ENV must be dev or prod, got %q", c.Env)
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		fail("PORT must be a port number, got %q", c.Port)
+	}
+
+	if c.Domain == "" {
+		fail("DOMAIN is required, e.g. http://localhost:%s", c.Port)
+	} else if u, err := url.Parse(c.Domain); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail("DOMAIN must be a http or https url, got %q", c.Domain)
+	}
+
+	if c.SecretKey == "" {
+		fail("SECRET_KEY is required")
+	} else if c.IsProd() && len(c.SecretKey) < 32 {
+		fail("SECRET_KEY must be at least 32 characters in prod")
+	}
+
+	for _, action := range c.UnverifiedEmailRestrictions {
+		if action != RestrictCheckout && action != RestrictAccessTokens {
+			fail("UNVERIFIED_EMAIL_RESTRICTIONS can only hold %s and %s, got %q", RestrictCheckout, RestrictAccessTokens, action)
+		}
+	}
+
+	if c.Database.Path == "" {
+		fail("DB_PATH is required")
+	}
+
+	if !slices.Contains(sessionStores, c.Sessions.Store) {
+		fail("SESSION_STORE must be one of %s, got %q", strings.Join(sessionStores, ", "), c.Sessions.Store)
+	}
+	if c.Sessions.Store == "sqlite" && c.Sessions.Path == "" {
+		fail("SESSION_DB_PATH is required for the sqlite session store")
+	}
+	if !tableName.MatchString(c.Sessions.Table) {
+		fail("SESSION_TABLE must be a plain table name, got %q", c.Sessions.Table)
+	}
+	// the migrations only create sessions in the app's database, any other
+	// table there belongs to the app
+	if c.Sessions.Store == "database" && c.Sessions.Table != "sessions" {
+		fail("SESSION_TABLE must be sessions for the database session store, got %q", c.Sessions.Table)
+	}
+	if c.Sessions.MaxAge < time.Second {
+		fail("SESSION_MAX_AGE must be at least a second, got %s", c.Sessions.MaxAge)
+	}
+	if c.Sessions.CleanupInterval <= 0 {
+		fail("SESSION_CLEANUP_INTERVAL must be positive, got %s", c.Sessions.CleanupInterval)
+	}
+
+	if c.Cache.DefaultExpiration <= 0 {
+		fail("CACHE_TTL must be positive, got %s", c.Cache.DefaultExpiration)
+	}
+	if c.Cache.CleanupInterval <= 0 {
+		fail("CACHE_CLEANUP_INTERVAL must be positive, got %s", c.Cache.CleanupInterval)
+	}
+
+	switch c.Mail.Mailer {
+	case "file":
+		if c.Mail.Dir == "" {
+			fail("MAIL_DIR is required for the file mailer")
+		}
+	case "smtp":
+		if c.Mail.SMTPHost == "" || c.Mail.From == "" {
+			fail("SMTP_HOST and MAIL_FROM are required to send email over SMTP")
+		}
+		if _, err := strconv.Atoi(c.Mail.SMTPPort); err != nil {
+			fail("SMTP_PORT must be a port number, got %q", c.Mail.SMTPPort)
+		}
+	default:
+		fail("MAILER must be file or smtp, got %q", c.Mail.Mailer)
+	}
+
+	if c.Mail.OutboxInterval <= 0 {
+		fail("MAIL_OUTBOX_INTERVAL must be positive, got %s", c.Mail.OutboxInterval)
+	}
+	if c.Mail.OutboxMaxAttempts < 1 {
+		fail("MAIL_OUTBOX_MAX_ATTEMPTS must be at least 1, got %d", c.Mail.OutboxMaxAttempts)
+	}
+
+	// billing is optional while developing, but must work in prod
+	if c.IsProd() && (c.Stripe.APIKey == "" || c.Stripe.WebhookSecret == "") {
+		fail("STRIPE_API_KEY and STRIPE_WEBHOOK_SECRET are required in prod")
+	}
+	if c.Stripe.APIKey != "" && !strings.HasPrefix(c.Stripe.APIKey, "sk_") && !strings.HasPrefix(c.Stripe.APIKey, "rk_") {
+		fail("STRIPE_API_KEY must be a secret or restricted key starting sk_ or rk_")
+	}
+	if c.Stripe.WebhookSecret != "" && !strings.HasPrefix(c.Stripe.WebhookSecret, "whsec_") {
+		fail("STRIPE_WEBHOOK_SECRET must start whsec_")
+	}
+	if !strings.HasPrefix(c.Stripe.PriceID, "price_") {
+		fail("STRIPE_PRICE_ID must be a price id starting price_, got %q", c.Stripe.PriceID)
+	}
+
+	if c.Stripe.EventRetryInterval <= 0 {
+		fail("STRIPE_EVENT_RETRY_INTERVAL must be positive, got %s", c.Stripe.EventRetryInterval)
+	}
+	if c.Stripe.EventMaxAttempts < 1 {
+		fail("STRIPE_EVENT_MAX_ATTEMPTS must be at least 1, got %d", c.Stripe.EventMaxAttempts)
+	}
+
+	if c.Plans.FreeTodoLimit < 0 || c.Plans.FreeListLimit < 0 || c.Plans.TrialDays < 0 {
+		fail("FREE_TODO_LIMIT, FREE_LIST_LIMIT and TRIAL_DAYS can not be negative")
+	}
+	for _, err := range c.Plans.Catalogue.Validate() {
+		fail("%s", err)
+	}
+
+	if c.Reminders.Before <= 0 {
+		fail("TODO_REMINDER_BEFORE must be positive, got %s", c.Reminders.Before)
+	}
+	if c.Reminders.Interval <= 0 {
+		fail("TODO_REMINDER_INTERVAL must be positive, got %s", c.Reminders.Interval)
+	}
+
+	if c.Dunning.GracePeriod <= 0 {
+		fail("DUNNING_GRACE_PERIOD must be positive, got %s", c.Dunning.GracePeriod)
+	}
+	for i, reminder := range c.Dunning.Reminders {
+		if reminder < 0 || reminder >= c.Dunning.GracePeriod || (i > 0 && reminder <= c.Dunning.Reminders[i-1]) {
+			fail("DUNNING_REMINDERS must be increasing and within DUNNING_GRACE_PERIOD, got %s", c.Dunning.Reminders)
+			break
+		}
+	}
+	if c.Dunning.Interval <= 0 {
+		fail("DUNNING_INTERVAL must be positive, got %s", c.Dunning.Interval)
+	}
+
+	return errs
+}
+
+// Print writes every setting as a KEY=value line, with secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	for _, s := range settings(c) {
+		value := s.String()
+		if s.secret && value != "" {
+			value = "[redacted]"
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", s.env, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every config variable for the test, so only what the
+// test sets is loaded.
+func clearEnv(t *testing.T) {
+	for _, s := range settings(&Config{}) {
+		t.Setenv(s.env, "")
+		os.Unsetenv(s.env)
+	}
+	t.Setenv("CONFIG_FILE", "")
+	os.Unsetenv("CONFIG_FILE")
+}
+
+func writeConfigFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeConfigFile(t, "DOMAIN=http://file.test/\nSECRET_KEY=file-secret\nPORT=9000\nSESSION_MAX_AGE=2h\nCACHE_TTL=1m\n")
+	t.Setenv("PORT", "9001")
+	t.Setenv("SESSION_MAX_AGE", "3h")
+
+	c, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path, "-session-max-age", "4h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Domain != "http://file.test" || c.SecretKey != "file-secret" {
+		t.Errorf("expected the file to set domain and secret key, got %q %q", c.Domain, c.SecretKey)
+	}
+	if c.Port != "9001" {
+		t.Errorf("expected the environment to override the file, got port %s", c.Port)
+	}
+	if c.Sessions.MaxAge != 4*time.Hour {
+		t.Errorf("expected the flag to override the environment, got %s", c.Sessions.MaxAge)
+	}
+	if c.Cache.DefaultExpiration != time.Minute || c.Cache.CleanupInterval != 10*time.Minute {
+		t.Errorf("expected the file and defaults for the cache, got %s %s", c.Cache.DefaultExpiration, c.Cache.CleanupInterval)
+	}
+	if !c.UnverifiedEmailRestricts(RestrictCheckout) || c.UnverifiedEmailRestricts(RestrictAccessTokens) {
+		t.Errorf("expected only checkout to be restricted by default, got %v", c.UnverifiedEmailRestrictions)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.env"))
+
+	// a config file that was asked for has to exist
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), "missing.env") {
+		t.Fatalf("expected an error reading the missing config file, got %v", err)
+	}
+
+	os.Unsetenv("CONFIG_FILE")
+	t.Setenv("ENV", "prod")
+	t.Setenv("SESSION_STORE", "redis")
+	t.Setenv("UNVERIFIED_EMAIL_RESTRICTIONS", "checkout, todos")
+
+	_, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil {
+		t.Fatal("expected an invalid config to be refused")
+	}
+	for _, problem := range []string{"DOMAIN is required", "SECRET_KEY is required", "SESSION_STORE", `"todos"`, "STRIPE_API_KEY"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected the error to mention %s, got %v", problem, err)
+		}
+	}
+
+	t.Setenv("CACHE_TTL", "soon")
+	_, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), "CACHE_TTL must be a duration") {
+		t.Errorf("expected a bad duration to be refused, got %v", err)
+	}
+}
+
+func TestDatabaseSessionStoreOnlyUsesTheSessionsTable(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SESSION_STORE", "database")
+	t.Setenv("SESSION_TABLE", "users")
+
+	_, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), "SESSION_TABLE must be sessions") {
+		t.Errorf("expected another table to be refused for the database store, got %v", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	c := Defaults()
+	c.Domain = "http://localhost:8080"
+	c.SecretKey = "very-secret"
+	c.Stripe.APIKey = "sk_test_secret"
+
+	var out bytes.Buffer
+	if err := c.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	printed := out.String()
+	if strings.Contains(printed, "very-secret") || strings.Contains(printed, "sk_test_secret") {
+		t.Fatalf("expected secrets to be redacted, got\n%s", printed)
+	}
+	for _, line := range []string{"SECRET_KEY=[redacted]", "STRIPE_API_KEY=[redacted]", "STRIPE_WEBHOOK_SECRET=\n", "DOMAIN=http://localhost:8080", "SESSION_MAX_AGE=1h0m0s"} {
+		if !strings.Contains(printed, line) {
+			t.Errorf("expected %q in\n%s", line, printed)
+		}
+	}
+}
+
+func TestLoadPlanCatalogue(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DOMAIN", "http://localhost:8080")
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("FREE_TODO_LIMIT", "5")
+
+	c, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free := c.Plans.Catalogue.Free(); free == nil || free.Entitlements.MaxTodos != 5 {
+		t.Errorf("expected the default free plan to have the FREE_TODO_LIMIT, got %+v", free)
+	}
+	if pro := c.Plans.Catalogue.ByPriceID(c.Stripe.PriceID); pro == nil || pro.Entitlements.MaxTodos != 0 {
+		t.Errorf("expected an unlimited plan for STRIPE_PRICE_ID, got %+v", pro)
+	}
+
+	plans := filepath.Join(t.TempDir(), "plans.json")
+	if err := os.WriteFile(plans, []byte(`[
+		{"id": "free", "name": "Free", "entitlements": {"max_todos": 20}},
+		{"id": "team", "name": "Team", "price": "$20 / month", "price_id": "price_team", "entitlements": {"attachments": true, "api_access": true}}
+	]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PLANS_FILE", plans)
+
+	c, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Plans.Catalogue) != 2 || c.Plans.Catalogue.Get("team").PriceID != "price_team" || c.Plans.Catalogue.Free().Entitlements.APIAccess {
+		t.Errorf("expected the catalogue from PLANS_FILE, got %+v", c.Plans.Catalogue)
+	}
+
+	if err := os.WriteFile(plans, []byte(`[{"id": "free", "name": "Free"}, {"id": "basic", "name": "Basic"}, {"id": "pro", "name": "Pro", "price_id": "pro"}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), "exactly one free plan") || !strings.Contains(err.Error(), "must start price_") {
+		t.Errorf("expected the catalogue's problems to be reported, got %v", err)
+	}
+}
@@ -6,8 +6,26 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func Connect() (*sql.DB, error) {
+func Connect(path string) (*sql.DB, error) {
 	driverName := "sqlite3"
-	dataSourceName := "data/main.db"
-	return sql.Open(driverName, dataSourceName)
+	return sql.Open(driverName, path)
+}
+
+// ConnectMemory opens a new in-memory database, for tests. Every
+// connection to :memory: is a fresh database so only one is kept open.
+func ConnectMemory() (*sql.DB, error) {
+	db, err := Connect(":memory:")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// SupportsFTS5 reports whether the sqlite3 driver was compiled with FTS5,
+// which todo search depends on. Build with -tags sqlite_fts5 to enable it.
+func SupportsFTS5(db *sql.DB) bool {
+	var enabled int
+	err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&enabled)
+	return err == nil && enabled == 1
 }
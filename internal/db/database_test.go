@@ -1 +1,16 @@
 package db
+
+import "testing"
+
+func TestSupportsFTS5(t *testing.T) {
+	conn, err := ConnectMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Exec(`CREATE VIRTUAL TABLE probe USING fts5(x)`)
+	if SupportsFTS5(conn) != (err == nil) {
+		t.Errorf("SupportsFTS5 returned %v but creating an fts5 table returned %v", SupportsFTS5(conn), err)
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-todo/internal/models"
+	"go-todo/internal/services"
+	"html"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The JSON API lives under /api/v1. Its handlers return errors like every
+// other handler, JSONErrors turns them into JSON error bodies.
+
+const (
+	apiDefaultPerPage = 25
+	apiMaxPerPage     = 100
+	apiMaxBodyBytes   = 1 << 20
+)
+
+type apiErrorBody struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Status  int                 `json:"status"`
+	Message string              `json:"message"`
+	Fields  map[string][]string `json:"fields,omitempty"`
+}
+
+type apiTodo struct {
+	ID          int        `json:"id"`
+	ListID      int        `json:"list_id"`
+	Description string     `json:"description"`
+	IsComplete  bool       `json:"is_complete"`
+	IsOverdue   bool       `json:"is_overdue"`
+	IsArchived  bool       `json:"is_archived"` // over the plan limit, read only
+	DueAt       *time.Time `json:"due_at"`
+}
+
+// descriptions are stored html escaped for the templates
+func newAPITodo(todo *models.Todo) apiTodo {
+	return apiTodo{
+		ID:          todo.ID,
+		ListID:      todo.ListID,
+		Description: html.UnescapeString(todo.Description),
+		IsComplete:  todo.IsComplete,
+		IsOverdue:   todo.IsOverdue(time.Now()),
+		IsArchived:  todo.IsArchived(),
+		DueAt:       todo.DueAt,
+	}
+}
+
+type apiPagination struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+func newAPIPagination(page, perPage, total int) apiPagination {
+	return apiPagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+}
+
+type apiPlan struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	TodoLimit   int    `json:"todo_limit"` // 0 means unlimited
+	ListLimit   int    `json:"list_limit"`
+	Todos       int    `json:"todos"`
+	Archived    int    `json:"archived_todos"`
+	Lists       int    `json:"lists"`
+	Attachments bool   `json:"attachments"`
+	APIAccess   bool   `json:"api_access"`
+}
+
+type apiUser struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Email         string   `json:"email"`
+	TimeZone      string   `json:"time_zone"`
+	IsPaidUser    bool     `json:"is_paid_user"`
+	Roles         []string `json:"roles"`
+	Plan          apiPlan  `json:"plan"`
+	CanCreateTodo bool     `json:"can_create_todo"`
+}
+
+func newAPIUser(user *models.User, stats *models.UserStats, plan *models.Plan, canCreateTodo bool) apiUser {
+	roles := []string{}
+	for name := range user.Roles {
+		roles = append(roles, name)
+	}
+	sort.Strings(roles)
+
+	return apiUser{
+		ID:         user.ID,
+		Name:       html.UnescapeString(user.Name),
+		Email:      html.UnescapeString(user.Email),
+		TimeZone:   user.TimeZone,
+		IsPaidUser: user.IsPaidUser,
+		Roles:      roles,
+		Plan: apiPlan{
+			ID:          plan.ID,
+			Name:        plan.Name,
+			TodoLimit:   plan.Entitlements.MaxTodos,
+			ListLimit:   plan.Entitlements.MaxLists,
+			Todos:       stats.TodoCount,
+			Archived:    stats.ArchivedTodoCount,
+			Lists:       stats.ListCount,
+			Attachments: plan.Entitlements.Attachments,
+			APIAccess:   plan.Entitlements.APIAccess,
+		},
+		CanCreateTodo: canCreateTodo,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(body)
+}
+
+// writeAPIValidationErrors responds 422 with the invalid fields, fields
+// without errors are left out.
+func writeAPIValidationErrors(w http.ResponseWriter, fields map[string][]string) error {
+	for field, fieldErrors := range fields {
+		if len(fieldErrors) == 0 {
+			delete(fields, field)
+		}
+	}
+	return writeJSON(w, http.StatusUnprocessableEntity, apiErrorBody{apiError{
+		Status:  http.StatusUnprocessableEntity,
+		Message: "The request contains invalid fields",
+		Fields:  fields,
+	}})
+}
+
+// JSONErrors is API middleware that writes the errors returned by the
+// handlers it wraps as JSON. Client errors keep their code and message,
+// anything else is logged and reported as an internal server error.
+func (h *Handler) JSONErrors(next HandleFunc) HandleFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		err := next(w, r)
+		if err == nil {
+			return nil
+		}
+
+		var clientError *services.ClientError
+		if errors.As(err, &clientError) && clientError.Code != 0 {
+			return writeJSON(w, clientError.Code, apiErrorBody{apiError{
+				Status:  clientError.Code,
+				Message: clientError.Message,
+			}})
+		}
+
+		h.logger.Error(fmt.Sprintf("%s %s failed", r.Method, r.URL.Path))
+		h.logger.Debug(err.Error())
+		return writeJSON(w, http.StatusInternalServerError, apiErrorBody{apiError{
+			Status:  http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		}})
+	}
+}
+
+// APINotFound answers API requests that match no route.
+func (h *Handler) APINotFound(w http.ResponseWriter, r *http.Request) error {
+	return services.NewClientError("No such API endpoint", http.StatusNotFound)
+}
+
+// decodeJSONBody reads a JSON request body into dst. Requiring the JSON
+// content type also keeps plain cross site form posts out of the API.
+func decodeJSONBody(r *http.Request, dst any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return services.NewClientError("Content-Type must be application/json", http.StatusUnsupportedMediaType)
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, apiMaxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return services.NewClientError(fmt.Sprintf("Invalid JSON body: %s", err.Error()), http.StatusBadRequest)
+	}
+	return nil
+}
+
+// apiIntParam parses an optional integer query parameter, falling back to
+// fallback when it is missing.
+func apiIntParam(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, services.NewClientError(fmt.Sprintf("%s must be a number", name), http.StatusBadRequest)
+	}
+	return parsed, nil
+}
+
+func apiTodoID(r *http.Request) (int, error) {
+	todoID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, services.NewClientError("Todo id must be a number", http.StatusBadRequest)
+	}
+	return todoID, nil
+}
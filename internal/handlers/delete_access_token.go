@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/services"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) RevokeAccessToken(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	tokenID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return services.NewClientError("Token id must be a number", http.StatusBadRequest)
+	}
+
+	clientError, err := h.service.RevokeAccessToken(user.ID, tokenID)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return asError(clientError)
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) revoked access token (%d)", user.ID, tokenID)
+	h.logger.Info(infoMsg)
+
+	return h.writeAccessTokens(w, user.ID, nil, user.Location(), nil)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (h *Handler) APIDeleteTodo(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	todoID, err := apiTodoID(r)
+	if err != nil {
+		return err
+	}
+
+	clientError, err := h.service.DeleteTodo(todoID, user)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return asError(clientError)
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) deleted todo (%d) through the api", user.ID, todoID)
+	h.logger.Info(infoMsg)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
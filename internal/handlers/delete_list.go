@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) DeleteList(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	listID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("path does not contain valid id %d", http.StatusBadRequest)
+	}
+
+	clientError, err := h.service.DeleteList(user.ID, listID)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return h.writeListSwitcher(w, user.ID, listID, []string{clientError.Message})
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) deleted list (%d)", user.ID, listID)
+	h.logger.Info(infoMsg)
+
+	return hxRedirect("/", w, r)
+}
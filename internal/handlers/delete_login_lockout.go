@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/services"
+	"net/http"
+	"strconv"
+)
+
+// DELETE /admin/lockouts/{id}
+func (h *Handler) UnlockLogin(w http.ResponseWriter, r *http.Request) error {
+	admin, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	lockoutID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return services.NewClientError("Invalid lockout id", http.StatusBadRequest)
+	}
+
+	clientError, err := h.service.UnlockLogin(lockoutID)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return asError(clientError)
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) lifted login lockout (%d)", admin.ID, lockoutID)
+	h.logger.Info(infoMsg)
+
+	// htmx removes the row
+	return nil
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// DELETE /settings/two-factor
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		return err
+	}
+
+	clientError, err := h.service.DisableTwoFactor(user, r.FormValue("code"))
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return h.writeTwoFactorPage(w, user, nil, []string{clientError.Message})
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) disabled two factor authentication", user.ID)
+	h.logger.Info(infoMsg)
+
+	return hxRedirect("/settings/two-factor", w, r)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/services"
+	"net/http"
+)
+
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
+	admin, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	userID := r.PathValue("user_id")
+	if userID == admin.ID {
+		return services.NewClientError("You cannot delete your own account from the admin area", http.StatusBadRequest)
+	}
+
+	user, err := h.service.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return services.NewClientError("That user does not exist", http.StatusNotFound)
+	}
+
+	err = h.service.DeleteUser(userID)
+	if err != nil {
+		return err
+	}
+
+	sessionCount, err := h.store.DeleteUserSessions(USER_SESSION, userID)
+	if err != nil {
+		// the user is already gone, their sessions now resolve to no user
+		h.logger.Error(fmt.Sprintf("Could not delete sessions for deleted user (%s)", userID))
+		h.logger.Debug(err.Error())
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) deleted user (%s) and %d sessions", admin.ID, userID, sessionCount)
+	h.logger.Info(infoMsg)
+
+	return hxRedirect("/admin/users", w, r)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// DELETE /settings/security/sessions/{id}
+/*
+	Signs one of the user's devices out. Signing out the device making the
+	request logs the user out here too.
+*/
+func (h *Handler) DeleteUserSession(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	session, err := h.store.Get(r, USER_SESSION)
+	if err != nil {
+		return err
+	}
+
+	sessionID := r.PathValue("id")
+
+	clientError, err := h.service.EndUserSession(user.ID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return asError(clientError)
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) signed out session (%s)", user.ID, sessionID)
+	h.logger.Info(infoMsg)
+
+	if sessionID == session.ID {
+		err = h.store.Delete(r, w, session)
+		if err != nil {
+			return err
+		}
+		return hxRedirect("/", w, r)
+	}
+
+	err = h.store.DeleteSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := h.service.GetActiveUserSessions(user.ID)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := h.render.UserSessions(renderer.NewUserSessionsProps(sessions, session.ID, user.Location()))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
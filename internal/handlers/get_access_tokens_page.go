@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+func (h *Handler) AccessTokensPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := h.service.GetUserAccessTokens(user.ID)
+	if err != nil {
+		return err
+	}
+
+	accessTokensProps := renderer.NewAccessTokensProps(tokens, nil, user.Location(), nil)
+	pageProps := renderer.NewAccessTokensPageProps(renderer.NewBasePageProps(user), accessTokensProps)
+
+	bytes, err := h.render.AccessTokensPage(pageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
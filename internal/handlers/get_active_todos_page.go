@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /todos/active
+/*
+	Lists every todo so the user can choose which stay active when they
+	have more than their plan allows
+*/
+func (h *Handler) ActiveTodosPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	return h.writeActiveTodosPage(w, user.ID, nil)
+}
+
+func (h *Handler) writeActiveTodosPage(w http.ResponseWriter, userID string, errors []string) error {
+	user, err := h.service.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	todos, err := h.service.GetAllUserTodos(user.ID)
+	if err != nil {
+		return err
+	}
+
+	lists, err := h.service.GetUserLists(user.ID)
+	if err != nil {
+		return err
+	}
+
+	_, todoLimit, err := h.service.GetTodoArchive(user.ID)
+	if err != nil {
+		return err
+	}
+
+	props := renderer.NewActiveTodosPageProps(renderer.NewBasePageProps(user), todos, lists, todoLimit, errors)
+	bytes, err := h.render.ActiveTodosPage(props)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
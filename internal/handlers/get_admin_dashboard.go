@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"net/http"
+	"time"
+)
+
+// the admin dashboard summarises the last week, the analytics page has more
+const adminDashboardDays = 7
+
+func (h *Handler) AdminDashboard(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	analytics, err := h.service.GetAnalytics(time.Now(), adminDashboardDays)
+	if err != nil {
+		return err
+	}
+
+	props := renderer.NewAdminDashboardProps(renderer.NewBasePageProps(user), analytics)
+	bytes, err := h.render.AdminDashboard(props)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
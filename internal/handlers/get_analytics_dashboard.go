@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"go-todo/internal/services"
+	"net/http"
+	"time"
+)
+
+func (h *Handler) AnalyticsDashboard(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	analytics, err := h.service.GetAnalytics(time.Now(), services.AnalyticsDays)
+	if err != nil {
+		return err
+	}
+
+	props := renderer.NewAnalyticsPageProps(renderer.NewBasePageProps(user), analytics)
+	bytes, err := h.render.AnalyticsPage(props)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
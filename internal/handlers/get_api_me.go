@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+)
+
+func (h *Handler) APIMe(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	stats, err := h.service.GetUserStats(user.ID)
+	if err != nil {
+		return err
+	}
+
+	plan, err := h.service.GetUserPlan(user.ID)
+	if err != nil {
+		return err
+	}
+
+	canCreateNewTodo, err := h.service.UserHasEntitlement(user, models.EntitlementCreateTodo)
+	if err != nil {
+		return fmt.Errorf("Error determining whether user can create new todo %v", err)
+	}
+
+	return writeJSON(w, http.StatusOK, newAPIUser(user, stats, plan, canCreateNewTodo))
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+func (h *Handler) APIGetTodo(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	todoID, err := apiTodoID(r)
+	if err != nil {
+		return err
+	}
+
+	todo, clientError, err := h.service.GetTodoByID(todoID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return asError(clientError)
+	}
+
+	return writeJSON(w, http.StatusOK, newAPITodo(todo))
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"go-todo/internal/services"
+	"net/http"
+)
+
+func (h *Handler) APIListTodos(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	page, err := apiIntParam(r, "page", 1)
+	if err != nil {
+		return err
+	}
+
+	perPage, err := apiIntParam(r, "per_page", apiDefaultPerPage)
+	if err != nil {
+		return err
+	}
+
+	listID, err := apiIntParam(r, "list_id", 0)
+	if err != nil {
+		return err
+	}
+
+	if page < 1 || perPage < 1 || perPage > apiMaxPerPage {
+		return services.NewClientError("page must be at least 1 and per_page between 1 and 100", http.StatusBadRequest)
+	}
+
+	todos, total, clientError, err := h.service.ListTodos(user.ID, listID, page, perPage)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return asError(clientError)
+	}
+
+	body := struct {
+		Todos      []apiTodo     `json:"todos"`
+		Pagination apiPagination `json:"pagination"`
+	}{
+		Todos:      []apiTodo{},
+		Pagination: newAPIPagination(page, perPage, total),
+	}
+	for _, todo := range todos {
+		body.Todos = append(body.Todos, newAPITodo(todo))
+	}
+
+	return writeJSON(w, http.StatusOK, body)
+}
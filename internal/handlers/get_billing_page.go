@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /settings/billing
+/*
+	Shows the user's plan, when it renews and their invoices
+*/
+func (h *Handler) BillingPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	plan, err := h.service.GetUserPlan(user.ID)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := h.service.GetCurrentSubscription(user.ID)
+	if err != nil {
+		return err
+	}
+
+	invoices, err := h.service.GetInvoices(user.ID)
+	if err != nil {
+		return err
+	}
+
+	props := renderer.NewBillingPageProps(renderer.NewBasePageProps(user), plan, subscription, invoices, h.service.Plans())
+	bytes, err := h.render.BillingPage(props)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"net/http"
+	"strconv"
+)
+
+// GET /todo/edit/description/{id}
+/*
+	Swaps the todo for an input so its description can be edited in place
+*/
+func (h *Handler) EditTodoDescription(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	todoID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("path does not contain valid id %d", http.StatusBadRequest)
+	}
+
+	todo, clientError, err := h.service.GetTodoByID(todoID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+	}
+
+	// archived todos are read only
+	if todo.IsArchived() {
+		return fmt.Errorf("todo is archived:%d", http.StatusForbidden)
+	}
+
+	bytes, err := h.render.TodoEdit(renderer.NewTodoEditProps(todo, "", nil))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
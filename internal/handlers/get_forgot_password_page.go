@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"go-todo/internal/models"
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /forgot-password
+func (h *Handler) ForgotPasswordPage(w http.ResponseWriter, r *http.Request) error {
+	user, _ := r.Context().Value(userIDKey).(*models.User)
+	if user != nil {
+		return noCacheRedirect("/", w, r)
+	}
+
+	basePageProps := renderer.NewBasePageProps(nil)
+	forgotPasswordPageProps := renderer.NewForgotPasswordPageProps(basePageProps, false, nil)
+	bytes, err := h.render.ForgotPassword(forgotPasswordPageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
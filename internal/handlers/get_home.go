@@ -13,31 +13,77 @@ func (h *Handler) HomePage(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	// 0 selects the user's first list
+	return h.writeHomePage(w, r, user, 0)
+}
+
+func (h *Handler) writeHomePage(w http.ResponseWriter, r *http.Request, user *models.User, listID int) error {
+	var err error
 	canCreateNewTodo := false
+	canCreateNewList := false
 	var list []*models.Todo
+	var lists []*models.List
+	var current *models.List
+	archivedTodoCount, todoLimit := 0, 0
 
 	if user != nil {
 		/*
-			if user is logged in we need to get their todos
-			and whether they have permission to create a new todo
+			if user is logged in we need to get their lists, the todos
+			on the selected list and whether they have permission to
+			create a new todo or list
 		*/
-		list, err = h.service.GetUserTodoList(user.ID)
+		lists, err = h.service.GetUserLists(user.ID)
+		if err != nil {
+			return fmt.Errorf("could not get user lists, %w", err)
+		}
+
+		current = lists[0]
+		if listID != 0 {
+			current = nil
+			for _, l := range lists {
+				if l.ID == listID {
+					current = l
+				}
+			}
+		}
+
+		// not one of the user's lists
+		if current == nil {
+			return noCacheRedirect("/", w, r)
+		}
+
+		todos, clientError, err := h.service.GetUserTodoList(user.ID, current.ID)
 		if err != nil {
 			return fmt.Errorf("could not get user list of todos, %w", err)
 		}
 
-		canCreateNewTodo, err = h.service.UserCanCreateNewTodo(user, list)
+		if clientError != nil {
+			return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+		}
+		list = todos
+
+		canCreateNewTodo, err = h.service.UserHasEntitlement(user, models.EntitlementCreateTodo)
 		if err != nil {
 			return fmt.Errorf("cannot determine whether user can create new todo, %w", err)
 		}
 
+		canCreateNewList, err = h.service.UserHasEntitlement(user, models.EntitlementCreateList)
+		if err != nil {
+			return fmt.Errorf("cannot determine whether user can create new list, %w", err)
+		}
+
+		archivedTodoCount, todoLimit, err = h.service.GetTodoArchive(user.ID)
+		if err != nil {
+			return fmt.Errorf("could not get user's archived todos, %w", err)
+		}
 	}
 
 	basePageProps := renderer.NewBasePageProps(user)
-	todoListProps := renderer.NewTodoListProps(list, canCreateNewTodo, nil)
+	listSwitcherProps := renderer.NewListSwitcherProps(lists, current, canCreateNewList, nil)
+	todoListProps := renderer.NewTodoListProps(current, lists, list, user.Location(), canCreateNewTodo, nil)
 	noErrors := []string{}
 	loginFormProps := renderer.NewLoginFormProps(noErrors, noErrors)
-	homePageProps := renderer.NewHomePageProps(basePageProps, todoListProps, loginFormProps)
+	homePageProps := renderer.NewHomePageProps(basePageProps, listSwitcherProps, todoListProps, loginFormProps, archivedTodoCount, todoLimit)
 
 	bytes, err := h.render.HomePage(homePageProps)
 	if err != nil {
@@ -50,7 +96,7 @@ func (h *Handler) HomePage(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	if user != nil {
-		infoMsg := fmt.Sprintf("User (%s) loaded their todo list", user.ID)
+		infoMsg := fmt.Sprintf("User (%s) loaded their todo list (%d)", user.ID, current.ID)
 		h.logger.Info(infoMsg)
 	}
 	return nil
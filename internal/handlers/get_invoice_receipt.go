@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /settings/billing/invoices/{id}
+/*
+	A printable receipt for one of the user's invoices, downloaded as an
+	html file when ?download=1
+*/
+func (h *Handler) InvoiceReceipt(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	invoice, clientError, err := h.service.GetUserInvoice(user, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return asError(clientError)
+	}
+
+	props := renderer.NewReceiptPageProps(user, renderer.NewInvoiceProps(invoice, h.service.Plans()))
+	bytes, err := h.render.ReceiptPage(props)
+	if err != nil {
+		return err
+	}
+
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "receipt-"+invoice.ID+".html"))
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
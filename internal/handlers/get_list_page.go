@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) ListPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	listID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return noCacheRedirect("/", w, r)
+	}
+
+	return h.writeHomePage(w, r, user, listID)
+}
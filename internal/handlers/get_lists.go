@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /lists
+/*
+	Renders the list switcher partial for the logged in user
+*/
+func (h *Handler) Lists(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	lists, err := h.service.GetUserLists(user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get user lists, %w", err)
+	}
+
+	canCreateNewList, err := h.service.UserHasEntitlement(user, models.EntitlementCreateList)
+	if err != nil {
+		return fmt.Errorf("cannot determine whether user can create new list, %w", err)
+	}
+
+	listSwitcherProps := renderer.NewListSwitcherProps(lists, nil, canCreateNewList, nil)
+	bytes, err := h.render.ListSwitcher(listSwitcherProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /admin/lockouts
+func (h *Handler) LoginLockoutsPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	lockouts, err := h.service.GetLoginLockouts()
+	if err != nil {
+		return err
+	}
+
+	props := renderer.NewLoginLockoutsPageProps(renderer.NewBasePageProps(user), lockouts)
+	bytes, err := h.render.LoginLockoutsPage(props)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"go-todo/internal/models"
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /reset-password?token=
+func (h *Handler) ResetPasswordPage(w http.ResponseWriter, r *http.Request) error {
+	token := r.URL.Query().Get("token")
+
+	isValid, err := h.service.PasswordResetTokenIsValid(token)
+	if err != nil {
+		return err
+	}
+
+	var clientErrors *models.ResetPasswordClientErrors
+	if !isValid {
+		clientErrors = &models.ResetPasswordClientErrors{
+			TokenErrors: []string{"This password reset link has expired or was already used. Please request a new one."},
+		}
+	}
+
+	// the token is a secret, keep it out of the referer sent to other sites
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	basePageProps := renderer.NewBasePageProps(nil)
+	resetPasswordPageProps := renderer.NewResetPasswordPageProps(basePageProps, token, false, clientErrors)
+	bytes, err := h.render.ResetPassword(resetPasswordPageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
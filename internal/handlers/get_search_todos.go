@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GET /todo/search?q=...&list_id=...
+/*
+	Live search over all of the user's todos. An empty query renders the
+	list the search was started from again.
+*/
+func (h *Handler) SearchTodos(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	lists, err := h.service.GetUserLists(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if query == "" {
+		listID, err := strconv.Atoi(r.URL.Query().Get("list_id"))
+		if err != nil {
+			return fmt.Errorf("query does not contain valid list id %d", http.StatusBadRequest)
+		}
+
+		currentList, clientError, err := h.service.GetListByID(listID, user.ID)
+		if err != nil {
+			return err
+		}
+
+		if clientError != nil {
+			return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+		}
+
+		list, clientError, err := h.service.GetUserTodoList(user.ID, currentList.ID)
+		if err != nil {
+			return err
+		}
+
+		if clientError != nil {
+			return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+		}
+
+		todoListProps := renderer.NewTodoListProps(currentList, lists, list, user.Location(), false, nil)
+		bytes, err := h.render.Todos(todoListProps.Todos)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(bytes)
+		return err
+	}
+
+	results, err := h.service.SearchTodos(user.ID, query)
+	if err != nil {
+		return err
+	}
+
+	searchResultsProps := renderer.NewSearchResultsProps(query, results, lists, user.Location())
+	bytes, err := h.render.SearchResults(searchResultsProps)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(bytes); err != nil {
+		return err
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) searched their todos", user.ID)
+	h.logger.Info(infoMsg)
+	return nil
+}
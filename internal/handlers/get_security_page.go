@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+func (h *Handler) SecurityPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	session, err := h.store.Get(r, USER_SESSION)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := h.service.GetActiveUserSessions(user.ID)
+	if err != nil {
+		return err
+	}
+
+	userSessionsProps := renderer.NewUserSessionsProps(sessions, session.ID, user.Location())
+	pageProps := renderer.NewSecurityPageProps(renderer.NewBasePageProps(user), userSessionsProps)
+
+	bytes, err := h.render.SecurityPage(pageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
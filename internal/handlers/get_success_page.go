@@ -4,11 +4,6 @@ import (
 	"fmt"
 	"go-todo/internal/server/renderer"
 	"net/http"
-	"os"
-
-	checkoutsession "github.com/stripe/stripe-go/v75/checkout/session"
-
-	"github.com/stripe/stripe-go/v75"
 )
 
 func (h *Handler) SuccessPage(w http.ResponseWriter, r *http.Request) error {
@@ -23,44 +18,24 @@ func (h *Handler) SuccessPage(w http.ResponseWriter, r *http.Request) error {
 
 	checkoutSessionID := r.URL.Query().Get("session_id")
 
-	stripeKey := os.Getenv(STRIPE_API_KEY)
-
-	if stripeKey == "" {
-		return fmt.Errorf("could not access key(%s) from ENV", STRIPE_API_KEY)
-	}
-
-	stripe.Key = stripeKey
-
-	s, err := checkoutsession.Get(checkoutSessionID, nil)
-	if err != nil {
-		errMsg := "could get checkout session from stripe"
-		return fmt.Errorf(errMsg)
-	}
-	// handle error ?
-
-	// get user from db
-	user, err = h.service.GetUserByEmail(s.CustomerEmail)
+	paid, clientError, err := h.service.CompleteCheckout(user, checkoutSessionID)
 	if err != nil {
 		return err
 	}
 
-	err = h.service.AddStripeIDToUser(user.ID, s.Customer.ID)
-	if err != nil {
-		return err
+	if clientError != nil {
+		warningMsg := fmt.Sprintf("User (%s) returned from checkout session (%s) they could not complete. %s", user.ID, checkoutSessionID, clientError.Message)
+		h.logger.Warning(warningMsg)
+		return asError(clientError)
 	}
 
-	if s.PaymentStatus == "paid" {
-		err = h.service.UpdateUserPaymentStatus(user.ID, true)
-		if err != nil {
-			return err
-		}
-
-		infoMsg := fmt.Sprintf("User (%s) became a premium user", user.ID)
+	if !paid {
+		infoMsg := fmt.Sprintf("User (%s) returned from checkout session (%s) before their subscription started", user.ID, checkoutSessionID)
 		h.logger.Info(infoMsg)
 	}
 
 	basePageProps := renderer.NewBasePageProps(user)
-	successPageProps := renderer.NewSuccessPageProps(basePageProps)
+	successPageProps := renderer.NewSuccessPageProps(basePageProps, paid)
 	bytes, err := h.render.Success(successPageProps)
 	if err != nil {
 		return err
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"net/http"
+	"strconv"
+)
+
+// GET /todo/{id}
+/*
+	Renders a single todo, e.g. when an inline edit is cancelled
+*/
+func (h *Handler) GetTodo(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	todoID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("path does not contain valid id %d", http.StatusBadRequest)
+	}
+
+	todo, clientError, err := h.service.GetTodoByID(todoID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+	}
+
+	lists, err := h.service.GetUserLists(user.ID)
+	if err != nil {
+		return err
+	}
+
+	todoBytes, err := h.render.Todo(renderer.NewTodoProps(todo, lists, user.Location()))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(todoBytes)
+	return err
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /login/two-factor
+func (h *Handler) TwoFactorLoginPage(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.store.Get(r, USER_SESSION)
+	if err != nil {
+		return err
+	}
+
+	if pendingTwoFactorUserID(session) == "" {
+		return noCacheRedirect("/", w, r)
+	}
+
+	basePageProps := renderer.NewBasePageProps(nil)
+	twoFactorLoginPageProps := renderer.NewTwoFactorLoginPageProps(basePageProps, nil)
+	bytes, err := h.render.TwoFactorLogin(twoFactorLoginPageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
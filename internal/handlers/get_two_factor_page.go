@@ -0,0 +1,17 @@
+package handlers
+
+import "net/http"
+
+// GET /settings/two-factor
+/*
+	Shows users without two factor a QR code to scan, or how many
+	recovery codes are left once it is enabled.
+*/
+func (h *Handler) TwoFactorPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	return h.writeTwoFactorPage(w, user, nil, nil)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"go-todo/internal/config"
 	"go-todo/internal/models"
 	"go-todo/internal/server/renderer"
 	"net/http"
@@ -20,8 +21,19 @@ func (h *Handler) UpgradePage(w http.ResponseWriter, r *http.Request) error {
 		h.logger.Info(infoMsg)
 	}()
 
+	currentPlan, err := h.service.GetUserPlan(user.ID)
+	if err != nil {
+		return err
+	}
+
+	trialEligible, err := h.service.IsTrialEligible(user.ID)
+	if err != nil {
+		return err
+	}
+
 	basePageProps := renderer.NewBasePageProps(user)
-	upgradePageProps := renderer.NewUpgradePageProps(basePageProps)
+	mustVerifyEmail := !user.EmailIsVerified() && h.config.UnverifiedEmailRestricts(config.RestrictCheckout)
+	upgradePageProps := renderer.NewUpgradePageProps(basePageProps, h.service.Plans(), currentPlan, trialEligible, mustVerifyEmail)
 	upgradePageBytes, err := h.render.Upgrade(upgradePageProps)
 	if err != nil {
 		return err
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"go-todo/internal/models"
+	"go-todo/internal/server/renderer"
+	"go-todo/internal/services"
+	"net/http"
+)
+
+func (h *Handler) UserProfilePage(w http.ResponseWriter, r *http.Request) error {
+	admin, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.service.GetUserByID(r.PathValue("user_id"))
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return services.NewClientError("That user does not exist", http.StatusNotFound)
+	}
+
+	stats, err := h.service.GetUserStats(user.ID)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := h.service.GetCurrentSubscription(user.ID)
+	if err != nil {
+		return err
+	}
+
+	formProps := renderer.NewAdminUserFormProps(user, admin.HasPermission(models.PermissionUsersManage), false, nil)
+	pageProps := renderer.NewAdminUserPageProps(renderer.NewBasePageProps(admin), stats, subscription, formProps)
+
+	bytes, err := h.render.AdminUserPage(pageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"go-todo/internal/server/renderer"
+	"go-todo/internal/services"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) UsersPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query().Get("q")
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	users, total, err := h.service.ListUsers(query, page)
+	if err != nil {
+		return err
+	}
+
+	tableProps := renderer.NewAdminUsersTableProps(users, query, total, page, services.UsersPageSize)
+
+	// searching swaps just the table
+	if r.Header.Get("HX-Request") == "true" {
+		bytes, err := h.render.AdminUsersTable(tableProps)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes)
+		return err
+	}
+
+	pageProps := renderer.NewAdminUsersPageProps(renderer.NewBasePageProps(user), tableProps)
+	bytes, err := h.render.AdminUsersPage(pageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// GET /verify-email?token=
+/*
+	Opened from the verification email, possibly in a browser the user
+	is not logged in on.
+*/
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) error {
+	user, _ := r.Context().Value(userIDKey).(*models.User)
+
+	// the token is a secret, keep it out of the referer sent to other sites
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	userID, clientError, err := h.service.VerifyEmail(r.URL.Query().Get("token"), []byte(h.config.SecretKey))
+	if err != nil {
+		return err
+	}
+
+	errors := []string{}
+	if clientError != nil {
+		w.WriteHeader(clientError.Code)
+		errors = append(errors, clientError.Message)
+	} else {
+		h.logger.Info(fmt.Sprintf("User (%s) verified their email", userID))
+
+		// show the header without the verification banner
+		if user != nil && user.ID == userID {
+			user, err = h.service.GetUserByID(userID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	basePageProps := renderer.NewBasePageProps(user)
+	verifyEmailPageProps := renderer.NewVerifyEmailPageProps(basePageProps, errors)
+	bytes, err := h.render.VerifyEmail(verifyEmailPageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
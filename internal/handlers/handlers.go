@@ -1,41 +1,135 @@
 package handlers
 
 import (
-	"errors"
 	"fmt"
+	"go-todo/internal/config"
 	"go-todo/internal/logger"
 	"go-todo/internal/models"
 	"go-todo/internal/server/renderer"
+	"go-todo/internal/server/sessionstore"
 	"go-todo/internal/services"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/sessions"
-	"github.com/michaeljs1990/sqlitestore"
 )
 
 type Handler struct {
 	service *services.Service
-	store   *sqlitestore.SqliteStore
+	store   sessionstore.Store
 	render  *renderer.Renderer
 	logger  *logger.Logger
+	config  *config.Config
 }
 
 type HandleFunc func(w http.ResponseWriter, r *http.Request) error
 type MiddleWareFunc func(next HandleFunc) HandleFunc
 
-const STRIPE_API_KEY = "STRIPE_API_KEY"
 const USER_SESSION = "user-session"
-const STRIPE_WEBHOOK_SECRET = "STRIPE_WEBHOOK_SECRET"
 
-func NewHandler(service *services.Service, store *sqlitestore.SqliteStore, renderer *renderer.Renderer, logger *logger.Logger) *Handler {
+func NewHandler(service *services.Service, store sessionstore.Store, renderer *renderer.Renderer, logger *logger.Logger, config *config.Config) *Handler {
 	return &Handler{
 		service: service,
 		store:   store,
 		render:  renderer,
 		logger:  logger,
+		config:  config,
 	}
 }
 
+// a login waiting for its second factor keeps the user's id under
+// pendingUserKey rather than "user", so the session is not logged in yet
+const (
+	pendingUserKey        = "pending_user"
+	pendingUserAtKey      = "pending_user_at"
+	pendingUserAttemptKey = "pending_user_attempts"
+	pendingRememberKey    = "pending_remember"
+)
+
+// twoFactorLoginTimeout is how long the user has to enter their code, and
+// maxTwoFactorAttempts how many tries they get, before logging in again.
+const (
+	twoFactorLoginTimeout = 5 * time.Minute
+	maxTwoFactorAttempts  = 5
+)
+
+// pendingTwoFactorUserID returns the user waiting to enter their code, or
+// "" when the session has no login in progress or it timed out.
+func pendingTwoFactorUserID(s *sessions.Session) string {
+	userID, _ := s.Values[pendingUserKey].(string)
+	startedAt, _ := s.Values[pendingUserAtKey].(int64)
+	if userID == "" || time.Since(time.Unix(startedAt, 0)) > twoFactorLoginTimeout {
+		return ""
+	}
+	return userID
+}
+
+func clearPendingTwoFactor(s *sessions.Session) {
+	delete(s.Values, pendingUserKey)
+	delete(s.Values, pendingUserAtKey)
+	delete(s.Values, pendingUserAttemptKey)
+	delete(s.Values, pendingRememberKey)
+}
+
+// rememberMeMaxAge is how long, in seconds, a login with "remember me"
+// ticked lasts. Other logins last as long as the store's MaxAge.
+const rememberMeMaxAge = 30 * 24 * 60 * 60
+
+// startUserSession logs userID in on a new session, so a session id known
+// before logging in cannot be used afterwards, and records the device it
+// was started from.
+func (h *Handler) startUserSession(w http.ResponseWriter, r *http.Request, session *sessions.Session, userID string, remember bool) error {
+	if session.ID != "" {
+		err := h.store.DeleteSession(session.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for k := range session.Values {
+		delete(session.Values, k)
+	}
+	session.ID = ""
+	session.IsNew = true
+
+	options := h.store.Options()
+	if remember {
+		options.MaxAge = rememberMeMaxAge
+	}
+	session.Options = &options
+
+	session.Values["user"] = userID
+	err := session.Save(r, w)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(options.MaxAge) * time.Second)
+	return h.service.StartUserSession(models.NewUserSession(session.ID, userID, clientIP(r), r.UserAgent(), now, expiresAt))
+}
+
+// endAllUserSessions signs the user out on every device and returns how
+// many sessions were ended.
+func (h *Handler) endAllUserSessions(userID string) (int, error) {
+	sessionCount, err := h.store.DeleteUserSessions(USER_SESSION, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = h.service.EndAllUserSessions(userID)
+	if err != nil {
+		return 0, err
+	}
+	return sessionCount, nil
+}
+
+// sendVerificationEmail emails user a link to verify their address.
+func (h *Handler) sendVerificationEmail(user *models.User) (*services.ClientError, error) {
+	return h.service.SendVerificationEmail(user, h.config.Domain, []byte(h.config.SecretKey))
+}
+
 func (h *Handler) getUserFromSession(s *sessions.Session, err error) (*models.User, error) {
 	if err != nil {
 		return nil, err
@@ -60,6 +154,25 @@ func (h *Handler) getUserFromContext(r *http.Request) (*models.User, error) {
 	return user, nil
 }
 
+// clientIP is the address the request came from. Forwarded headers are
+// ignored as they can be set by anyone when not behind a proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// asError returns a service client error from a handler so the router
+// responds with its status code instead of a 500.
+func asError(clientError *services.ClientError) error {
+	if clientError == nil {
+		return nil
+	}
+	return clientError
+}
+
 func noCacheRedirect(path string, w http.ResponseWriter, r *http.Request) error {
 	// Set cache-control headers to prevent caching
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -71,39 +184,106 @@ func noCacheRedirect(path string, w http.ResponseWriter, r *http.Request) error
 	return nil
 }
 
-// func (h *Handler) Upgrade(w http.ResponseWriter, r *http.Request) {
+// hxRedirect sends htmx requests to path with a full page load, falling
+// back to a regular redirect for plain form posts.
+func hxRedirect(path string, w http.ResponseWriter, r *http.Request) error {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", path)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return noCacheRedirect(path, w, r)
+}
 
-// 	// stripe code
+// writeListSwitcher rerenders the list switcher, e.g. after a list was
+// renamed or a list action was rejected.
+func (h *Handler) writeListSwitcher(w http.ResponseWriter, userID string, currentListID int, listErrors []string) error {
+	user, err := h.service.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
 
-// }
+	lists, err := h.service.GetUserLists(userID)
+	if err != nil {
+		return fmt.Errorf("could not get user lists, %w", err)
+	}
 
-func (h *Handler) AdminDashboard(w http.ResponseWriter, r *http.Request) error {
-	return errors.New("not implemented")
-}
+	var current *models.List
+	for _, l := range lists {
+		if l.ID == currentListID {
+			current = l
+		}
+	}
 
-func (h *Handler) AnalyticsDashboard(w http.ResponseWriter, r *http.Request) error {
-	return errors.New("not implemented")
-}
+	canCreateNewList, err := h.service.UserHasEntitlement(user, models.EntitlementCreateList)
+	if err != nil {
+		return fmt.Errorf("cannot determine whether user can create new list, %w", err)
+	}
+
+	listSwitcherProps := renderer.NewListSwitcherProps(lists, current, canCreateNewList, listErrors)
+	bytes, err := h.render.ListSwitcher(listSwitcherProps)
+	if err != nil {
+		return err
+	}
 
-func (h *Handler) UsersPage(w http.ResponseWriter, r *http.Request) error {
-	// h.service.GetUsers()
-	return errors.New("not implemented")
+	_, err = w.Write(bytes)
+	return err
 }
 
-func (h *Handler) UserProfilePage(w http.ResponseWriter, r *http.Request) error {
-	_, err := h.service.GetUserByID(r.PathValue("user_id"))
+// writeAccessTokens rerenders the user's tokens, showing newToken's secret
+// when one was just created.
+func (h *Handler) writeAccessTokens(w http.ResponseWriter, userID string, newToken *models.AccessToken, loc *time.Location, clientErrors *models.CreateAccessTokenClientErrors) error {
+	tokens, err := h.service.GetUserAccessTokens(userID)
+	if err != nil {
+		return err
+	}
+
+	accessTokensProps := renderer.NewAccessTokensProps(tokens, newToken, loc, clientErrors)
+	bytes, err := h.render.AccessTokens(accessTokensProps)
 	if err != nil {
 		return err
 	}
-	return nil
-}
 
-func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) error {
-	// h.service.GetUsers()
-	return errors.New("not implemented")
+	_, err = w.Write(bytes)
+	return err
 }
 
-func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
-	// h.service.GetUsers()
-	return errors.New("not implemented")
+// writeTwoFactorPage renders the two factor settings page, showing
+// recoveryCodes when two factor was just enabled.
+func (h *Handler) writeTwoFactorPage(w http.ResponseWriter, user *models.User, recoveryCodes []string, codeErrors []string) error {
+	var enrollment *models.TwoFactorEnrollment
+	unusedRecoveryCodes := 0
+
+	if user.TwoFactorEnabled() {
+		count, err := h.service.CountUnusedRecoveryCodes(user.ID)
+		if err != nil {
+			return err
+		}
+		unusedRecoveryCodes = count
+	} else {
+		e, clientError, err := h.service.BeginTwoFactorEnrollment(user)
+		if err != nil {
+			return err
+		}
+		if clientError != nil {
+			return asError(clientError)
+		}
+		enrollment = e
+	}
+
+	basePageProps := renderer.NewBasePageProps(user)
+	twoFactorPageProps := renderer.NewTwoFactorPageProps(basePageProps, enrollment, recoveryCodes, unusedRecoveryCodes, codeErrors)
+	bytes, err := h.render.TwoFactor(twoFactorPageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
 }
+
+// func (h *Handler) Upgrade(w http.ResponseWriter, r *http.Request) {
+
+// 	// stripe code
+
+// }
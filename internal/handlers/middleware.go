@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"go-todo/internal/models"
+	"go-todo/internal/server/sessionstore"
+	"go-todo/internal/services"
 	"net/http"
+	"strings"
 )
 
 type key string
 
 const userIDKey key = "user"
+const accessTokenKey key = "access-token"
 
 func (h *Handler) PathLogger(next HandleFunc) HandleFunc {
 	return func(w http.ResponseWriter, r *http.Request) error {
@@ -20,23 +24,86 @@ func (h *Handler) PathLogger(next HandleFunc) HandleFunc {
 
 func (h *Handler) UserMustBeAdmin(next HandleFunc) HandleFunc {
 	return func(w http.ResponseWriter, r *http.Request) error {
-		user := r.Context().Value(userIDKey).(*models.User)
+		user, _ := r.Context().Value(userIDKey).(*models.User)
 
-		if _, ok := user.Roles["admin"]; !ok {
-			return fmt.Errorf("user must be admin")
+		if !user.HasRole(models.RoleAdmin) {
+			return services.NewClientError("user must be admin", http.StatusForbidden)
 		}
 
 		return next(w, r)
 	}
 }
 
+// UserMustHavePermission returns middleware that only lets through users
+// with a role granting permission, e.g. models.PermissionUsersManage.
+func (h *Handler) UserMustHavePermission(permission string) MiddleWareFunc {
+	return func(next HandleFunc) HandleFunc {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			user, _ := r.Context().Value(userIDKey).(*models.User)
+
+			if !user.HasPermission(permission) {
+				if user != nil {
+					warningMsg := fmt.Sprintf("User (%s) denied %s %s, missing permission %s", user.ID, r.Method, r.URL.Path, permission)
+					h.logger.Warning(warningMsg)
+				}
+				return services.NewClientError("You do not have permission to do that", http.StatusForbidden)
+			}
+
+			return next(w, r)
+		}
+	}
+}
+
+// UserMustBeEntitled returns middleware that stops users whose plan does
+// not grant entitlement.
+func (h *Handler) UserMustBeEntitled(entitlement string) MiddleWareFunc {
+	return func(next HandleFunc) HandleFunc {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			user, err := h.getUserFromContext(r)
+			if err != nil {
+				return err
+			}
+
+			entitled, err := h.service.UserHasEntitlement(user, entitlement)
+			if err != nil {
+				return err
+			}
+
+			if !entitled {
+				infoMsg := fmt.Sprintf("User (%s) denied %s %s, their plan does not include %s", user.ID, r.Method, r.URL.Path, entitlement)
+				h.logger.Info(infoMsg)
+				return services.NewClientError("Your plan does not include that, upgrade to use it", http.StatusForbidden)
+			}
+
+			return next(w, r)
+		}
+	}
+}
+
+// UserMustHaveVerifiedEmail returns middleware that stops users who have
+// not verified their email taking action, when the
+// UNVERIFIED_EMAIL_RESTRICTIONS policy restricts it.
+func (h *Handler) UserMustHaveVerifiedEmail(action string) MiddleWareFunc {
+	return func(next HandleFunc) HandleFunc {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			user, _ := r.Context().Value(userIDKey).(*models.User)
+
+			if user != nil && !user.EmailIsVerified() && h.config.UnverifiedEmailRestricts(action) {
+				return services.NewClientError("Please verify your email address first", http.StatusForbidden)
+			}
+
+			return next(w, r)
+		}
+	}
+}
+
 func (h *Handler) UserMustBeLoggedIn(next HandleFunc) HandleFunc {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		// do something
 
 		user, ok := r.Context().Value(userIDKey).(*models.User)
 		if !ok || user == nil {
-			return fmt.Errorf("user must be logged in")
+			return services.NewClientError("user must be logged in", http.StatusUnauthorized)
 		}
 
 		return next(w, r)
@@ -45,12 +112,69 @@ func (h *Handler) UserMustBeLoggedIn(next HandleFunc) HandleFunc {
 
 func (h *Handler) AddUserToContext(next HandleFunc) HandleFunc {
 	return func(w http.ResponseWriter, r *http.Request) error {
-		user, err := h.getUserFromSession(h.store.Get(r, USER_SESSION))
+		session, err := h.store.Get(r, USER_SESSION)
+		user, err := h.getUserFromSession(session, err)
 		if err != nil {
 			return fmt.Errorf("could not get user from session in middleware, %w", err)
 		}
 
+		if user != nil {
+			active, err := h.service.TouchUserSession(session.ID, user.ID, clientIP(r), sessionstore.ExpiresAt(session))
+			if err != nil {
+				return err
+			}
+
+			if !active {
+				infoMsg := fmt.Sprintf("Session (%s) for user (%s) was signed out, logging out", session.ID, user.ID)
+				h.logger.Info(infoMsg)
+
+				err = h.store.Delete(r, w, session)
+				if err != nil {
+					return err
+				}
+				user = nil
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey, user)
+
+		return next(w, r.WithContext(ctx))
+	}
+}
+
+// AddUserFromAccessToken authenticates requests that send a personal access
+// token in an "Authorization: Bearer" header, putting the token's user in
+// the context just like AddUserToContext. Requests without the header are
+// passed through untouched. Read only tokens may only make GET requests.
+func (h *Handler) AddUserFromAccessToken(next HandleFunc) HandleFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" {
+			return next(w, r)
+		}
+
+		secret, ok := strings.CutPrefix(authorization, "Bearer ")
+		if !ok {
+			return services.NewClientError("Authorization header must be a Bearer token", http.StatusUnauthorized)
+		}
+
+		user, token, err := h.service.AuthenticateAccessToken(strings.TrimSpace(secret))
+		if err != nil {
+			return err
+		}
+
+		if user == nil {
+			return services.NewClientError("Invalid or revoked access token", http.StatusUnauthorized)
+		}
+
+		if !token.CanWrite() && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			warningMsg := fmt.Sprintf("User (%s) used read only token (%d) for %s %s", user.ID, token.ID, r.Method, r.URL.Path)
+			h.logger.Warning(warningMsg)
+			return services.NewClientError("This access token is read only", http.StatusForbidden)
+		}
+
 		ctx := context.WithValue(r.Context(), userIDKey, user)
+		ctx = context.WithValue(ctx, accessTokenKey, token)
 
 		return next(w, r.WithContext(ctx))
 	}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"go-todo/internal/services"
+	"net/http"
+)
+
+// fields left out of the request body are not changed
+type apiUpdateTodoRequest struct {
+	Description *string `json:"description"`
+	IsComplete  *bool   `json:"is_complete"`
+	ListID      *int    `json:"list_id"`
+	DueAt       *string `json:"due_at"`
+}
+
+func (h *Handler) APIUpdateTodo(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	todoID, err := apiTodoID(r)
+	if err != nil {
+		return err
+	}
+
+	var req apiUpdateTodoRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return err
+	}
+
+	update := models.TodoUpdate{
+		Description: req.Description,
+		IsComplete:  req.IsComplete,
+		ListID:      req.ListID,
+		DueAt:       req.DueAt,
+	}
+
+	todo, clientErrors, err := h.service.UpdateTodo(user.ID, todoID, update, user.Location())
+	if err != nil {
+		return err
+	}
+
+	if clientErrors != nil {
+		if len(clientErrors.TodoErrors) > 0 {
+			code := http.StatusNotFound
+			if todo != nil && todo.IsArchived() {
+				code = http.StatusForbidden
+			}
+			return services.NewClientError(clientErrors.TodoErrors[0], code)
+		}
+		return writeAPIValidationErrors(w, map[string][]string{
+			"list_id":     clientErrors.ListErrors,
+			"description": clientErrors.DescriptionErrors,
+			"due_at":      clientErrors.DueAtErrors,
+		})
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) updated todo (%d) through the api", user.ID, todo.ID)
+	h.logger.Info(infoMsg)
+
+	return writeJSON(w, http.StatusOK, newAPITodo(todo))
+}
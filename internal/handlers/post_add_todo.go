@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"fmt"
+	"go-todo/internal/models"
 	"go-todo/internal/server/renderer"
 	"net/http"
+	"strconv"
 )
 
 func (h *Handler) AddTodo(w http.ResponseWriter, r *http.Request) error {
@@ -22,24 +24,55 @@ func (h *Handler) AddTodo(w http.ResponseWriter, r *http.Request) error {
 		return h.Logout(w, r)
 	}
 
-	// TODO render client errors
-	_, clientErrors, err := h.service.CreateTodo(user.ID, r.FormValue("description"))
-	// TODO for now i am returning an error if todo is nil
+	listID, err := strconv.Atoi(r.FormValue("list_id"))
+	if err != nil {
+		return fmt.Errorf("form does not contain valid list id %d", http.StatusBadRequest)
+	}
+
+	currentList, clientError, err := h.service.GetListByID(listID, user.ID)
 	if err != nil {
 		return err
 	}
 
-	list, err := h.service.GetUserTodoList(user.ID)
+	if clientError != nil {
+		return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+	}
+
+	canCreateNewTodo, err := h.service.UserHasEntitlement(user, models.EntitlementCreateTodo)
+	if err != nil {
+		return (fmt.Errorf("Error determining whether user can create new todo %v", err))
+	}
+
+	// the form is hidden at the limit but the request can still be replayed
+	var todo *models.Todo
+	var clientErrors *models.CreateTodoClientErrors
+	if canCreateNewTodo {
+		todo, clientErrors, err = h.service.CreateTodo(user.ID, currentList.ID, r.FormValue("description"), r.FormValue("due_at"), user.Location())
+		if err != nil {
+			return err
+		}
+	}
+
+	list, clientError, err := h.service.GetUserTodoList(user.ID, currentList.ID)
 	if err != nil {
 		return fmt.Errorf("Error getting todo list at add todo. %v", err)
 	}
 
-	canCreateNewTodo, err := h.service.UserCanCreateNewTodo(user, list)
+	if clientError != nil {
+		return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+	}
+
+	lists, err := h.service.GetUserLists(user.ID)
+	if err != nil {
+		return fmt.Errorf("Error getting lists at add todo. %v", err)
+	}
+
+	canCreateNewTodo, err = h.service.UserHasEntitlement(user, models.EntitlementCreateTodo)
 	if err != nil {
 		return (fmt.Errorf("Error determining whether user can create new todo %v", err))
 	}
 
-	todoListProps := renderer.NewTodoListProps(list, canCreateNewTodo, clientErrors)
+	todoListProps := renderer.NewTodoListProps(currentList, lists, list, user.Location(), canCreateNewTodo, clientErrors)
 	todoList, err := h.render.TodoList(todoListProps)
 	if err != nil {
 		return err
@@ -49,7 +82,7 @@ func (h *Handler) AddTodo(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	if clientErrors == nil {
+	if todo != nil {
 		infoMsg := fmt.Sprintf("User (%s) added a todo", user.ID)
 		h.logger.Info(infoMsg)
 	}
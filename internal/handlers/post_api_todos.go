@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"go-todo/internal/services"
+	"net/http"
+)
+
+type apiCreateTodoRequest struct {
+	Description string `json:"description"`
+	ListID      int    `json:"list_id"`
+	DueAt       string `json:"due_at"`
+}
+
+func (h *Handler) APICreateTodo(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	var req apiCreateTodoRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return err
+	}
+
+	canCreateNewTodo, err := h.service.UserHasEntitlement(user, models.EntitlementCreateTodo)
+	if err != nil {
+		return fmt.Errorf("Error determining whether user can create new todo %v", err)
+	}
+
+	if !canCreateNewTodo {
+		return services.NewClientError("You have reached your plan's todo limit", http.StatusForbidden)
+	}
+
+	// todos go on the user's first list unless one is given
+	listID := req.ListID
+	if listID == 0 {
+		lists, err := h.service.GetUserLists(user.ID)
+		if err != nil {
+			return err
+		}
+		listID = lists[0].ID
+	}
+
+	todo, clientErrors, err := h.service.CreateTodo(user.ID, listID, req.Description, req.DueAt, user.Location())
+	if err != nil {
+		return err
+	}
+
+	if clientErrors != nil {
+		return writeAPIValidationErrors(w, map[string][]string{
+			"list_id":     clientErrors.ListErrors,
+			"description": clientErrors.DescriptionErrors,
+			"due_at":      clientErrors.DueAtErrors,
+		})
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) added a todo through the api", user.ID)
+	h.logger.Info(infoMsg)
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/todos/%d", todo.ID))
+	return writeJSON(w, http.StatusCreated, newAPITodo(todo))
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// POST /todos/active
+/*
+	Keeps the checked todos active and archives the rest
+*/
+func (h *Handler) ChooseActiveTodos(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		return fmt.Errorf("Error parsing form at choose active todos. %v", err)
+	}
+
+	todoIDs := []int{}
+	for _, value := range r.PostForm["todo_id"] {
+		todoID, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("form contains an invalid todo id:%d", http.StatusBadRequest)
+		}
+		todoIDs = append(todoIDs, todoID)
+	}
+
+	clientError, err := h.service.ChooseActiveTodos(user, todoIDs)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		w.WriteHeader(clientError.Code)
+		return h.writeActiveTodosPage(w, user.ID, []string{clientError.Message})
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) chose %d active todos", user.ID, len(todoIDs))
+	h.logger.Info(infoMsg)
+	return noCacheRedirect("/", w, r)
+}
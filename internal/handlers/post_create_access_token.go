@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (h *Handler) CreateAccessToken(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return fmt.Errorf("Error parsing form at create access token. %v", err)
+	}
+
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	token, clientErrors, err := h.service.CreateAccessToken(user.ID, r.FormValue("name"), r.FormValue("scope"))
+	if err != nil {
+		return err
+	}
+
+	if token != nil {
+		infoMsg := fmt.Sprintf("User (%s) created %s access token (%d)", user.ID, token.Scope, token.ID)
+		h.logger.Info(infoMsg)
+	}
+
+	return h.writeAccessTokens(w, user.ID, token, user.Location(), clientErrors)
+}
@@ -3,10 +3,6 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"os"
-
-	"github.com/stripe/stripe-go/v75"
-	billingportalsession "github.com/stripe/stripe-go/v75/billingportal/session"
 )
 
 func (h *Handler) CreateCustomerPortalSession(w http.ResponseWriter, r *http.Request) error {
@@ -25,30 +21,16 @@ func (h *Handler) CreateCustomerPortalSession(w http.ResponseWriter, r *http.Req
 		return fmt.Errorf("Could not get user by id in createcustomerportalsession handler")
 	}
 
-	stripeKey := os.Getenv(STRIPE_API_KEY)
-	if stripeKey == "" {
-		// do something
-		return fmt.Errorf("no stripe key")
-	}
-
-	stripe.Key = stripeKey
-
-	domain := os.Getenv("DOMAIN")
-	if domain == "" {
-		return fmt.Errorf("no domain key")
-	}
-
-	params := &stripe.BillingPortalSessionParams{
-		Customer:  stripe.String(user.StripeCustomerID),
-		ReturnURL: stripe.String(domain),
+	portalURL, clientError, err := h.service.StartCustomerPortal(user)
+	if err != nil {
+		return err
 	}
 
-	s, err := billingportalsession.New(params)
-	if err != nil {
-		return fmt.Errorf("Failed to create a billing portal session")
+	if clientError != nil {
+		return asError(clientError)
 	}
 
-	http.Redirect(w, r, s.URL, http.StatusSeeOther)
+	http.Redirect(w, r, portalURL, http.StatusSeeOther)
 
 	infoMsg := fmt.Sprintf("User (%s) visited their billing portal", user.ID)
 	h.logger.Info(infoMsg)
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (h *Handler) CreateList(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return fmt.Errorf("Error parsing form at create list. %v", err)
+	}
+
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	list, clientError, err := h.service.CreateList(user, r.FormValue("name"))
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return h.writeListSwitcher(w, user.ID, 0, []string{clientError.Message})
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) created list (%d)", user.ID, list.ID)
+	h.logger.Info(infoMsg)
+
+	return hxRedirect(fmt.Sprintf("/lists/%d", list.ID), w, r)
+}
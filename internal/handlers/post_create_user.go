@@ -16,10 +16,11 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) error {
 	name := r.FormValue("username")
 	email := r.FormValue("email")
 	password := r.FormValue("password")
+	timeZone := r.FormValue("time_zone")
 
 	// TODO sanitize and clean input
 
-	newUser, userErrors, err := h.service.NewUser(name, email, password)
+	newUser, userErrors, err := h.service.NewUser(name, email, password, timeZone)
 	if err != nil {
 		return err
 	}
@@ -41,6 +42,12 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) error {
 	infoMsg := fmt.Sprintf("New user (%s) created", newUser.ID)
 	h.logger.Info(infoMsg)
 
+	// the account is usable without the email, it can be resent later
+	_, err = h.sendVerificationEmail(newUser)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Could not send verification email to new user (%s). %s", newUser.ID, err))
+	}
+
 	return noCacheRedirect("/", w, r)
 
 }
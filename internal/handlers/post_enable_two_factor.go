@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// POST /settings/two-factor
+func (h *Handler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		return err
+	}
+
+	recoveryCodes, clientError, err := h.service.EnableTwoFactor(user, r.FormValue("code"))
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		w.WriteHeader(clientError.Code)
+		return h.writeTwoFactorPage(w, user, nil, []string{clientError.Message})
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) enabled two factor authentication", user.ID)
+	h.logger.Info(infoMsg)
+
+	user, err = h.service.GetUserByID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return h.writeTwoFactorPage(w, user, recoveryCodes, nil)
+}
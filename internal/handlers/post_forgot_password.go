@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// POST /forgot-password
+/*
+	Emails a reset link, through the outbox, when the address belongs to
+	a user. The page reads the same, and takes as long, either way so it
+	cannot be used to discover accounts. Requests are throttled per email
+	and per ip so it cannot be used to flood an inbox.
+*/
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return err
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+
+	emailErrors := []string{}
+	if email == "" {
+		emailErrors = append(emailErrors, "You must provide an email.")
+	} else {
+		ip := clientIP(r)
+		retryAfter, err := h.service.RequestPasswordReset(email, h.config.Domain, ip)
+		if err != nil {
+			return err
+		}
+
+		if retryAfter > 0 {
+			warningMsg := fmt.Sprintf("Password reset for %q from %s refused, locked out for %s", email, ip, retryAfter.Round(time.Second))
+			h.logger.Warning(warningMsg)
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			emailErrors = append(emailErrors, "Too many password reset requests, please try again later.")
+		} else {
+			h.logger.Info("Password reset requested")
+		}
+	}
+
+	basePageProps := renderer.NewBasePageProps(nil)
+	forgotPasswordPageProps := renderer.NewForgotPasswordPageProps(basePageProps, len(emailErrors) == 0, emailErrors)
+	bytes, err := h.render.ForgotPassword(forgotPasswordPageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"go-todo/internal/models"
 	"go-todo/internal/server/renderer"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // POST /login
@@ -37,17 +40,33 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	email, password := r.FormValue("email"), r.FormValue("password")
+	remember := r.FormValue("remember_me") == "on"
 
-	user, userErrors, err := h.service.Login(email, password)
+	ip := clientIP(r)
+
+	user, userErrors, err := h.service.Login(email, password, ip)
 	if err != nil {
 		return err
 	}
 
 	if userErrors != nil {
+		// audit failed logins, lockouts mean someone is guessing passwords
+		if userErrors.LockedFor > 0 {
+			warningMsg := fmt.Sprintf("Login for %q from %s refused, locked out for %s", email, ip, userErrors.LockedFor.Round(time.Second))
+			h.logger.Warning(warningMsg)
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(userErrors.LockedFor.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+		} else if len(userErrors.PasswordErrors) > 0 {
+			warningMsg := fmt.Sprintf("Failed login for %q from %s", email, ip)
+			h.logger.Warning(warningMsg)
+		}
+
 		loginFormProps := renderer.NewLoginFormProps(userErrors.EmailErrors, userErrors.PasswordErrors)
 		basePageProps := renderer.NewBasePageProps(nil)
-		todoListProps := renderer.NewTodoListProps([]*models.Todo{}, false, nil)
-		homePageProps := renderer.NewHomePageProps(basePageProps, todoListProps, loginFormProps)
+		listSwitcherProps := renderer.NewListSwitcherProps([]*models.List{}, nil, false, nil)
+		todoListProps := renderer.NewTodoListProps(nil, []*models.List{}, []*models.Todo{}, user.Location(), false, nil)
+		homePageProps := renderer.NewHomePageProps(basePageProps, listSwitcherProps, todoListProps, loginFormProps, 0, 0)
 		bytes, err := h.render.HomePage(homePageProps)
 		if err != nil {
 			return err
@@ -61,8 +80,29 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
-	session.Values["user"] = user.ID
-	err = session.Save(r, w)
+	// keep the user's time zone in step with the browser they log in from
+	err = h.service.UpdateUserTimeZone(user.ID, r.FormValue("time_zone"))
+	if err != nil {
+		return err
+	}
+
+	if user.TwoFactorEnabled() {
+		clearPendingTwoFactor(session)
+		session.Values[pendingUserKey] = user.ID
+		session.Values[pendingUserAtKey] = time.Now().Unix()
+		session.Values[pendingRememberKey] = remember
+		err = session.Save(r, w)
+		if err != nil {
+			return err
+		}
+
+		infoMsg := fmt.Sprintf("User (%s) entered their password, waiting for two factor code", user.ID)
+		h.logger.Info(infoMsg)
+
+		return noCacheRedirect("/login/two-factor", w, r)
+	}
+
+	err = h.startUserSession(w, r, session, user.ID, remember)
 	if err != nil {
 		return err
 	}
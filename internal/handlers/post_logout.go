@@ -12,6 +12,7 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	sessionID := session.ID
 	err = h.store.Delete(r, w, session)
 	if err != nil {
 		h.logger.Error("Could not delete user session")
@@ -19,9 +20,17 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	noCacheRedirect("/", w, r)
+	if user != nil {
+		// the session is gone so a missing record is not a problem
+		_, err = h.service.EndUserSession(user.ID, sessionID)
+		if err != nil {
+			return err
+		}
+
+		infoMsg := fmt.Sprintf("Session deleted for user (%s)", user.ID)
+		h.logger.Info(infoMsg)
+	}
 
-	infoMsg := fmt.Sprintf("Session deleted for user (%s)", user.ID)
-	h.logger.Info(infoMsg)
+	noCacheRedirect("/", w, r)
 	return nil
 }
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// POST /todo/move/{id}
+/*
+	Moves the todo to the list in the list_id form value. The todo is
+	removed from the list currently on screen.
+*/
+func (h *Handler) MoveTodo(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return fmt.Errorf("Error parsing form at move todo. %v", err)
+	}
+
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	todoID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("path does not contain valid id %d", http.StatusBadRequest)
+	}
+
+	listID, err := strconv.Atoi(r.FormValue("list_id"))
+	if err != nil {
+		return fmt.Errorf("form does not contain valid list id %d", http.StatusBadRequest)
+	}
+
+	todo, clientError, err := h.service.MoveTodo(user.ID, todoID, listID)
+	if err != nil {
+		return err
+	}
+
+	if clientError != nil {
+		return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+	}
+
+	if _, err := w.Write([]byte("")); err != nil {
+		return err
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) moved todo (%d) to list (%d)", user.ID, todo.ID, listID)
+	h.logger.Info(infoMsg)
+	return nil
+}
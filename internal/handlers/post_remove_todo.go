@@ -21,22 +21,14 @@ func (h *Handler) RemoveTodo(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	todo, clientError, internalError := h.service.GetTodoByID(todoID, user.ID)
+	// the service checks ownership, or the todos:delete:any permission
+	clientError, internalError := h.service.DeleteTodo(todoID, user)
 	if internalError != nil {
-		return err
-	}
-
-	if clientError != nil {
-		return fmt.Errorf("client err", clientError.Message)
-	}
-
-	clientError, internalError = h.service.DeleteTodo(todo.ID, user.ID)
-	if internalError != nil {
-		return err
+		return internalError
 	}
 
 	if clientError != nil {
-		return fmt.Errorf("%s:%d", clientError.Message, clientError.Code)
+		return asError(clientError)
 	}
 
 	if _, err := w.Write([]byte("")); err != nil {
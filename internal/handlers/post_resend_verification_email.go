@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// POST /verify-email/resend
+/*
+	Swaps in a message saying whether the email was sent, so the banner
+	explains why nothing was sent when the user asks too often.
+*/
+func (h *Handler) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	clientError, err := h.sendVerificationEmail(user)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("We've sent a new link to %s.", user.Email)
+	if clientError != nil {
+		message = clientError.Message
+	} else {
+		h.logger.Info(fmt.Sprintf("User (%s) resent their verification email", user.ID))
+	}
+
+	verifyEmailStatusProps := renderer.NewVerifyEmailStatusProps(message, clientError != nil)
+	bytes, err := h.render.VerifyEmailStatus(verifyEmailStatusProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
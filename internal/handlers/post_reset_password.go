@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"net/http"
+)
+
+// POST /reset-password
+/*
+	Sets the new password and logs the user out everywhere, including
+	the browser they reset it from.
+*/
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return err
+	}
+
+	token := r.FormValue("token")
+
+	userID, clientErrors, err := h.service.ResetPassword(token, r.FormValue("password"), r.FormValue("confirm_password"))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	if clientErrors == nil {
+		sessionCount, err := h.endAllUserSessions(userID)
+		if err != nil {
+			return err
+		}
+
+		h.logger.Info(fmt.Sprintf("User (%s) reset their password, ended %d session(s)", userID, sessionCount))
+	}
+
+	basePageProps := renderer.NewBasePageProps(nil)
+	resetPasswordPageProps := renderer.NewResetPasswordPageProps(basePageProps, token, clientErrors == nil, clientErrors)
+	bytes, err := h.render.ResetPassword(resetPasswordPageProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// POST /settings/security/sign-out-everywhere
+/*
+	Signs the user out on every device, including the one making the
+	request.
+*/
+func (h *Handler) SignOutEverywhere(w http.ResponseWriter, r *http.Request) error {
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	session, err := h.store.Get(r, USER_SESSION)
+	if err != nil {
+		return err
+	}
+
+	sessionCount, err := h.endAllUserSessions(user.ID)
+	if err != nil {
+		return err
+	}
+
+	// the stored session is already gone, this expires the cookie
+	err = h.store.Delete(r, w, session)
+	if err != nil {
+		return err
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) signed out everywhere, ended %d session(s)", user.ID, sessionCount)
+	h.logger.Info(infoMsg)
+
+	return hxRedirect("/", w, r)
+}
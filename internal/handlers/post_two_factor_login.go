@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// POST /login/two-factor
+/*
+	Finishes logging in a user with two factor enabled. Too many wrong
+	codes send them back to enter their password again, and lock the
+	user out of two factor logins for a while.
+*/
+func (h *Handler) TwoFactorLogin(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.store.Get(r, USER_SESSION)
+	if err != nil {
+		return err
+	}
+
+	userID := pendingTwoFactorUserID(session)
+	if userID == "" {
+		return noCacheRedirect("/", w, r)
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		return err
+	}
+
+	ok, lockedFor, err := h.service.VerifyTwoFactorLogin(userID, r.FormValue("code"))
+	if err != nil {
+		return err
+	}
+
+	// the lockout is kept against the user, the session count only sends
+	// them back to their password sooner
+	if lockedFor > 0 {
+		clearPendingTwoFactor(session)
+		err = session.Save(r, w)
+		if err != nil {
+			return err
+		}
+
+		warningMsg := fmt.Sprintf("Two factor login for user (%s) refused, locked out for %s", userID, lockedFor.Round(time.Second))
+		h.logger.Warning(warningMsg)
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedFor.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+
+		basePageProps := renderer.NewBasePageProps(nil)
+		twoFactorLoginPageProps := renderer.NewTwoFactorLoginPageProps(basePageProps, []string{"Too many wrong codes, please try again later."})
+		bytes, err := h.render.TwoFactorLogin(twoFactorLoginPageProps)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(bytes)
+		return err
+	}
+
+	if !ok {
+		attempts, _ := session.Values[pendingUserAttemptKey].(int)
+		attempts++
+		session.Values[pendingUserAttemptKey] = attempts
+
+		warningMsg := fmt.Sprintf("User (%s) entered a wrong two factor code (%d/%d)", userID, attempts, maxTwoFactorAttempts)
+		h.logger.Warning(warningMsg)
+
+		if attempts >= maxTwoFactorAttempts {
+			clearPendingTwoFactor(session)
+		}
+
+		err = session.Save(r, w)
+		if err != nil {
+			return err
+		}
+
+		if attempts >= maxTwoFactorAttempts {
+			return noCacheRedirect("/", w, r)
+		}
+
+		basePageProps := renderer.NewBasePageProps(nil)
+		twoFactorLoginPageProps := renderer.NewTwoFactorLoginPageProps(basePageProps, []string{"That code is not right, please try again."})
+		bytes, err := h.render.TwoFactorLogin(twoFactorLoginPageProps)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(bytes)
+		return err
+	}
+
+	remember, _ := session.Values[pendingRememberKey].(bool)
+	err = h.startUserSession(w, r, session, userID, remember)
+	if err != nil {
+		return err
+	}
+
+	infoMsg := fmt.Sprintf("Session created for user (%s) logged in with two factor", userID)
+	h.logger.Info(infoMsg)
+
+	return noCacheRedirect("/", w, r)
+}
@@ -1,10 +1,69 @@
 package handlers
 
 import (
-	"errors"
+	"fmt"
+	"go-todo/internal/server/renderer"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
+// POST /todo/update/description/{id}
+/*
+	Saves an inline edit. Responds with the updated todo, or with the edit
+	form and its errors when the description is invalid.
+*/
 func (h *Handler) UpdateTodoDescription(w http.ResponseWriter, r *http.Request) error {
-	return errors.New("UpdateTodoDescription not implemented")
+	err := r.ParseForm()
+	if err != nil {
+		return fmt.Errorf("Error parsing form at update todo description. %v", err)
+	}
+
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	todoID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("path does not contain valid id %d", http.StatusBadRequest)
+	}
+
+	description := r.FormValue("description")
+
+	todo, clientErrors, err := h.service.UpdateTodoDescription(user.ID, todoID, description)
+	if err != nil {
+		return err
+	}
+
+	if clientErrors != nil && len(clientErrors.TodoErrors) > 0 {
+		return fmt.Errorf("%s:%d", strings.Join(clientErrors.TodoErrors, ", "), http.StatusUnauthorized)
+	}
+
+	if clientErrors != nil {
+		bytes, err := h.render.TodoEdit(renderer.NewTodoEditProps(todo, description, clientErrors.DescriptionErrors))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bytes)
+		return err
+	}
+
+	lists, err := h.service.GetUserLists(user.ID)
+	if err != nil {
+		return err
+	}
+
+	todoBytes, err := h.render.Todo(renderer.NewTodoProps(todo, lists, user.Location()))
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(todoBytes); err != nil {
+		return err
+	}
+
+	infoMsg := fmt.Sprintf("User (%s) updated description of todo (%d)", user.ID, todo.ID)
+	h.logger.Info(infoMsg)
+	return nil
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"go-todo/internal/server/renderer"
 	"net/http"
 	"strconv"
 )
@@ -39,7 +40,12 @@ func (h *Handler) UpdateTodoStatus(w http.ResponseWriter, r *http.Request) error
 		return fmt.Errorf("service did not return a todo item")
 	}
 
-	todoBytes, err := h.render.Todo(todo)
+	lists, err := h.service.GetUserLists(user.ID)
+	if err != nil {
+		return err
+	}
+
+	todoBytes, err := h.render.Todo(renderer.NewTodoProps(todo, lists, user.Location()))
 	if err != nil {
 		return err
 	}
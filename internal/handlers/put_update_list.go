@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) UpdateList(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return fmt.Errorf("Error parsing form at update list. %v", err)
+	}
+
+	user, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	listID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return fmt.Errorf("path does not contain valid id %d", http.StatusBadRequest)
+	}
+
+	_, clientError, err := h.service.RenameList(user.ID, listID, r.FormValue("name"))
+	if err != nil {
+		return err
+	}
+
+	listErrors := []string{}
+	if clientError != nil {
+		listErrors = append(listErrors, clientError.Message)
+	} else {
+		infoMsg := fmt.Sprintf("User (%s) renamed list (%d)", user.ID, listID)
+		h.logger.Info(infoMsg)
+	}
+
+	return h.writeListSwitcher(w, user.ID, listID, listErrors)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"go-todo/internal/server/renderer"
+	"go-todo/internal/services"
+	"net/http"
+)
+
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return fmt.Errorf("Error parsing form at update user. %v", err)
+	}
+
+	admin, err := h.getUserFromContext(r)
+	if err != nil {
+		return err
+	}
+
+	userID := r.PathValue("user_id")
+	existing, err := h.service.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		return services.NewClientError("That user does not exist", http.StatusNotFound)
+	}
+
+	user, clientErrors, err := h.service.UpdateUser(userID, r.FormValue("name"), r.FormValue("email"))
+	if err != nil {
+		return err
+	}
+
+	if clientErrors == nil {
+		infoMsg := fmt.Sprintf("User (%s) updated user (%s)", admin.ID, userID)
+		h.logger.Info(infoMsg)
+	}
+
+	formProps := renderer.NewAdminUserFormProps(user, true, clientErrors == nil, clientErrors)
+	bytes, err := h.render.AdminUserForm(formProps)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+const fileMailerFrom = "go-todo <no-reply@localhost>"
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+// FileMailer writes each message to its own .eml file in dir instead of
+// sending it.
+type FileMailer struct {
+	dir string
+}
+
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{dir: dir}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return fmt.Errorf("Could not create mail directory %s. %w", m.dir, err)
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), unsafeFileNameChars.ReplaceAllString(msg.To, "_"))
+	path := filepath.Join(m.dir, name)
+
+	if err := os.WriteFile(path, format(fileMailerFrom, msg, now), 0644); err != nil {
+		return fmt.Errorf("Could not write email to %s. %w", path, err)
+	}
+	return nil
+}
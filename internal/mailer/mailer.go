@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"fmt"
+	"go-todo/internal/config"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email. SMTPMailer sends it for real, FileMailer writes
+// it to disk for local development and tests.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FromConfig builds the mailer config selects. "smtp" sends through
+// SMTPHost, anything else writes messages to Dir.
+func FromConfig(config config.Mail) (Mailer, error) {
+	if config.Mailer == "smtp" {
+		return NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.From)
+	}
+	return NewFileMailer(config.Dir), nil
+}
+
+// headerValue strips line breaks so values cannot inject extra headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// format renders msg with the headers both mailers write.
+func format(from string, msg Message, date time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
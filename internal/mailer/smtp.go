@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer sends mail through host:port, authenticating when a
+// username is given.
+func NewSMTPMailer(host, port, username, password, from string) (*SMTPMailer, error) {
+	if host == "" || from == "" {
+		return nil, errors.New("SMTP_HOST and MAIL_FROM are required to send email over SMTP")
+	}
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	err := smtp.SendMail(m.addr, m.auth, m.from, []string{headerValue(msg.To)}, format(m.from, msg, time.Now()))
+	if err != nil {
+		return fmt.Errorf("Could not send email to %s. %w", msg.To, err)
+	}
+	return nil
+}
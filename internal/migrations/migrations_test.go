@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	database "go-todo/internal/db"
+	"testing"
+	"testing/fstest"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := database.ConnectMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+var testFS = fstest.MapFS{
+	"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a(id INTEGER);")},
+	"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b(id INTEGER);")},
+	"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+}
+
+func TestUpDownStatus(t *testing.T) {
+	db := openTestDB(t)
+
+	m, err := NewMigratorFromFS(db, testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up()
+	if err != nil || applied != 2 {
+		t.Fatalf("expected 2 migrations applied, got %d (%v)", applied, err)
+	}
+
+	applied, err = m.Up()
+	if err != nil || applied != 0 {
+		t.Fatalf("expected second Up to be a no-op, got %d (%v)", applied, err)
+	}
+
+	if _, err := db.Exec("INSERT INTO b(id) VALUES (1)"); err != nil {
+		t.Fatalf("table b should exist: %v", err)
+	}
+
+	rolledBack, err := m.Down(1)
+	if err != nil || rolledBack != 1 {
+		t.Fatalf("expected 1 migration rolled back, got %d (%v)", rolledBack, err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("expected only 0001 applied, got %+v", statuses)
+	}
+}
+
+func TestCheckRefusesNewerDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	m, err := NewMigratorFromFS(db, testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	older, err := NewMigratorFromFS(db, fstest.MapFS{
+		"0001_create_a.up.sql": testFS["0001_create_a.up.sql"],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := older.Check(); !errors.Is(err, ErrDatabaseAhead) {
+		t.Errorf("expected ErrDatabaseAhead, got %v", err)
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	m, err := NewMigrator(openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Latest() < 1 {
+		t.Error("expected at least one embedded migration")
+	}
+}
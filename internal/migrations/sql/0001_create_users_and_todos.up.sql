@@ -1,3 +1,5 @@
+-- Mirrors the original sql/index.sql so databases created before migrations
+-- existed are adopted without changes.
 CREATE TABLE IF NOT EXISTS users(
     id TEXT PRIMARY KEY UNIQUE NOT NULL,
     name TEXT DEFAULT "",
@@ -12,4 +14,4 @@ CREATE TABLE IF NOT EXISTS todos(
     user_id TEXT NOT NULL,
     description TEXT NOT NULL DEFAULT "",
     is_complete BOOLEAN DEFAULT FALSE
-)
+);
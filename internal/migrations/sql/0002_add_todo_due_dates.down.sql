@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS todos_user_id_due_at;
+
+ALTER TABLE users DROP COLUMN time_zone;
+ALTER TABLE todos DROP COLUMN due_at;
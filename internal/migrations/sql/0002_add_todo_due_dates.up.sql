@@ -0,0 +1,5 @@
+-- due_at is stored in UTC; time_zone is used to interpret and render it
+ALTER TABLE todos ADD COLUMN due_at DATETIME;
+ALTER TABLE users ADD COLUMN time_zone TEXT NOT NULL DEFAULT "UTC";
+
+CREATE INDEX IF NOT EXISTS todos_user_id_due_at ON todos(user_id, due_at);
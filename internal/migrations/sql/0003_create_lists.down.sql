@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS todos_list_id;
+ALTER TABLE todos DROP COLUMN list_id;
+
+DROP INDEX IF EXISTS lists_user_id;
+DROP TABLE IF EXISTS lists;
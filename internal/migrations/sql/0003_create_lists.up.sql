@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS lists(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT "",
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS lists_user_id ON lists(user_id);
+
+ALTER TABLE todos ADD COLUMN list_id INTEGER REFERENCES lists(id);
+
+CREATE INDEX IF NOT EXISTS todos_list_id ON todos(list_id);
+
+-- every existing user gets a default list holding the todos they already have
+INSERT INTO lists(user_id, name) SELECT id, "My Todos" FROM users;
+
+UPDATE todos SET list_id = (SELECT lists.id FROM lists WHERE lists.user_id = todos.user_id)
+WHERE list_id IS NULL;
@@ -0,0 +1,22 @@
+-- requires SQLite built with FTS5 (go build -tags sqlite_fts5)
+CREATE VIRTUAL TABLE IF NOT EXISTS todos_fts USING fts5(
+    description,
+    content='todos',
+    content_rowid='id',
+    tokenize='porter unicode61'
+);
+
+INSERT INTO todos_fts(rowid, description) SELECT id, description FROM todos;
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_insert AFTER INSERT ON todos BEGIN
+    INSERT INTO todos_fts(rowid, description) VALUES (new.id, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_delete AFTER DELETE ON todos BEGIN
+    INSERT INTO todos_fts(todos_fts, rowid, description) VALUES ('delete', old.id, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_update AFTER UPDATE OF description ON todos BEGIN
+    INSERT INTO todos_fts(todos_fts, rowid, description) VALUES ('delete', old.id, old.description);
+    INSERT INTO todos_fts(rowid, description) VALUES (new.id, new.description);
+END;
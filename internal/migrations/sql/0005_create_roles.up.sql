@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS roles(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT ""
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions(
+    role_id INTEGER NOT NULL REFERENCES roles(id),
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles(
+    user_id TEXT NOT NULL,
+    role_id INTEGER NOT NULL REFERENCES roles(id),
+    granted_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles(name, description) VALUES
+    ("admin", "Full access to the admin area"),
+    ("support", "Can view users and remove todos on their behalf");
+
+INSERT INTO role_permissions(role_id, permission)
+SELECT id, permission FROM roles, (
+    SELECT "users:view" AS permission
+    UNION ALL SELECT "users:manage"
+    UNION ALL SELECT "todos:delete:any"
+    UNION ALL SELECT "analytics:view"
+) WHERE roles.name = "admin";
+
+INSERT INTO role_permissions(role_id, permission)
+SELECT id, permission FROM roles, (
+    SELECT "users:view" AS permission
+    UNION ALL SELECT "todos:delete:any"
+) WHERE roles.name = "support";
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS plan_changes_created_at;
+DROP INDEX IF EXISTS todos_completed_at;
+DROP INDEX IF EXISTS todos_created_at;
+DROP INDEX IF EXISTS users_created_at;
+
+DROP TABLE IF EXISTS plan_changes;
+
+ALTER TABLE todos DROP COLUMN completed_at;
+ALTER TABLE todos DROP COLUMN created_at;
+ALTER TABLE users DROP COLUMN created_at;
@@ -0,0 +1,20 @@
+-- timestamps are stored in UTC; rows created before this migration keep NULL
+-- because their real dates are unknown
+ALTER TABLE users ADD COLUMN created_at DATETIME;
+ALTER TABLE todos ADD COLUMN created_at DATETIME;
+ALTER TABLE todos ADD COLUMN completed_at DATETIME;
+
+-- every time a user moves between the free and paid plans. source is the
+-- stripe webhook event type, "checkout" or "admin"
+CREATE TABLE IF NOT EXISTS plan_changes(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    is_paid_user BOOLEAN NOT NULL,
+    source TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS users_created_at ON users(created_at);
+CREATE INDEX IF NOT EXISTS todos_created_at ON todos(created_at);
+CREATE INDEX IF NOT EXISTS todos_completed_at ON todos(completed_at);
+CREATE INDEX IF NOT EXISTS plan_changes_created_at ON plan_changes(created_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS access_tokens_user_id;
+DROP TABLE IF EXISTS access_tokens;
@@ -0,0 +1,12 @@
+-- single use password reset tokens. only a sha256 hash of each token is
+-- kept, used_at is set when the token is redeemed
+CREATE TABLE IF NOT EXISTS password_reset_tokens(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_tokens_user_id ON password_reset_tokens(user_id);
@@ -0,0 +1,7 @@
+-- email_verification_sent_at throttles how often the verification email
+-- can be resent
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
+ALTER TABLE users ADD COLUMN email_verification_sent_at DATETIME;
+
+-- accounts created before verification existed are trusted
+UPDATE users SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP);
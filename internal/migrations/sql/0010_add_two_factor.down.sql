@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS recovery_codes_user_id;
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users DROP COLUMN totp_last_step;
+ALTER TABLE users DROP COLUMN two_factor_enabled_at;
+ALTER TABLE users DROP COLUMN totp_secret;
@@ -0,0 +1,18 @@
+-- totp_secret is set when enrollment starts and two_factor_enabled_at once
+-- the user confirms it with a code. totp_last_step is the time step of the
+-- last accepted code so it cannot be replayed
+ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN two_factor_enabled_at DATETIME;
+ALTER TABLE users ADD COLUMN totp_last_step INTEGER;
+
+-- one time codes for when the user loses their authenticator, only a
+-- sha256 hash of each is kept
+CREATE TABLE IF NOT EXISTS recovery_codes(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id ON recovery_codes(user_id);
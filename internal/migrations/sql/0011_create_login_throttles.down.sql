@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS login_throttles_locked_until;
+DROP TABLE IF EXISTS login_throttles;
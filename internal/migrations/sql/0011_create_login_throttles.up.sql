@@ -0,0 +1,14 @@
+-- failed logins per email address and per ip. key is the lower cased email
+-- or the ip, emails without an account are throttled the same way so
+-- lockouts do not reveal who has one
+CREATE TABLE IF NOT EXISTS login_throttles(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL CHECK (kind IN ('email', 'ip')),
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at DATETIME NOT NULL,
+    locked_until DATETIME,
+    UNIQUE(kind, key)
+);
+
+CREATE INDEX IF NOT EXISTS login_throttles_locked_until ON login_throttles(locked_until);
@@ -0,0 +1,13 @@
+-- the devices a user is logged in on. session_id is the id of the session
+-- in the session store, deleting that session signs the device out
+CREATE TABLE IF NOT EXISTS user_sessions(
+    session_id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_sessions_user_id ON user_sessions(user_id);
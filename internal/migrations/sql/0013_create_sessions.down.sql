@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS sessions_expires_on;
+DROP TABLE IF EXISTS sessions;
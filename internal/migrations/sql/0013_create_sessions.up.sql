@@ -0,0 +1,11 @@
+-- sessions for the database session store backend, laid out like the
+-- table in the sqlite backend's own file
+CREATE TABLE IF NOT EXISTS sessions(
+    id INTEGER PRIMARY KEY,
+    session_data TEXT NOT NULL,
+    created_on DATETIME NOT NULL,
+    modified_on DATETIME NOT NULL,
+    expires_on DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expires_on ON sessions(expires_on);
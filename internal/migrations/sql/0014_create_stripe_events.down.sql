@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS stripe_events_received_at;
+DROP INDEX IF EXISTS stripe_events_next_attempt_at;
+DROP TABLE IF EXISTS stripe_events;
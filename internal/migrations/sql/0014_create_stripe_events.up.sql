@@ -0,0 +1,18 @@
+-- every verified stripe webhook event. Stripe retries deliveries, the id
+-- makes processing an event twice a no-op. Events that failed to process
+-- are retried from next_attempt_at, it is null once there is nothing left
+-- to do
+CREATE TABLE IF NOT EXISTS stripe_events(
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    received_at DATETIME NOT NULL,
+    processed_at DATETIME,
+    next_attempt_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS stripe_events_next_attempt_at ON stripe_events(next_attempt_at);
+CREATE INDEX IF NOT EXISTS stripe_events_received_at ON stripe_events(received_at);
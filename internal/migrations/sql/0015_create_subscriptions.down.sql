@@ -0,0 +1,3 @@
+ALTER TABLE stripe_events DROP COLUMN created_at;
+DROP INDEX IF EXISTS subscriptions_user_id;
+DROP TABLE IF EXISTS subscriptions;
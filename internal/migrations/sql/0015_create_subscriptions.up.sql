@@ -0,0 +1,21 @@
+-- subscriptions to the paid plan, kept in step with stripe by webhook
+-- events. users.is_paid_user is derived from them and only changes when a
+-- subscription does
+CREATE TABLE IF NOT EXISTS subscriptions(
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    customer_id TEXT NOT NULL,
+    price_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_end DATETIME NOT NULL,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT false,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    last_event_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS subscriptions_user_id ON subscriptions(user_id);
+
+-- when stripe created each event, so subscriptions ignore stale events.
+-- null for events received before it was recorded
+ALTER TABLE stripe_events ADD COLUMN created_at DATETIME;
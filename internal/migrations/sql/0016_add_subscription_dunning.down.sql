@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN payment_grace_ends_at;
+ALTER TABLE subscriptions DROP COLUMN dunning_reminders_sent;
+ALTER TABLE subscriptions DROP COLUMN grace_ends_at;
+ALTER TABLE subscriptions DROP COLUMN past_due_since;
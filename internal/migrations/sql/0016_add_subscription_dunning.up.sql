@@ -0,0 +1,9 @@
+-- a past due subscription keeps its plan until grace_ends_at, reminding
+-- the user to pay along the way
+ALTER TABLE subscriptions ADD COLUMN past_due_since DATETIME;
+ALTER TABLE subscriptions ADD COLUMN grace_ends_at DATETIME;
+ALTER TABLE subscriptions ADD COLUMN dunning_reminders_sent INTEGER NOT NULL DEFAULT 0;
+
+-- derived from subscriptions like is_paid_user, set while the user is in
+-- a grace period so every page can warn them
+ALTER TABLE users ADD COLUMN payment_grace_ends_at DATETIME;
@@ -0,0 +1 @@
+ALTER TABLE todos DROP COLUMN archived_at;
@@ -0,0 +1,3 @@
+-- todos over the plan's limit are archived rather than hidden, they are
+-- read only until the user upgrades or chooses them to stay active
+ALTER TABLE todos ADD COLUMN archived_at DATETIME;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS invoices_user_id;
+DROP TABLE IF EXISTS invoices;
@@ -0,0 +1,21 @@
+-- invoices recorded from invoice.paid and invoice.payment_failed webhook
+-- events so users can see their billing history without leaving the app.
+-- amount is in the currency's smallest unit, e.g. cents
+CREATE TABLE IF NOT EXISTS invoices(
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    customer_id TEXT NOT NULL,
+    subscription_id TEXT NOT NULL DEFAULT '',
+    number TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    price_id TEXT NOT NULL DEFAULT '',
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    period_start DATETIME NOT NULL,
+    period_end DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    paid_at DATETIME,
+    last_event_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS invoices_user_id ON invoices(user_id, created_at);
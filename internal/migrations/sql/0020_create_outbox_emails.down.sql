@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS outbox_emails_next_attempt_at;
+DROP TABLE IF EXISTS outbox_emails;
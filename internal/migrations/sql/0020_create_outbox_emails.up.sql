@@ -0,0 +1,17 @@
+-- email waiting to be sent. Messages are saved here and sent by a worker
+-- so a mailer that is down does not lose them, failed sends are retried
+-- from next_attempt_at, it is null once there is nothing left to do
+CREATE TABLE IF NOT EXISTS outbox_emails(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    to_address TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    sent_at DATETIME,
+    next_attempt_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS outbox_emails_next_attempt_at ON outbox_emails(next_attempt_at);
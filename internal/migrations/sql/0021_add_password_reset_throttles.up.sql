@@ -0,0 +1,21 @@
+-- password reset requests are throttled per email address and per ip, as
+-- reset_email and reset_ip. sqlite cannot change a check constraint so the
+-- table is rebuilt
+CREATE TABLE login_throttles_new(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL CHECK (kind IN ('email', 'ip', 'user', 'reset_email', 'reset_ip')),
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at DATETIME NOT NULL,
+    locked_until DATETIME,
+    UNIQUE(kind, key)
+);
+
+INSERT INTO login_throttles_new(id, kind, key, failures, last_failed_at, locked_until)
+SELECT id, kind, key, failures, last_failed_at, locked_until FROM login_throttles;
+
+DROP INDEX IF EXISTS login_throttles_locked_until;
+DROP TABLE login_throttles;
+ALTER TABLE login_throttles_new RENAME TO login_throttles;
+
+CREATE INDEX IF NOT EXISTS login_throttles_locked_until ON login_throttles(locked_until);
@@ -0,0 +1 @@
+ALTER TABLE todos DROP COLUMN reminder_sent_at;
@@ -0,0 +1,3 @@
+-- when the reminder that a todo is coming due was sent, so it is sent
+-- once. Changing the due date clears it
+ALTER TABLE todos ADD COLUMN reminder_sent_at DATETIME;
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// access token scopes, read tokens may only make GET requests
+const (
+	AccessTokenScopeRead  = "read"
+	AccessTokenScopeWrite = "write"
+)
+
+// AccessTokenPrefix starts every personal access token so they are easy to
+// recognise, e.g. in leaked credential scans.
+const AccessTokenPrefix = "gtd_"
+
+// AccessToken is a personal access token. The token itself is only shown
+// when it is created, Prefix is enough of it to tell tokens apart.
+type AccessToken struct {
+	ID         int
+	UserID     string
+	Name       string
+	Prefix     string
+	Scope      string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	Token      string // only set when the token is created
+}
+
+// CanWrite reports whether the token may change data.
+func (t *AccessToken) CanWrite() bool {
+	return t != nil && t.Scope == AccessTokenScopeWrite
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// DailyCount is a count for a single UTC day.
+type DailyCount struct {
+	Day   time.Time
+	Count int
+}
+
+// plan change sources that are not stripe webhook event types
+const (
+	PlanChangeSourceDunning   = "dunning"
+	PlanChangeSourceReconcile = "reconcile"
+)
+
+// Analytics holds the metrics shown on the admin dashboards. Each daily
+// series has one entry per day from Since up to and including today.
+type Analytics struct {
+	Since            time.Time
+	Signups          []DailyCount
+	ActiveUsers      []DailyCount
+	TodosCreated     []DailyCount
+	TodosCompleted   []DailyCount
+	Conversions      []DailyCount
+	Cancellations    []DailyCount
+	PaidUsers        int
+	FreeUsers        int
+	FreeUsersAtLimit int
+}
+
+func (a *Analytics) TotalUsers() int {
+	return a.PaidUsers + a.FreeUsers
+}
+
+// FreeUsersAtLimitPercent is the share of free users who have hit the
+// free plan's todo limit.
+func (a *Analytics) FreeUsersAtLimitPercent() int {
+	if a.FreeUsers == 0 {
+		return 0
+	}
+	return a.FreeUsersAtLimit * 100 / a.FreeUsers
+}
+
+// Total sums a daily series.
+func Total(counts []DailyCount) int {
+	total := 0
+	for _, count := range counts {
+		total += count.Count
+	}
+	return total
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// checkout session payment statuses. Checkouts that start a trial
+// complete with nothing to pay.
+const (
+	PaymentStatusPaid              = "paid"
+	PaymentStatusUnpaid            = "unpaid"
+	PaymentStatusNoPaymentRequired = "no_payment_required"
+)
+
+// UserIDMetadataKey is the subscription metadata holding the id of the
+// user it belongs to.
+const UserIDMetadataKey = "user_id"
+
+// CheckoutSession is a hosted checkout page a user is sent to to pay for
+// the paid plan. UserID is who started it, CustomerID and SubscriptionID
+// are only set once they have paid.
+type CheckoutSession struct {
+	ID             string
+	URL            string
+	UserID         string
+	CustomerID     string
+	CustomerEmail  string
+	SubscriptionID string
+	PaymentStatus  string
+}
+
+// IsPaid reports whether the checkout was completed, paid for or with
+// nothing to pay.
+func (s *CheckoutSession) IsPaid() bool {
+	return s.PaymentStatus == PaymentStatusPaid || s.PaymentStatus == PaymentStatusNoPaymentRequired
+}
+
+// BillingDifference is something about a user's billing that differs from
+// the billing provider, e.g. a subscription status a missed webhook event
+// would have changed. Fixed is true once it has been put right.
+type BillingDifference struct {
+	UserID string
+	Email  string
+	Field  string
+	Local  string
+	Remote string
+	Fixed  bool
+}
+
+// BillingFailure is a user whose billing could not be compared with the
+// billing provider, e.g. because their customer was deleted there.
+type BillingFailure struct {
+	UserID string
+	Email  string
+	Err    error
+}
+
+// BillingReconciliation is the result of comparing every billing user
+// with the billing provider. Applied is true when the differences were
+// fixed rather than only found.
+type BillingReconciliation struct {
+	Users       int
+	Differences []BillingDifference
+	Failures    []BillingFailure
+	Applied     bool
+}
+
+// BillingEvent is a verified webhook event from the billing provider. Data
+// is the raw JSON of the object the event is about.
+type BillingEvent struct {
+	ID      string
+	Type    string
+	Created time.Time
+	Data    json.RawMessage
+}
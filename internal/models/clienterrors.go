@@ -1,5 +1,29 @@
 package models
 
 type CreateTodoClientErrors struct {
+	ListErrors        []string
 	DescriptionErrors []string
+	DueAtErrors       []string
+}
+
+type UpdateTodoClientErrors struct {
+	TodoErrors        []string
+	ListErrors        []string
+	DescriptionErrors []string
+	DueAtErrors       []string
+}
+
+type UpdateUserClientErrors struct {
+	NameErrors  []string
+	EmailErrors []string
+}
+
+type CreateAccessTokenClientErrors struct {
+	NameErrors  []string
+	ScopeErrors []string
+}
+
+type ResetPasswordClientErrors struct {
+	TokenErrors    []string
+	PasswordErrors []string
 }
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// invoice statuses
+const (
+	InvoicePaid          = "paid"
+	InvoicePaymentFailed = "payment_failed"
+)
+
+// Invoice is a bill for a subscription, recorded from the billing
+// provider's invoice events. Amount is in the smallest unit of Currency,
+// e.g. cents. PriceID is the price of the plan it bills for.
+type Invoice struct {
+	ID             string
+	UserID         string
+	CustomerID     string
+	SubscriptionID string
+	Number         string
+	Status         string
+	PriceID        string
+	Amount         int64
+	Currency       string
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	CreatedAt      time.Time
+	PaidAt         *time.Time
+	LastEventAt    time.Time
+}
+
+func (i *Invoice) IsPaid() bool {
+	return i.Status == InvoicePaid
+}
+
+// zeroDecimalCurrencies have no minor unit, their amounts are whole units.
+var zeroDecimalCurrencies = map[string]bool{
+	"jpy": true,
+	"krw": true,
+	"vnd": true,
+}
+
+var currencySymbols = map[string]string{
+	"usd": "$",
+	"eur": "€",
+	"gbp": "£",
+}
+
+// FormatAmount formats an amount in currency's smallest unit, e.g. 500 usd
+// is "$5.00". Currencies without a symbol are shown by their code.
+func FormatAmount(amount int64, currency string) string {
+	currency = strings.ToLower(currency)
+
+	value := fmt.Sprintf("%d", amount)
+	if !zeroDecimalCurrencies[currency] {
+		sign := ""
+		if amount < 0 {
+			sign, amount = "-", -amount
+		}
+		value = fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+	}
+
+	if symbol, ok := currencySymbols[currency]; ok {
+		return symbol + value
+	}
+	return value + " " + strings.ToUpper(currency)
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// login throttles are kept per email address and per ip, per user for
+// second factor codes, and per email address and per ip for password
+// reset requests
+const (
+	LoginThrottleEmail      = "email"
+	LoginThrottleIP         = "ip"
+	LoginThrottleUser       = "user"
+	LoginThrottleResetEmail = "reset_email"
+	LoginThrottleResetIP    = "reset_ip"
+)
+
+// LoginThrottle counts recent failed logins for an email address or ip.
+// LockedUntil is set once there have been too many.
+type LoginThrottle struct {
+	ID           int
+	Kind         string
+	Key          string
+	Failures     int
+	LastFailedAt time.Time
+	LockedUntil  *time.Time
+}
+
+// IsLocked reports whether logins are refused at now.
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t != nil && t.LockedUntil != nil && t.LockedUntil.After(now)
+}
@@ -1,27 +1,87 @@
 package models
 
+import "time"
+
 type Todo struct {
 	ID          int
 	UserID      string
+	ListID      int
 	Description string
 	IsComplete  bool
+	DueAt       *time.Time
+	// ArchivedAt is set while the todo is over the user's plan limit
+	ArchivedAt *time.Time
 }
 
-func NewTodo(userID string, description string) Todo {
+func NewTodo(userID string, listID int, description string) Todo {
 	return Todo{
 		UserID:      userID,
+		ListID:      listID,
 		Description: description,
 	}
 }
 
+// IsArchived reports whether the todo is read only because it is over the
+// user's plan limit.
+func (t *Todo) IsArchived() bool {
+	return t.ArchivedAt != nil
+}
+
+// IsOverdue reports whether an incomplete todo's due date has passed.
+// Archived todos can not be worked on so are never overdue.
+func (t *Todo) IsOverdue(now time.Time) bool {
+	return t.DueAt != nil && !t.IsComplete && !t.IsArchived() && t.DueAt.Before(now)
+}
+
+// IsDueOn reports whether an incomplete todo is due on the same calendar
+// day as now, in now's location.
+func (t *Todo) IsDueOn(now time.Time) bool {
+	if t.DueAt == nil || t.IsComplete || t.IsArchived() {
+		return false
+	}
+	due := t.DueAt.In(now.Location())
+	y1, m1, d1 := due.Date()
+	y2, m2, d2 := now.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// TodoUpdate holds the changes to apply to a todo, nil fields are left as
+// they are. An empty DueAt removes the due date.
+type TodoUpdate struct {
+	Description *string
+	IsComplete  *bool
+	ListID      *int
+	DueAt       *string
+}
+
+type List struct {
+	ID        int
+	UserID    string
+	Name      string
+	TodoCount int
+}
+
+func NewList(userID string, name string) List {
+	return List{
+		UserID: userID,
+		Name:   name,
+	}
+}
+
 type User struct {
-	ID               string
-	Name             string
-	Email            string
-	Password         string
-	IsPaidUser       bool
-	StripeCustomerID string
-	Roles            map[string]string
+	ID                 string
+	Name               string
+	Email              string
+	Password           string
+	IsPaidUser         bool
+	StripeCustomerID   string
+	TimeZone           string
+	EmailVerifiedAt    *time.Time
+	TwoFactorEnabledAt *time.Time
+	// set while a payment has failed and the user keeps their plan
+	PaymentGraceEndsAt *time.Time
+	Roles              map[string]string // role name => role description
+	Permissions        map[string]bool
 }
 
 func NewUser(ID string, name string, email string, password string, isPaidUser bool, stripeCustomerID string) User {
@@ -32,5 +92,60 @@ func NewUser(ID string, name string, email string, password string, isPaidUser b
 		Password:         password,
 		IsPaidUser:       isPaidUser,
 		StripeCustomerID: stripeCustomerID,
+		TimeZone:         "UTC",
 	}
 }
+
+// UserStats summarises a user's data for the admin area.
+type UserStats struct {
+	TodoCount          int
+	CompletedTodoCount int
+	ArchivedTodoCount  int
+	ListCount          int
+}
+
+// Location returns the user's time zone, falling back to UTC when it is
+// unset or unknown.
+func (u *User) Location() *time.Location {
+	if u == nil || u.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// EmailIsVerified reports whether the user has confirmed they own their
+// email address.
+func (u *User) EmailIsVerified() bool {
+	return u != nil && u.EmailVerifiedAt != nil
+}
+
+// TwoFactorEnabled reports whether logging in needs a code from the user's
+// authenticator app.
+func (u *User) TwoFactorEnabled() bool {
+	return u != nil && u.TwoFactorEnabledAt != nil
+}
+
+// TwoFactorEnrollment is what a user needs to add their account to an
+// authenticator app. QRCode is a PNG of URI.
+type TwoFactorEnrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte
+}
+
+// SearchResult is a todo matched by a full text search. Snippet is the
+// matching part of the description with each matched term wrapped in
+// SnippetMatchStart and SnippetMatchEnd.
+type SearchResult struct {
+	Todo    *Todo
+	Snippet string
+}
+
+const (
+	SnippetMatchStart = "\x02"
+	SnippetMatchEnd   = "\x03"
+)
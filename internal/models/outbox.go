@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// outbox email statuses
+const (
+	// waiting to be sent, or to be retried
+	OutboxEmailPending = "pending"
+	// handed to the mailer
+	OutboxEmailSent = "sent"
+	// failed every attempt, it is not sent
+	OutboxEmailFailed = "failed"
+)
+
+// OutboxEmail is an email saved to be sent by the outbox worker.
+// NextAttemptAt is when it is next due to be sent, nil once it is sent or
+// has failed for good.
+type OutboxEmail struct {
+	ID            int
+	To            string
+	Subject       string
+	Body          string
+	Status        string
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	SentAt        *time.Time
+	NextAttemptAt *time.Time
+}
+
+func NewOutboxEmail(to, subject, body string, createdAt time.Time) OutboxEmail {
+	return OutboxEmail{
+		To:            to,
+		Subject:       subject,
+		Body:          body,
+		Status:        OutboxEmailPending,
+		CreatedAt:     createdAt,
+		NextAttemptAt: &createdAt,
+	}
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// entitlements a plan can grant
+const (
+	EntitlementCreateTodo  = "create_todo"
+	EntitlementCreateList  = "create_list"
+	EntitlementAttachments = "attachments"
+	EntitlementAPIAccess   = "api_access"
+)
+
+// Entitlements is what a plan lets its users have. Zero limits are
+// unlimited.
+type Entitlements struct {
+	MaxTodos    int  `json:"max_todos"`
+	MaxLists    int  `json:"max_lists"`
+	Attachments bool `json:"attachments"`
+	APIAccess   bool `json:"api_access"`
+}
+
+// Plan is one entry in the plan catalogue. The free plan has no PriceID,
+// every other plan is bought through its Stripe price. Price is only
+// shown, e.g. "$5 / month", Stripe decides what is charged. TrialDays is
+// the free trial users who have never subscribed get.
+type Plan struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Description  string       `json:"description"`
+	Price        string       `json:"price"`
+	PriceID      string       `json:"price_id"`
+	TrialDays    int          `json:"trial_days"`
+	Entitlements Entitlements `json:"entitlements"`
+}
+
+func (p *Plan) IsFree() bool {
+	return p.PriceID == ""
+}
+
+// PlanCatalogue is every plan users can be on, in the order they are
+// offered.
+type PlanCatalogue []Plan
+
+// Free returns the plan users are on when they have no subscription.
+func (c PlanCatalogue) Free() *Plan {
+	for i := range c {
+		if c[i].IsFree() {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+// Get returns the plan with id, or nil when there is none.
+func (c PlanCatalogue) Get(id string) *Plan {
+	for i := range c {
+		if c[i].ID == id {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+// ByPriceID returns the plan bought through priceID, or nil when there is
+// none.
+func (c PlanCatalogue) ByPriceID(priceID string) *Plan {
+	for i := range c {
+		if !c[i].IsFree() && c[i].PriceID == priceID {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+// Paid returns the plans that can be bought.
+func (c PlanCatalogue) Paid() []Plan {
+	paid := []Plan{}
+	for _, plan := range c {
+		if !plan.IsFree() {
+			paid = append(paid, plan)
+		}
+	}
+	return paid
+}
+
+// Validate returns everything wrong with the catalogue.
+func (c PlanCatalogue) Validate() []error {
+	errs := []error{}
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	free := 0
+	ids := map[string]bool{}
+	priceIDs := map[string]bool{}
+	for _, plan := range c {
+		if plan.ID == "" || plan.Name == "" {
+			fail("every plan needs an id and a name, got %+v", plan)
+		}
+		if ids[plan.ID] {
+			fail("plan id %q is used more than once", plan.ID)
+		}
+		ids[plan.ID] = true
+
+		if plan.IsFree() {
+			free++
+		} else if !strings.HasPrefix(plan.PriceID, "price_") {
+			fail("plan %q price_id must start price_, got %q", plan.ID, plan.PriceID)
+		} else if priceIDs[plan.PriceID] {
+			fail("plan %q has the same price_id as another plan", plan.ID)
+		}
+		priceIDs[plan.PriceID] = true
+
+		if plan.Entitlements.MaxTodos < 0 || plan.Entitlements.MaxLists < 0 || plan.TrialDays < 0 {
+			fail("plan %q max_todos, max_lists and trial_days can not be negative", plan.ID)
+		}
+		if plan.IsFree() && plan.TrialDays > 0 {
+			fail("the free plan %q can not have a trial", plan.ID)
+		}
+	}
+
+	if free != 1 {
+		errs = append(errs, errors.New("the plan catalogue needs exactly one free plan, one without a price_id"))
+	}
+	return errs
+}
@@ -0,0 +1,35 @@
+package models
+
+const RoleAdmin = "admin"
+
+// permissions granted to roles through the role_permissions table
+const (
+	PermissionUsersView      = "users:view"
+	PermissionUsersManage    = "users:manage"
+	PermissionTodosDeleteAny = "todos:delete:any"
+	PermissionAnalyticsView  = "analytics:view"
+)
+
+type Role struct {
+	ID          int
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// HasRole is nil safe so it can be used on the user from the request
+// context whether or not anyone is logged in.
+func (u *User) HasRole(role string) bool {
+	if u == nil {
+		return false
+	}
+	_, ok := u.Roles[role]
+	return ok
+}
+
+func (u *User) HasPermission(permission string) bool {
+	if u == nil {
+		return false
+	}
+	return u.Permissions[permission]
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// stripe event statuses
+const (
+	// received but not processed yet, or waiting to be retried
+	StripeEventPending = "pending"
+	// processed, it is never processed again unless replayed
+	StripeEventProcessed = "processed"
+	// failed every attempt, it is only processed again if replayed
+	StripeEventFailed = "failed"
+)
+
+// StripeEvent is a webhook event received from Stripe. CreatedAt is when
+// Stripe created it, which can be well before it was received.
+// NextAttemptAt is when it is next due to be processed, nil once it is
+// processed or has failed for good.
+type StripeEvent struct {
+	ID            string
+	Type          string
+	Payload       string
+	Status        string
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	ReceivedAt    time.Time
+	ProcessedAt   *time.Time
+	NextAttemptAt *time.Time
+}
+
+func NewStripeEvent(id, eventType, payload string, createdAt, receivedAt, nextAttemptAt time.Time) StripeEvent {
+	return StripeEvent{
+		ID:            id,
+		Type:          eventType,
+		Payload:       payload,
+		Status:        StripeEventPending,
+		CreatedAt:     createdAt,
+		ReceivedAt:    receivedAt,
+		NextAttemptAt: &nextAttemptAt,
+	}
+}
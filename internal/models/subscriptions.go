@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// subscription statuses
+const (
+	SubscriptionTrialing = "trialing"
+	SubscriptionActive   = "active"
+	SubscriptionPastDue  = "past_due"
+	SubscriptionPaused   = "paused"
+	SubscriptionCanceled = "canceled"
+)
+
+// subscriptionTransitions are the statuses each status can move to. A
+// status can always stay the same, e.g. when a period renews. Canceled is
+// final, subscribing again starts a new subscription.
+var subscriptionTransitions = map[string][]string{
+	SubscriptionTrialing: {SubscriptionActive, SubscriptionPastDue, SubscriptionPaused, SubscriptionCanceled},
+	SubscriptionActive:   {SubscriptionPastDue, SubscriptionPaused, SubscriptionCanceled},
+	SubscriptionPastDue:  {SubscriptionActive, SubscriptionPaused, SubscriptionCanceled},
+	SubscriptionPaused:   {SubscriptionActive, SubscriptionPastDue, SubscriptionCanceled},
+	SubscriptionCanceled: {},
+}
+
+// Subscription is a user's subscription to a paid plan. LastEventAt is
+// when the billing event it was last updated from was created, so events
+// that arrive out of order do not undo newer ones. While it is past due
+// it keeps the plan until GraceEndsAt, with DunningRemindersSent counting
+// the reminders to pay sent so far.
+type Subscription struct {
+	ID                   string
+	UserID               string
+	CustomerID           string
+	PriceID              string
+	Status               string
+	CurrentPeriodEnd     time.Time
+	CancelAtPeriodEnd    bool
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	LastEventAt          time.Time
+	PastDueSince         *time.Time
+	GraceEndsAt          *time.Time
+	DunningRemindersSent int
+}
+
+func NewSubscription(id, userID, customerID, priceID, status string, currentPeriodEnd time.Time, cancelAtPeriodEnd bool) Subscription {
+	return Subscription{
+		ID:                id,
+		UserID:            userID,
+		CustomerID:        customerID,
+		PriceID:           priceID,
+		Status:            status,
+		CurrentPeriodEnd:  currentPeriodEnd,
+		CancelAtPeriodEnd: cancelAtPeriodEnd,
+	}
+}
+
+// IsSubscriptionStatus reports whether status is one subscriptions can have.
+func IsSubscriptionStatus(status string) bool {
+	_, ok := subscriptionTransitions[status]
+	return ok
+}
+
+// CanTransitionTo returns an error when the subscription can not move to
+// status.
+func (s *Subscription) CanTransitionTo(status string) error {
+	if !IsSubscriptionStatus(status) {
+		return fmt.Errorf("%q is not a subscription status", status)
+	}
+	if s.Status == status {
+		return nil
+	}
+	for _, allowed := range subscriptionTransitions[s.Status] {
+		if allowed == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("subscription %s can not go from %s to %s", s.ID, s.Status, status)
+}
+
+// IsEntitledAt reports whether the subscription grants the paid plan at
+// now. Past due subscriptions keep it until their grace period ends.
+func (s *Subscription) IsEntitledAt(now time.Time) bool {
+	switch s.Status {
+	case SubscriptionTrialing, SubscriptionActive:
+		return true
+	case SubscriptionPastDue:
+		return s.InGracePeriodAt(now)
+	default:
+		return false
+	}
+}
+
+// InGracePeriodAt reports whether the subscription is past due but still
+// has its plan at now.
+func (s *Subscription) InGracePeriodAt(now time.Time) bool {
+	return s.Status == SubscriptionPastDue && (s.GraceEndsAt == nil || now.Before(*s.GraceEndsAt))
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// UserSession describes a device a user is logged in on. ID is the id of
+// the session in the session store.
+type UserSession struct {
+	ID         string
+	UserID     string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+}
+
+func NewUserSession(id, userID, ip, userAgent string, createdAt, expiresAt time.Time) UserSession {
+	return UserSession{
+		ID:         id,
+		UserID:     userID,
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  createdAt,
+		LastSeenAt: createdAt,
+		ExpiresAt:  expiresAt,
+	}
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-todo/internal/models"
+	"time"
+)
+
+const accessTokenColumns = `id, user_id, name, prefix, scope, created_at, last_used_at`
+
+func scanAccessToken(row rowScanner) (*models.AccessToken, error) {
+	token := models.AccessToken{}
+	var lastUsedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &token.Scope, &token.CreatedAt, &lastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		lastUsed := lastUsedAt.Time.UTC()
+		token.LastUsedAt = &lastUsed
+	}
+	return &token, nil
+}
+
+func (r *Repository) CreateAccessToken(token *models.AccessToken, tokenHash string) (int, error) {
+	stmt, err := r.db.Prepare(`INSERT INTO access_tokens(user_id, name, token_hash, prefix, scope, created_at) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("Issue preparing create access token statement. %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(token.UserID, token.Name, tokenHash, token.Prefix, token.Scope, token.CreatedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("Error executing create access token statement. %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("could not get last insert id. %w", err)
+	}
+	return int(id), nil
+}
+
+// GetAccessTokenByHash returns the unrevoked token with tokenHash, or nil.
+func (r *Repository) GetAccessTokenByHash(tokenHash string) (*models.AccessToken, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + accessTokenColumns + ` FROM access_tokens WHERE token_hash = ? AND revoked_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get access token by hash query. %w", err)
+	}
+	defer stmt.Close()
+
+	token, err := scanAccessToken(stmt.QueryRow(tokenHash))
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error executing get access token by hash query. %w", err)
+	}
+	return token, nil
+}
+
+// GetAccessTokensByUserID returns the user's unrevoked tokens, newest first.
+func (r *Repository) GetAccessTokensByUserID(userID string) ([]*models.AccessToken, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + accessTokenColumns + ` FROM access_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get access tokens by user id query. %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Error executing get access tokens by user id query. %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*models.AccessToken{}
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning access tokens. %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAccessToken revokes one of the user's tokens and reports whether
+// there was an unrevoked token to revoke.
+func (r *Repository) RevokeAccessToken(userID string, tokenID int, revokedAt time.Time) (bool, error) {
+	stmt, err := r.db.Prepare(`UPDATE access_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`)
+	if err != nil {
+		return false, fmt.Errorf("Issue preparing revoke access token statement. %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(revokedAt.UTC(), tokenID, userID)
+	if err != nil {
+		return false, fmt.Errorf("Error executing revoke access token statement. %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (r *Repository) UpdateAccessTokenLastUsed(tokenID int, lastUsedAt time.Time) error {
+	stmt, err := r.db.Prepare(`UPDATE access_tokens SET last_used_at = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing update access token last used statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(lastUsedAt.UTC(), tokenID)
+	if err != nil {
+		return fmt.Errorf("Error executing update access token last used statement. %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) CountAccessTokensByUserID(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM access_tokens WHERE user_id = ? AND revoked_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting access tokens by user id. %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+)
+
+// countByDay runs query, which must select a day formatted as YYYY-MM-DD
+// and a count, and returns the counts keyed by day.
+func (r *Repository) countByDay(query string, args ...any) (map[string]int, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var day string
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, err
+		}
+		counts[day] = count
+	}
+	return counts, rows.Err()
+}
+
+// sinceValue formats since the way sqlite's date functions compare it.
+func sinceValue(since time.Time) string {
+	return since.UTC().Format("2006-01-02 15:04:05")
+}
+
+func (r *Repository) CountSignupsByDay(since time.Time) (map[string]int, error) {
+	counts, err := r.countByDay(`SELECT date(created_at), COUNT(*) FROM users
+		WHERE datetime(created_at) >= ?
+		GROUP BY date(created_at)`, sinceValue(since))
+	if err != nil {
+		return nil, fmt.Errorf("Error counting signups by day. %w", err)
+	}
+	return counts, nil
+}
+
+// CountActiveUsersByDay counts the distinct users who created or completed
+// a todo on each day.
+func (r *Repository) CountActiveUsersByDay(since time.Time) (map[string]int, error) {
+	counts, err := r.countByDay(`SELECT day, COUNT(DISTINCT user_id) FROM (
+			SELECT user_id, date(created_at) AS day FROM todos WHERE datetime(created_at) >= ?
+			UNION
+			SELECT user_id, date(completed_at) AS day FROM todos WHERE datetime(completed_at) >= ?
+		)
+		GROUP BY day`, sinceValue(since), sinceValue(since))
+	if err != nil {
+		return nil, fmt.Errorf("Error counting active users by day. %w", err)
+	}
+	return counts, nil
+}
+
+func (r *Repository) CountTodosCreatedByDay(since time.Time) (map[string]int, error) {
+	counts, err := r.countByDay(`SELECT date(created_at), COUNT(*) FROM todos
+		WHERE datetime(created_at) >= ?
+		GROUP BY date(created_at)`, sinceValue(since))
+	if err != nil {
+		return nil, fmt.Errorf("Error counting todos created by day. %w", err)
+	}
+	return counts, nil
+}
+
+func (r *Repository) CountTodosCompletedByDay(since time.Time) (map[string]int, error) {
+	counts, err := r.countByDay(`SELECT date(completed_at), COUNT(*) FROM todos
+		WHERE datetime(completed_at) >= ?
+		GROUP BY date(completed_at)`, sinceValue(since))
+	if err != nil {
+		return nil, fmt.Errorf("Error counting todos completed by day. %w", err)
+	}
+	return counts, nil
+}
+
+// CountPlanChangesByDay counts moves onto the paid plan (conversions) or
+// back to the free plan (cancellations) that came from stripe, ignoring
+// changes made by admins.
+func (r *Repository) CountPlanChangesByDay(since time.Time, isPaidUser bool) (map[string]int, error) {
+	counts, err := r.countByDay(`SELECT date(created_at), COUNT(*) FROM plan_changes
+		WHERE datetime(created_at) >= ? AND is_paid_user = ? AND source != 'admin'
+		GROUP BY date(created_at)`, sinceValue(since), isPaidUser)
+	if err != nil {
+		return nil, fmt.Errorf("Error counting plan changes by day. %w", err)
+	}
+	return counts, nil
+}
+
+func (r *Repository) RecordPlanChange(userID string, isPaidUser bool, source string) error {
+	stmt, err := r.db.Prepare(`INSERT INTO plan_changes(user_id, is_paid_user, source) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing record plan change statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID, isPaidUser, source)
+	if err != nil {
+		return fmt.Errorf("Error executing record plan change statement. %w", err)
+	}
+	return nil
+}
+
+// CountUsersByPlan returns how many users are on the paid and free plans.
+func (r *Repository) CountUsersByPlan() (int, int, error) {
+	var paid, free int
+	err := r.db.QueryRow(`SELECT
+			COALESCE(SUM(CASE WHEN is_paid_user THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN is_paid_user THEN 0 ELSE 1 END), 0)
+		FROM users`).Scan(&paid, &free)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Error counting users by plan. %w", err)
+	}
+	return paid, free, nil
+}
+
+// CountFreeUsersAtTodoLimit counts free users with at least limit active
+// todos.
+func (r *Repository) CountFreeUsersAtTodoLimit(limit int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users
+		WHERE NOT is_paid_user
+		AND (SELECT COUNT(*) FROM todos WHERE todos.user_id = users.id AND todos.archived_at IS NULL) >= ?`, limit).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting free users at the todo limit. %w", err)
+	}
+	return count, nil
+}
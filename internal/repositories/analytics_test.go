@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"go-todo/internal/models"
+	"testing"
+	"time"
+)
+
+func TestAnalyticsCounts(t *testing.T) {
+	r := newTestRepository(t)
+
+	for _, user := range []models.User{
+		models.NewUser("u1", "Alice", "alice@example.com", "", false, ""),
+		models.NewUser("u2", "Bob", "bob@example.com", "", true, ""),
+	} {
+		if err := r.SaveUser(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fixtures := []string{
+		`UPDATE users SET created_at = '2030-03-01 10:00:00' WHERE id = 'u1'`,
+		`UPDATE users SET created_at = '2030-03-02 23:59:59' WHERE id = 'u2'`,
+		`INSERT INTO todos(user_id, description, created_at) VALUES ('u1', 'a', '2030-03-01 11:00:00')`,
+		`INSERT INTO todos(user_id, description, created_at) VALUES ('u1', 'b', '2030-03-01 12:00:00')`,
+		`INSERT INTO todos(user_id, description, created_at, is_complete, completed_at) VALUES ('u2', 'c', '2030-02-01 12:00:00', 1, '2030-03-02 09:00:00')`,
+		`INSERT INTO plan_changes(user_id, is_paid_user, source, created_at) VALUES ('u2', 1, 'invoice.paid', '2030-03-02 09:00:00')`,
+		`INSERT INTO plan_changes(user_id, is_paid_user, source, created_at) VALUES ('u1', 1, 'admin', '2030-03-02 09:00:00')`,
+	}
+	for _, fixture := range fixtures {
+		if _, err := r.db.Exec(fixture); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	since := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	signups, err := r.CountSignupsByDay(since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signups["2030-03-01"] != 1 || signups["2030-03-02"] != 1 {
+		t.Errorf("unexpected signups %v", signups)
+	}
+
+	created, err := r.CountTodosCreatedByDay(since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 1 || created["2030-03-01"] != 2 {
+		t.Errorf("expected todos created before since to be ignored, got %v", created)
+	}
+
+	active, err := r.CountActiveUsersByDay(since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active["2030-03-01"] != 1 || active["2030-03-02"] != 1 {
+		t.Errorf("unexpected active users %v", active)
+	}
+
+	conversions, err := r.CountPlanChangesByDay(since, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversions["2030-03-02"] != 1 {
+		t.Errorf("expected admin plan changes to be ignored, got %v", conversions)
+	}
+
+	atLimit, err := r.CountFreeUsersAtTodoLimit(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atLimit != 1 {
+		t.Errorf("expected 1 free user at the limit, got %d", atLimit)
+	}
+}
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-todo/internal/models"
+)
+
+const invoiceColumns = `id, user_id, customer_id, subscription_id, number, status, price_id, amount, currency, period_start, period_end, created_at, paid_at, last_event_at`
+
+func scanInvoice(row rowScanner) (*models.Invoice, error) {
+	invoice := models.Invoice{}
+	var paidAt sql.NullTime
+	err := row.Scan(&invoice.ID, &invoice.UserID, &invoice.CustomerID, &invoice.SubscriptionID, &invoice.Number, &invoice.Status, &invoice.PriceID,
+		&invoice.Amount, &invoice.Currency, &invoice.PeriodStart, &invoice.PeriodEnd, &invoice.CreatedAt, &paidAt, &invoice.LastEventAt)
+	if err != nil {
+		return nil, err
+	}
+	if paidAt.Valid {
+		paid := paidAt.Time.UTC()
+		invoice.PaidAt = &paid
+	}
+	invoice.PeriodStart = invoice.PeriodStart.UTC()
+	invoice.PeriodEnd = invoice.PeriodEnd.UTC()
+	invoice.CreatedAt = invoice.CreatedAt.UTC()
+	invoice.LastEventAt = invoice.LastEventAt.UTC()
+	return &invoice, nil
+}
+
+// SaveInvoice inserts or updates an invoice.
+func (r *Repository) SaveInvoice(invoice *models.Invoice) error {
+	stmt, err := r.db.Prepare(`INSERT INTO invoices(` + invoiceColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			user_id = excluded.user_id,
+			customer_id = excluded.customer_id,
+			subscription_id = excluded.subscription_id,
+			number = excluded.number,
+			status = excluded.status,
+			price_id = excluded.price_id,
+			amount = excluded.amount,
+			currency = excluded.currency,
+			period_start = excluded.period_start,
+			period_end = excluded.period_end,
+			paid_at = excluded.paid_at,
+			last_event_at = excluded.last_event_at`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing save invoice statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(invoice.ID, invoice.UserID, invoice.CustomerID, invoice.SubscriptionID, invoice.Number, invoice.Status, invoice.PriceID,
+		invoice.Amount, invoice.Currency, invoice.PeriodStart.UTC(), invoice.PeriodEnd.UTC(), invoice.CreatedAt.UTC(), nullTime(invoice.PaidAt), invoice.LastEventAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Error executing save invoice statement. %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetInvoice(id string) (*models.Invoice, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + invoiceColumns + ` FROM invoices WHERE id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get invoice query. %w", err)
+	}
+	defer stmt.Close()
+
+	invoice, err := scanInvoice(stmt.QueryRow(id))
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error executing get invoice query. %w", err)
+	}
+	return invoice, nil
+}
+
+// GetInvoicesByUserID returns the user's invoices, newest first.
+func (r *Repository) GetInvoicesByUserID(userID string) ([]*models.Invoice, error) {
+	rows, err := r.db.Query(`SELECT `+invoiceColumns+` FROM invoices WHERE user_id = ? ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying invoices by user id. %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []*models.Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning invoices. %w", err)
+		}
+		invoices = append(invoices, invoice)
+	}
+	return invoices, rows.Err()
+}
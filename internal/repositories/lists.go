@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+)
+
+func (r *Repository) CreateList(list *models.List) (int, error) {
+	stmt, err := r.db.Prepare(`INSERT INTO lists(user_id, name) VALUES (?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("Issue preparing insert statement for create list. %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(list.UserID, list.Name)
+	if err != nil {
+		return 0, fmt.Errorf("Issue executing insert statement for create list. %w", err)
+	}
+	_id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("could not get last insert id. %w", err)
+	}
+	return int(_id), nil
+}
+
+func (r *Repository) GetListByID(ID int) (*models.List, error) {
+	stmt, err := r.db.Prepare(`SELECT
+			lists.id,
+			lists.user_id,
+			lists.name,
+			(SELECT COUNT(*) FROM todos WHERE todos.list_id = lists.id)
+		FROM lists
+		WHERE lists.id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing statement for getting list by id. %w", err)
+	}
+	defer stmt.Close()
+
+	list := models.List{}
+	err = stmt.QueryRow(ID).Scan(&list.ID, &list.UserID, &list.Name, &list.TodoCount)
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Issue executing statement for get list by id. %w", err)
+	}
+	return &list, nil
+}
+
+func (r *Repository) GetListsByUserID(userID string) ([]*models.List, error) {
+	stmt, err := r.db.Prepare(`SELECT
+			lists.id,
+			lists.user_id,
+			lists.name,
+			(SELECT COUNT(*) FROM todos WHERE todos.list_id = lists.id)
+		FROM lists
+		WHERE lists.user_id = ?
+		ORDER BY lists.id`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing statement for getting lists by user id. %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Error while querying lists by user id. %w", err)
+	}
+	defer rows.Close()
+
+	lists := []*models.List{}
+	for rows.Next() {
+		list := models.List{}
+		err := rows.Scan(&list.ID, &list.UserID, &list.Name, &list.TodoCount)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning lists. %w", err)
+		}
+		lists = append(lists, &list)
+	}
+
+	return lists, rows.Err()
+}
+
+func (r *Repository) UpdateList(list models.List) error {
+	stmt, err := r.db.Prepare(`UPDATE lists SET name = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing statement for updating list. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(list.Name, list.ID)
+	if err != nil {
+		return fmt.Errorf("Error while executing update list statement. %w", err)
+	}
+	return nil
+}
+
+// DeleteList removes the list along with every todo on it.
+func (r *Repository) DeleteList(listID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin delete list transaction. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM todos WHERE list_id = ?`, listID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting todos for list. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM lists WHERE id = ?`, listID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting list. %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Could not commit delete list transaction. %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) CountListsByUserID(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM lists WHERE user_id = ?`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting lists by user id. %w", err)
+	}
+	return count, nil
+}
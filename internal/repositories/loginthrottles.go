@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-todo/internal/models"
+	"time"
+)
+
+const loginThrottleColumns = `id, kind, key, failures, last_failed_at, locked_until`
+
+func scanLoginThrottle(row rowScanner) (*models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{}
+	var lockedUntil sql.NullTime
+	err := row.Scan(&throttle.ID, &throttle.Kind, &throttle.Key, &throttle.Failures, &throttle.LastFailedAt, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	throttle.LastFailedAt = throttle.LastFailedAt.UTC()
+	if lockedUntil.Valid {
+		until := lockedUntil.Time.UTC()
+		throttle.LockedUntil = &until
+	}
+	return &throttle, nil
+}
+
+func (r *Repository) GetLoginThrottle(kind, key string) (*models.LoginThrottle, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + loginThrottleColumns + ` FROM login_throttles WHERE kind = ? AND key = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get login throttle query. %w", err)
+	}
+	defer stmt.Close()
+
+	throttle, err := scanLoginThrottle(stmt.QueryRow(kind, key))
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error executing get login throttle query. %w", err)
+	}
+	return throttle, nil
+}
+
+// RecordLoginFailure counts a failed login and returns the new number of
+// failures. Failures before forgetBefore are forgotten first.
+func (r *Repository) RecordLoginFailure(kind, key string, now, forgetBefore time.Time) (int, error) {
+	stmt, err := r.db.Prepare(`INSERT INTO login_throttles(kind, key, failures, last_failed_at) VALUES (?, ?, 1, ?)
+		ON CONFLICT(kind, key) DO UPDATE SET
+			failures = CASE WHEN last_failed_at < ? THEN 1 ELSE failures + 1 END,
+			last_failed_at = excluded.last_failed_at
+		RETURNING failures`)
+	if err != nil {
+		return 0, fmt.Errorf("Issue preparing record login failure statement. %w", err)
+	}
+	defer stmt.Close()
+
+	var failures int
+	err = stmt.QueryRow(kind, key, now.UTC(), forgetBefore.UTC()).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("Error executing record login failure statement. %w", err)
+	}
+	return failures, nil
+}
+
+func (r *Repository) LockLogin(kind, key string, until time.Time) error {
+	stmt, err := r.db.Prepare(`UPDATE login_throttles SET locked_until = ? WHERE kind = ? AND key = ?`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing lock login statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(until.UTC(), kind, key)
+	if err != nil {
+		return fmt.Errorf("Error executing lock login statement. %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) ClearLoginThrottle(kind, key string) error {
+	_, err := r.db.Exec(`DELETE FROM login_throttles WHERE kind = ? AND key = ?`, kind, key)
+	if err != nil {
+		return fmt.Errorf("Error clearing login throttle. %w", err)
+	}
+	return nil
+}
+
+// DeleteLoginThrottle removes a throttle by id, reporting whether it
+// existed.
+func (r *Repository) DeleteLoginThrottle(id int) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM login_throttles WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("Error deleting login throttle. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetLockedLoginThrottles returns the throttles locked at now, those
+// locked longest first.
+func (r *Repository) GetLockedLoginThrottles(now time.Time) ([]*models.LoginThrottle, error) {
+	rows, err := r.db.Query(`SELECT `+loginThrottleColumns+` FROM login_throttles WHERE locked_until > ? ORDER BY locked_until DESC, id`, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("Error querying locked login throttles. %w", err)
+	}
+	defer rows.Close()
+
+	throttles := []*models.LoginThrottle{}
+	for rows.Next() {
+		throttle, err := scanLoginThrottle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning login throttles. %w", err)
+		}
+		throttles = append(throttles, throttle)
+	}
+
+	return throttles, rows.Err()
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-todo/internal/models"
+	"time"
+)
+
+const outboxEmailColumns = `id, to_address, subject, body, status, attempts, last_error, created_at, sent_at, next_attempt_at`
+
+func scanOutboxEmail(row rowScanner) (*models.OutboxEmail, error) {
+	email := models.OutboxEmail{}
+	var sentAt, nextAttemptAt sql.NullTime
+	err := row.Scan(&email.ID, &email.To, &email.Subject, &email.Body, &email.Status, &email.Attempts, &email.LastError, &email.CreatedAt, &sentAt, &nextAttemptAt)
+	if err != nil {
+		return nil, err
+	}
+	email.CreatedAt = email.CreatedAt.UTC()
+	if sentAt.Valid {
+		at := sentAt.Time.UTC()
+		email.SentAt = &at
+	}
+	if nextAttemptAt.Valid {
+		at := nextAttemptAt.Time.UTC()
+		email.NextAttemptAt = &at
+	}
+	return &email, nil
+}
+
+// SaveOutboxEmail stores an email to be sent and returns its id.
+func (r *Repository) SaveOutboxEmail(email *models.OutboxEmail) (int, error) {
+	stmt, err := r.db.Prepare(`INSERT INTO outbox_emails(to_address, subject, body, status, attempts, last_error, created_at, sent_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("Issue preparing save outbox email statement. %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(email.To, email.Subject, email.Body, email.Status, email.Attempts, email.LastError, email.CreatedAt.UTC(), nullTime(email.SentAt), nullTime(email.NextAttemptAt))
+	if err != nil {
+		return 0, fmt.Errorf("Error executing save outbox email statement. %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("could not get last insert id. %w", err)
+	}
+	return int(id), nil
+}
+
+func (r *Repository) GetOutboxEmail(id int) (*models.OutboxEmail, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + outboxEmailColumns + ` FROM outbox_emails WHERE id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get outbox email query. %w", err)
+	}
+	defer stmt.Close()
+
+	email, err := scanOutboxEmail(stmt.QueryRow(id))
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error executing get outbox email query. %w", err)
+	}
+	return email, nil
+}
+
+// GetDueOutboxEmails returns up to limit emails due to be sent at now,
+// oldest first.
+func (r *Repository) GetDueOutboxEmails(now time.Time, limit int) ([]*models.OutboxEmail, error) {
+	rows, err := r.db.Query(`SELECT `+outboxEmailColumns+` FROM outbox_emails WHERE next_attempt_at IS NOT NULL AND next_attempt_at <= ? ORDER BY created_at, id LIMIT ?`, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying outbox emails. %w", err)
+	}
+	defer rows.Close()
+
+	emails := []*models.OutboxEmail{}
+	for rows.Next() {
+		email, err := scanOutboxEmail(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning outbox emails. %w", err)
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
+
+// ClaimOutboxEmail takes an email that is due at now for one attempt,
+// moving its next attempt to claimedUntil so nothing else takes it in the
+// meantime. It reports false when the email is not due.
+func (r *Repository) ClaimOutboxEmail(id int, now, claimedUntil time.Time) (bool, error) {
+	res, err := r.db.Exec(`UPDATE outbox_emails SET attempts = attempts + 1, next_attempt_at = ?
+		WHERE id = ? AND next_attempt_at IS NOT NULL AND next_attempt_at <= ?`, claimedUntil.UTC(), id, now.UTC())
+	if err != nil {
+		return false, fmt.Errorf("Error claiming outbox email. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *Repository) MarkOutboxEmailSent(id int, sentAt time.Time) error {
+	_, err := r.db.Exec(`UPDATE outbox_emails SET status = ?, last_error = '', sent_at = ?, next_attempt_at = NULL WHERE id = ?`,
+		models.OutboxEmailSent, sentAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("Error marking outbox email sent. %w", err)
+	}
+	return nil
+}
+
+// MarkOutboxEmailFailed records why an attempt failed. The email is
+// retried at nextAttemptAt, or has failed for good when it is nil.
+func (r *Repository) MarkOutboxEmailFailed(id int, lastError string, nextAttemptAt *time.Time) error {
+	status := models.OutboxEmailPending
+	if nextAttemptAt == nil {
+		status = models.OutboxEmailFailed
+	}
+
+	_, err := r.db.Exec(`UPDATE outbox_emails SET status = ?, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		status, lastError, nullTime(nextAttemptAt), id)
+	if err != nil {
+		return fmt.Errorf("Error marking outbox email failed. %w", err)
+	}
+	return nil
+}
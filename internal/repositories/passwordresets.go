@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+)
+
+// CreatePasswordResetToken stores a new reset token for the user. Any
+// tokens they were sent before are used up so only the newest email works.
+func (r *Repository) CreatePasswordResetToken(userID, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin create password reset token transaction. %w", err)
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL`, now, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error invalidating password reset tokens. %w", err)
+	}
+
+	if _, err := tx.Exec(`INSERT INTO password_reset_tokens(user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)`, userID, tokenHash, expiresAt.UTC(), now); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error creating password reset token. %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Could not commit create password reset token transaction. %w", err)
+	}
+	return nil
+}
+
+// GetPasswordResetUserID returns the user a reset token belongs to, or ""
+// when the token is unknown, used or expired at now.
+func (r *Repository) GetPasswordResetUserID(tokenHash string, now time.Time) (string, error) {
+	stmt, err := r.db.Prepare(`SELECT user_id FROM password_reset_tokens WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`)
+	if err != nil {
+		return "", fmt.Errorf("Issue preparing get password reset token query. %w", err)
+	}
+	defer stmt.Close()
+
+	var userID string
+	err = stmt.QueryRow(tokenHash, now.UTC()).Scan(&userID)
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return "", nil
+		}
+		return "", fmt.Errorf("Error executing get password reset token query. %w", err)
+	}
+	return userID, nil
+}
+
+// ResetPassword redeems a reset token and sets the user's password hash in
+// one transaction. It returns the user's id, or "" when the token is
+// unknown, used or expired at now.
+func (r *Repository) ResetPassword(tokenHash, passwordHash string, now time.Time) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("Could not begin reset password transaction. %w", err)
+	}
+
+	// marking the token used in the same statement that checks it means it
+	// cannot be redeemed twice
+	var userID string
+	err = tx.QueryRow(`UPDATE password_reset_tokens SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING user_id`, now.UTC(), tokenHash, now.UTC()).Scan(&userID)
+	if err != nil {
+		tx.Rollback()
+		if err.Error() == sqlNoResult {
+			return "", nil
+		}
+		return "", fmt.Errorf("Error redeeming password reset token. %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET password = ? WHERE id = ?`, passwordHash, userID); err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("Error updating user password. %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("Could not commit reset password transaction. %w", err)
+	}
+	return userID, nil
+}
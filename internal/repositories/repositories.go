@@ -13,3 +13,8 @@ func NewRepository(db *sql.DB) *Repository {
 		db: db,
 	}
 }
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
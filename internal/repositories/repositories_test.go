@@ -1 +1,29 @@
 package repositories
+
+import (
+	database "go-todo/internal/db"
+	"go-todo/internal/migrations"
+	"testing"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	db, err := database.ConnectMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if !database.SupportsFTS5(db) {
+		t.Fatal("sqlite3 was built without FTS5, run tests with -tags sqlite_fts5")
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewRepository(db)
+}
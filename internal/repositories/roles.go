@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+)
+
+// loadUserRoles fills in the user's roles and the permissions those roles
+// grant.
+func (r *Repository) loadUserRoles(user *models.User) error {
+	rows, err := r.db.Query(`SELECT
+			roles.name,
+			roles.description,
+			COALESCE(role_permissions.permission, '')
+		FROM user_roles
+		JOIN roles ON roles.id = user_roles.role_id
+		LEFT JOIN role_permissions ON role_permissions.role_id = roles.id
+		WHERE user_roles.user_id = ?`, user.ID)
+	if err != nil {
+		return fmt.Errorf("Error querying roles for user. %w", err)
+	}
+	defer rows.Close()
+
+	user.Roles = map[string]string{}
+	user.Permissions = map[string]bool{}
+	for rows.Next() {
+		var name, description, permission string
+		if err := rows.Scan(&name, &description, &permission); err != nil {
+			return fmt.Errorf("Issue scanning user roles. %w", err)
+		}
+		user.Roles[name] = description
+		if permission != "" {
+			user.Permissions[permission] = true
+		}
+	}
+	return rows.Err()
+}
+
+func (r *Repository) GetRoles() ([]*models.Role, error) {
+	rows, err := r.db.Query(`SELECT
+			roles.id,
+			roles.name,
+			roles.description,
+			COALESCE(role_permissions.permission, '')
+		FROM roles
+		LEFT JOIN role_permissions ON role_permissions.role_id = roles.id
+		ORDER BY roles.name, role_permissions.permission`)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying roles. %w", err)
+	}
+	defer rows.Close()
+
+	roles := []*models.Role{}
+	byID := map[int]*models.Role{}
+	for rows.Next() {
+		role := models.Role{}
+		var permission string
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &permission); err != nil {
+			return nil, fmt.Errorf("Issue scanning roles. %w", err)
+		}
+		existing, ok := byID[role.ID]
+		if !ok {
+			existing = &role
+			byID[role.ID] = existing
+			roles = append(roles, existing)
+		}
+		if permission != "" {
+			existing.Permissions = append(existing.Permissions, permission)
+		}
+	}
+	return roles, rows.Err()
+}
+
+func (r *Repository) GetRoleByName(name string) (*models.Role, error) {
+	role := models.Role{}
+	err := r.db.QueryRow(`SELECT id, name, description FROM roles WHERE name = ?`, name).Scan(&role.ID, &role.Name, &role.Description)
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error getting role by name. %w", err)
+	}
+	return &role, nil
+}
+
+func (r *Repository) GrantRole(userID string, roleID int) error {
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO user_roles(user_id, role_id) VALUES (?, ?)`, userID, roleID)
+	if err != nil {
+		return fmt.Errorf("Error granting role to user. %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) RevokeRole(userID string, roleID int) error {
+	_, err := r.db.Exec(`DELETE FROM user_roles WHERE user_id = ? AND role_id = ?`, userID, roleID)
+	if err != nil {
+		return fmt.Errorf("Error revoking role from user. %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-todo/internal/models"
+	"time"
+)
+
+const stripeEventColumns = `id, type, payload, status, attempts, last_error, created_at, received_at, processed_at, next_attempt_at`
+
+func scanStripeEvent(row rowScanner) (*models.StripeEvent, error) {
+	event := models.StripeEvent{}
+	var createdAt, processedAt, nextAttemptAt sql.NullTime
+	err := row.Scan(&event.ID, &event.Type, &event.Payload, &event.Status, &event.Attempts, &event.LastError, &createdAt, &event.ReceivedAt, &processedAt, &nextAttemptAt)
+	if err != nil {
+		return nil, err
+	}
+	event.ReceivedAt = event.ReceivedAt.UTC()
+	// events received before created_at was recorded
+	event.CreatedAt = event.ReceivedAt
+	if createdAt.Valid {
+		event.CreatedAt = createdAt.Time.UTC()
+	}
+	if processedAt.Valid {
+		at := processedAt.Time.UTC()
+		event.ProcessedAt = &at
+	}
+	if nextAttemptAt.Valid {
+		at := nextAttemptAt.Time.UTC()
+		event.NextAttemptAt = &at
+	}
+	return &event, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// SaveStripeEvent stores an event, reporting false when an event with its
+// id was already stored.
+func (r *Repository) SaveStripeEvent(event *models.StripeEvent) (bool, error) {
+	stmt, err := r.db.Prepare(`INSERT INTO stripe_events(` + stripeEventColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO NOTHING`)
+	if err != nil {
+		return false, fmt.Errorf("Issue preparing save stripe event statement. %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(event.ID, event.Type, event.Payload, event.Status, event.Attempts, event.LastError, event.CreatedAt.UTC(), event.ReceivedAt.UTC(), nullTime(event.ProcessedAt), nullTime(event.NextAttemptAt))
+	if err != nil {
+		return false, fmt.Errorf("Error executing save stripe event statement. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *Repository) GetStripeEvent(id string) (*models.StripeEvent, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + stripeEventColumns + ` FROM stripe_events WHERE id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get stripe event query. %w", err)
+	}
+	defer stmt.Close()
+
+	event, err := scanStripeEvent(stmt.QueryRow(id))
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error executing get stripe event query. %w", err)
+	}
+	return event, nil
+}
+
+func (r *Repository) queryStripeEvents(query string, args ...any) ([]*models.StripeEvent, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying stripe events. %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.StripeEvent{}
+	for rows.Next() {
+		event, err := scanStripeEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning stripe events. %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetStripeEvents returns up to limit events with status, or with any
+// status when it is blank, most recently received first.
+func (r *Repository) GetStripeEvents(status string, limit int) ([]*models.StripeEvent, error) {
+	return r.queryStripeEvents(`SELECT `+stripeEventColumns+` FROM stripe_events WHERE ? = '' OR status = ? ORDER BY received_at DESC LIMIT ?`, status, status, limit)
+}
+
+// GetDueStripeEvents returns up to limit events due to be processed at
+// now, oldest first.
+func (r *Repository) GetDueStripeEvents(now time.Time, limit int) ([]*models.StripeEvent, error) {
+	return r.queryStripeEvents(`SELECT `+stripeEventColumns+` FROM stripe_events WHERE next_attempt_at IS NOT NULL AND next_attempt_at <= ? ORDER BY received_at LIMIT ?`, now.UTC(), limit)
+}
+
+// ClaimStripeEvent takes an event that is due at now for one attempt,
+// moving its next attempt to claimedUntil so nothing else takes it in the
+// meantime. It reports false when the event is not due.
+func (r *Repository) ClaimStripeEvent(id string, now, claimedUntil time.Time) (bool, error) {
+	res, err := r.db.Exec(`UPDATE stripe_events SET attempts = attempts + 1, next_attempt_at = ?
+		WHERE id = ? AND next_attempt_at IS NOT NULL AND next_attempt_at <= ?`, claimedUntil.UTC(), id, now.UTC())
+	if err != nil {
+		return false, fmt.Errorf("Error claiming stripe event. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *Repository) MarkStripeEventProcessed(id string, processedAt time.Time) error {
+	_, err := r.db.Exec(`UPDATE stripe_events SET status = ?, last_error = '', processed_at = ?, next_attempt_at = NULL WHERE id = ?`,
+		models.StripeEventProcessed, processedAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("Error marking stripe event processed. %w", err)
+	}
+	return nil
+}
+
+// MarkStripeEventFailed records why an attempt failed. The event is
+// retried at nextAttemptAt, or has failed for good when it is nil.
+func (r *Repository) MarkStripeEventFailed(id, lastError string, nextAttemptAt *time.Time) error {
+	status := models.StripeEventPending
+	if nextAttemptAt == nil {
+		status = models.StripeEventFailed
+	}
+
+	_, err := r.db.Exec(`UPDATE stripe_events SET status = ?, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		status, lastError, nullTime(nextAttemptAt), id)
+	if err != nil {
+		return fmt.Errorf("Error marking stripe event failed. %w", err)
+	}
+	return nil
+}
+
+// ReplayStripeEvent makes an event due at now whatever its status, so it
+// is processed again.
+func (r *Repository) ReplayStripeEvent(id string, now time.Time) error {
+	_, err := r.db.Exec(`UPDATE stripe_events SET status = ?, next_attempt_at = ? WHERE id = ?`, models.StripeEventPending, now.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("Error replaying stripe event. %w", err)
+	}
+	return nil
+}
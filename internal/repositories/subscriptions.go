@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-todo/internal/models"
+)
+
+const subscriptionColumns = `id, user_id, customer_id, price_id, status, current_period_end, cancel_at_period_end, created_at, updated_at, last_event_at,
+	past_due_since, grace_ends_at, dunning_reminders_sent`
+
+func scanSubscription(row rowScanner) (*models.Subscription, error) {
+	subscription := models.Subscription{}
+	var pastDueSince, graceEndsAt sql.NullTime
+	err := row.Scan(&subscription.ID, &subscription.UserID, &subscription.CustomerID, &subscription.PriceID, &subscription.Status,
+		&subscription.CurrentPeriodEnd, &subscription.CancelAtPeriodEnd, &subscription.CreatedAt, &subscription.UpdatedAt, &subscription.LastEventAt,
+		&pastDueSince, &graceEndsAt, &subscription.DunningRemindersSent)
+	if err != nil {
+		return nil, err
+	}
+	if pastDueSince.Valid {
+		since := pastDueSince.Time.UTC()
+		subscription.PastDueSince = &since
+	}
+	if graceEndsAt.Valid {
+		endsAt := graceEndsAt.Time.UTC()
+		subscription.GraceEndsAt = &endsAt
+	}
+	subscription.CurrentPeriodEnd = subscription.CurrentPeriodEnd.UTC()
+	subscription.CreatedAt = subscription.CreatedAt.UTC()
+	subscription.UpdatedAt = subscription.UpdatedAt.UTC()
+	subscription.LastEventAt = subscription.LastEventAt.UTC()
+	return &subscription, nil
+}
+
+// SaveSubscription inserts or updates a subscription. Its created at is
+// kept from the first save.
+func (r *Repository) SaveSubscription(subscription *models.Subscription) error {
+	stmt, err := r.db.Prepare(`INSERT INTO subscriptions(` + subscriptionColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			user_id = excluded.user_id,
+			customer_id = excluded.customer_id,
+			price_id = excluded.price_id,
+			status = excluded.status,
+			current_period_end = excluded.current_period_end,
+			cancel_at_period_end = excluded.cancel_at_period_end,
+			updated_at = excluded.updated_at,
+			last_event_at = excluded.last_event_at,
+			past_due_since = excluded.past_due_since,
+			grace_ends_at = excluded.grace_ends_at,
+			dunning_reminders_sent = excluded.dunning_reminders_sent`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing save subscription statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(subscription.ID, subscription.UserID, subscription.CustomerID, subscription.PriceID, subscription.Status,
+		subscription.CurrentPeriodEnd.UTC(), subscription.CancelAtPeriodEnd, subscription.CreatedAt.UTC(), subscription.UpdatedAt.UTC(), subscription.LastEventAt.UTC(),
+		nullTime(subscription.PastDueSince), nullTime(subscription.GraceEndsAt), subscription.DunningRemindersSent)
+	if err != nil {
+		return fmt.Errorf("Error executing save subscription statement. %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetSubscription(id string) (*models.Subscription, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get subscription query. %w", err)
+	}
+	defer stmt.Close()
+
+	subscription, err := scanSubscription(stmt.QueryRow(id))
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error executing get subscription query. %w", err)
+	}
+	return subscription, nil
+}
+
+func (r *Repository) querySubscriptions(query string, args ...any) ([]*models.Subscription, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying subscriptions. %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []*models.Subscription{}
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning subscriptions. %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// GetSubscriptionsByUserID returns the user's subscriptions, newest first.
+func (r *Repository) GetSubscriptionsByUserID(userID string) ([]*models.Subscription, error) {
+	return r.querySubscriptions(`SELECT `+subscriptionColumns+` FROM subscriptions WHERE user_id = ? ORDER BY created_at DESC`, userID)
+}
+
+// GetSubscriptionsByStatus returns every subscription with status, oldest
+// first.
+func (r *Repository) GetSubscriptionsByStatus(status string) ([]*models.Subscription, error) {
+	return r.querySubscriptions(`SELECT `+subscriptionColumns+` FROM subscriptions WHERE status = ? ORDER BY created_at`, status)
+}
+
+// SetDunningRemindersSent records how many reminders to pay have been sent
+// for a past due subscription.
+func (r *Repository) SetDunningRemindersSent(subscriptionID string, sent int) error {
+	_, err := r.db.Exec(`UPDATE subscriptions SET dunning_reminders_sent = ? WHERE id = ?`, sent, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("Error setting dunning reminders sent. %w", err)
+	}
+	return nil
+}
@@ -4,16 +4,55 @@ import (
 	"database/sql"
 	"fmt"
 	"go-todo/internal/models"
+	"time"
 )
 
+const todoColumns = `id, user_id, list_id, description, is_complete, due_at, archived_at`
+
+func scanTodo(row rowScanner) (*models.Todo, error) {
+	todo := models.Todo{}
+	var listID sql.NullInt64
+	var dueAt, archivedAt sql.NullTime
+	err := row.Scan(&todo.ID, &todo.UserID, &listID, &todo.Description, &todo.IsComplete, &dueAt, &archivedAt)
+	if err != nil {
+		return nil, err
+	}
+	todo.ListID = int(listID.Int64)
+	if dueAt.Valid {
+		due := dueAt.Time.UTC()
+		todo.DueAt = &due
+	}
+	if archivedAt.Valid {
+		archived := archivedAt.Time.UTC()
+		todo.ArchivedAt = &archived
+	}
+	return &todo, nil
+}
+
+// due dates are always persisted in UTC
+func dueAtValue(dueAt *time.Time) any {
+	if dueAt == nil {
+		return nil
+	}
+	return dueAt.UTC()
+}
+
+// todos created before lists existed may not belong to one
+func listIDValue(listID int) any {
+	if listID == 0 {
+		return nil
+	}
+	return listID
+}
+
 func (r *Repository) CreateTodo(todo *models.Todo) (int, error) {
-	stmt, err := r.db.Prepare(`INSERT INTO todos(user_id, description, is_complete) VALUES (?, ?, false)`)
+	stmt, err := r.db.Prepare(`INSERT INTO todos(user_id, list_id, description, is_complete, due_at, created_at) VALUES (?, ?, ?, false, ?, CURRENT_TIMESTAMP)`)
 	if err != nil {
 		return 0, fmt.Errorf("Issue preparing insert statement for create todo. %w", err)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(todo.UserID, todo.Description)
+	res, err := stmt.Exec(todo.UserID, listIDValue(todo.ListID), todo.Description, dueAtValue(todo.DueAt))
 	if err != nil {
 		return 0, fmt.Errorf("Issu executing insert statement for create todo. %w", err)
 	}
@@ -25,22 +64,26 @@ func (r *Repository) CreateTodo(todo *models.Todo) (int, error) {
 }
 
 func (r *Repository) GetTodoByID(ID int) (*models.Todo, error) {
-	stmt, err := r.db.Prepare("SELECT id, user_id, description, is_complete FROM todos WHERE id = ?")
+	stmt, err := r.db.Prepare("SELECT " + todoColumns + " FROM todos WHERE id = ?")
 	if err != nil {
 		return nil, fmt.Errorf("Issue preparing statment for getting todo by id. %w", err)
 	}
 	defer stmt.Close()
 
-	todo := models.Todo{}
-	err = stmt.QueryRow(ID).Scan(&todo.ID, &todo.UserID, &todo.Description, &todo.IsComplete)
+	todo, err := scanTodo(stmt.QueryRow(ID))
 	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("Issue executing statement for get todo by id. %w", err)
 	}
-	return &todo, nil
+	return todo, nil
 }
 
 func (r *Repository) GetTodosByUserID(userID string, limit int) ([]*models.Todo, error) {
-	query := `SELECT id, user_id, description, is_complete FROM todos WHERE user_id = ?`
+	// todos with a due date come first, soonest first, then undated todos
+	// in the order they were created
+	query := `SELECT ` + todoColumns + ` FROM todos WHERE user_id = ? ORDER BY due_at IS NULL, due_at, id`
 	if limit > 0 {
 		query += ` limit ?`
 	}
@@ -64,31 +107,215 @@ func (r *Repository) GetTodosByUserID(userID string, limit int) ([]*models.Todo,
 
 	todoList := []*models.Todo{}
 	for rows.Next() {
-		todo := models.Todo{}
-		err := rows.Scan(&todo.ID, &todo.UserID, &todo.Description, &todo.IsComplete)
+		todo, err := scanTodo(rows)
 		if err != nil {
 			return nil, fmt.Errorf("Issue scanning todos. %w", err)
 		}
-		todoList = append(todoList, &todo)
+		todoList = append(todoList, todo)
 	}
 
 	return todoList, nil
 }
 
+// GetTodosByListID returns every todo on the list, archived ones last,
+// otherwise ordered like GetTodosByUserID.
+func (r *Repository) GetTodosByListID(listID int) ([]*models.Todo, error) {
+	rows, err := r.db.Query(`SELECT `+todoColumns+` FROM todos WHERE list_id = ? ORDER BY archived_at IS NOT NULL, due_at IS NULL, due_at, id`, listID)
+	if err != nil {
+		return nil, fmt.Errorf("Error while querying todos by list id. %w", err)
+	}
+	defer rows.Close()
+
+	todoList := []*models.Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning todos by list id. %w", err)
+		}
+		todoList = append(todoList, todo)
+	}
+
+	return todoList, rows.Err()
+}
+
+// GetTodosPage returns the user's todos, only those on listID unless it
+// is 0, ordered like GetTodosByUserID.
+func (r *Repository) GetTodosPage(userID string, listID int, limit, offset int) ([]*models.Todo, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + todoColumns + ` FROM todos
+		WHERE user_id = ? AND (? = 0 OR list_id = ?)
+		ORDER BY due_at IS NULL, due_at, id
+		LIMIT ? OFFSET ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get todos page query. %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID, listID, listID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("Error executing get todos page query. %w", err)
+	}
+	defer rows.Close()
+
+	todoList := []*models.Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning todos page. %w", err)
+		}
+		todoList = append(todoList, todo)
+	}
+
+	return todoList, rows.Err()
+}
+
+// CountTodos counts the todos GetTodosPage pages through.
+func (r *Repository) CountTodos(userID string, listID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM todos WHERE user_id = ? AND (? = 0 OR list_id = ?)`, userID, listID, listID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting todos. %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repository) CountTodosByUserID(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM todos WHERE user_id = ?`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting todos by user id. %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repository) CountActiveTodosByUserID(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM todos WHERE user_id = ? AND archived_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting active todos by user id. %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repository) CountArchivedTodosByUserID(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM todos WHERE user_id = ? AND archived_at IS NOT NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting archived todos by user id. %w", err)
+	}
+	return count, nil
+}
+
+func (r *Repository) CountCompletedTodosByUserID(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM todos WHERE user_id = ? AND is_complete = 1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting completed todos by user id. %w", err)
+	}
+	return count, nil
+}
+
 func (r *Repository) UpdateTodo(todo models.Todo) error {
-	stmt, err := r.db.Prepare(`UPDATE todos SET user_id = ?, description = ?, is_complete = ? WHERE id = ?`)
+	// completed_at keeps the first completion time until the todo is
+	// reopened, a new due date gets a new reminder
+	stmt, err := r.db.Prepare(`UPDATE todos SET
+			user_id = ?,
+			list_id = ?,
+			description = ?,
+			is_complete = ?,
+			due_at = ?,
+			completed_at = CASE WHEN ? THEN COALESCE(completed_at, CURRENT_TIMESTAMP) ELSE NULL END,
+			reminder_sent_at = CASE WHEN due_at IS ? THEN reminder_sent_at ELSE NULL END
+		WHERE id = ?`)
 	if err != nil {
 		return fmt.Errorf("Issue preparing statement for updating todos. %w", err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(todo.UserID, todo.Description, todo.IsComplete, todo.ID)
+	_, err = stmt.Exec(todo.UserID, listIDValue(todo.ListID), todo.Description, todo.IsComplete, dueAtValue(todo.DueAt), todo.IsComplete, dueAtValue(todo.DueAt), todo.ID)
 	if err != nil {
 		return fmt.Errorf("Error while executing update todo statement. %w", err)
 	}
 	return nil
 }
 
+// activeTodoOrder is the order todos keep their place in when some have to
+// be archived, open todos first, then those due soonest, then the oldest.
+const activeTodoOrder = `is_complete, due_at IS NULL, due_at, id`
+
+// ArchiveTodosOverLimit archives the user's active todos beyond the first
+// keep in activeTodoOrder and returns how many were archived.
+func (r *Repository) ArchiveTodosOverLimit(userID string, keep int, now time.Time) (int, error) {
+	res, err := r.db.Exec(`UPDATE todos SET archived_at = ?
+		WHERE user_id = ? AND archived_at IS NULL AND id NOT IN (
+			SELECT id FROM todos WHERE user_id = ? AND archived_at IS NULL
+			ORDER BY `+activeTodoOrder+` LIMIT ?
+		)`, now.UTC(), userID, userID, keep)
+	if err != nil {
+		return 0, fmt.Errorf("Error archiving todos over the limit. %w", err)
+	}
+	archived, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get archived todo count. %w", err)
+	}
+	return int(archived), nil
+}
+
+// RestoreArchivedTodos makes up to limit of the user's archived todos
+// active again, all of them when limit is 0, and returns how many were
+// restored. Todos archived first are restored first.
+func (r *Repository) RestoreArchivedTodos(userID string, limit int) (int, error) {
+	if limit <= 0 {
+		// no limit in SQLite
+		limit = -1
+	}
+	res, err := r.db.Exec(`UPDATE todos SET archived_at = NULL
+		WHERE id IN (
+			SELECT id FROM todos WHERE user_id = ? AND archived_at IS NOT NULL
+			ORDER BY archived_at, `+activeTodoOrder+` LIMIT ?
+		)`, userID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("Error restoring archived todos. %w", err)
+	}
+	restored, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get restored todo count. %w", err)
+	}
+	return int(restored), nil
+}
+
+// SetActiveTodos makes todoIDs the user's only active todos, archiving the
+// rest. Ids of other users' todos are ignored.
+func (r *Repository) SetActiveTodos(userID string, todoIDs []int, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin set active todos transaction. %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE todos SET archived_at = COALESCE(archived_at, ?) WHERE user_id = ?`, now.UTC(), userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error archiving todos. %w", err)
+	}
+
+	stmt, err := tx.Prepare(`UPDATE todos SET archived_at = NULL WHERE id = ? AND user_id = ?`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Issue preparing statement to restore todos. %w", err)
+	}
+	defer stmt.Close()
+
+	for _, todoID := range todoIDs {
+		if _, err := stmt.Exec(todoID, userID); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Error restoring todo. %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Could not commit set active todos transaction. %w", err)
+	}
+	return nil
+}
+
 func (r *Repository) DeleteTodo(todoID int) error {
 	stmt, err := r.db.Prepare("DELETE FROM todos WHERE id = ?")
 	if err != nil {
@@ -138,3 +365,94 @@ func (r *Repository) DeleteUnattributedTodos() error {
 	}
 	return nil
 }
+
+// SearchTodos runs an FTS5 match query over the user's todos, best
+// matches first.
+func (r *Repository) SearchTodos(userID string, query string, limit int) ([]*models.SearchResult, error) {
+	stmt, err := r.db.Prepare(`SELECT
+			todos.id,
+			todos.user_id,
+			todos.list_id,
+			todos.description,
+			todos.is_complete,
+			todos.due_at,
+			todos.archived_at,
+			snippet(todos_fts, 0, ?, ?, '…', 16)
+		FROM todos_fts
+		JOIN todos ON todos.id = todos_fts.rowid
+		WHERE todos_fts MATCH ? AND todos.user_id = ?
+		ORDER BY bm25(todos_fts), todos.id
+		LIMIT ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing statement for searching todos. %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(models.SnippetMatchStart, models.SnippetMatchEnd, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error while searching todos. %w", err)
+	}
+	defer rows.Close()
+
+	results := []*models.SearchResult{}
+	for rows.Next() {
+		todo := models.Todo{}
+		var listID sql.NullInt64
+		var dueAt, archivedAt sql.NullTime
+		result := models.SearchResult{Todo: &todo}
+		err := rows.Scan(&todo.ID, &todo.UserID, &listID, &todo.Description, &todo.IsComplete, &dueAt, &archivedAt, &result.Snippet)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning todo search results. %w", err)
+		}
+		todo.ListID = int(listID.Int64)
+		if dueAt.Valid {
+			due := dueAt.Time.UTC()
+			todo.DueAt = &due
+		}
+		if archivedAt.Valid {
+			archived := archivedAt.Time.UTC()
+			todo.ArchivedAt = &archived
+		}
+		results = append(results, &result)
+	}
+
+	return results, rows.Err()
+}
+
+// GetTodosDueForReminder returns the open todos due after now and by
+// until whose reminder has not been sent, by user and then due date.
+func (r *Repository) GetTodosDueForReminder(now, until time.Time) ([]*models.Todo, error) {
+	rows, err := r.db.Query(`SELECT `+todoColumns+` FROM todos
+		WHERE is_complete = false AND archived_at IS NULL AND reminder_sent_at IS NULL AND due_at > ? AND due_at <= ?
+		ORDER BY user_id, due_at, id`, now.UTC(), until.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("Error querying todos due for reminder. %w", err)
+	}
+	defer rows.Close()
+
+	todos := []*models.Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning todos due for reminder. %w", err)
+		}
+		todos = append(todos, todo)
+	}
+
+	return todos, rows.Err()
+}
+
+// ClaimTodoReminder records that the reminder for a todo was sent at
+// sentAt, reporting false when it already had been.
+func (r *Repository) ClaimTodoReminder(todoID int, sentAt time.Time) (bool, error) {
+	res, err := r.db.Exec(`UPDATE todos SET reminder_sent_at = ? WHERE id = ? AND reminder_sent_at IS NULL`, sentAt.UTC(), todoID)
+	if err != nil {
+		return false, fmt.Errorf("Error claiming todo reminder. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
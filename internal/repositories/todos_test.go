@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"go-todo/internal/models"
+	"testing"
+	"time"
+)
+
+func TestGetTodosByUserIDSortsByDueDate(t *testing.T) {
+	r := newTestRepository(t)
+
+	later := time.Date(2030, 1, 2, 9, 0, 0, 0, time.UTC)
+	sooner := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	for _, todo := range []models.Todo{
+		{UserID: "u1", Description: "undated"},
+		{UserID: "u1", Description: "later", DueAt: &later},
+		{UserID: "u1", Description: "sooner", DueAt: &sooner},
+	} {
+		if _, err := r.CreateTodo(&todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todos, err := r.GetTodosByUserID("u1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, todo := range todos {
+		got = append(got, todo.Description)
+	}
+	want := []string{"sooner", "later", "undated"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected order %v, got %v", want, got)
+		}
+	}
+
+	if !todos[0].DueAt.Equal(sooner) {
+		t.Errorf("expected due date %v to round trip, got %v", sooner, todos[0].DueAt)
+	}
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetTOTPSecret returns the user's authenticator secret, which is set
+// during enrollment before two factor is enabled, or "" when there is none.
+func (r *Repository) GetTOTPSecret(userID string) (string, error) {
+	var secret sql.NullString
+	err := r.db.QueryRow(`SELECT totp_secret FROM users WHERE id = ?`, userID).Scan(&secret)
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return "", nil
+		}
+		return "", fmt.Errorf("Error getting totp secret. %w", err)
+	}
+	return secret.String, nil
+}
+
+// SetPendingTOTPSecret starts enrollment with secret. It does nothing once
+// two factor is enabled so an enabled secret cannot be swapped out.
+func (r *Repository) SetPendingTOTPSecret(userID, secret string) error {
+	stmt, err := r.db.Prepare(`UPDATE users SET totp_secret = ? WHERE id = ? AND two_factor_enabled_at IS NULL`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing set totp secret statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(secret, userID)
+	if err != nil {
+		return fmt.Errorf("Error executing set totp secret statement. %w", err)
+	}
+	return nil
+}
+
+// EnableTwoFactor turns two factor on for the user, recording step as the
+// last code used, and replaces their recovery codes.
+func (r *Repository) EnableTwoFactor(userID string, step int64, recoveryCodeHashes []string, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin enable two factor transaction. %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET two_factor_enabled_at = ?, totp_last_step = ? WHERE id = ?`, now.UTC(), step, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error enabling two factor. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting recovery codes. %w", err)
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`INSERT INTO recovery_codes(user_id, code_hash, created_at) VALUES (?, ?, ?)`, userID, codeHash, now.UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Error creating recovery code. %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Could not commit enable two factor transaction. %w", err)
+	}
+	return nil
+}
+
+// DisableTwoFactor turns two factor off for the user and forgets their
+// secret and recovery codes.
+func (r *Repository) DisableTwoFactor(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin disable two factor transaction. %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET totp_secret = NULL, two_factor_enabled_at = NULL, totp_last_step = NULL WHERE id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error disabling two factor. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting recovery codes. %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Could not commit disable two factor transaction. %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep records that the code for step was used. It reports false
+// when a code for step or a later one was already used, so each code only
+// works once.
+func (r *Repository) UseTOTPStep(userID string, step int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE users SET totp_last_step = ?
+		WHERE id = ? AND two_factor_enabled_at IS NOT NULL AND (totp_last_step IS NULL OR totp_last_step < ?)`, step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("Error using totp step. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+// UseRecoveryCode marks one of the user's unused recovery codes as used.
+// It reports false when they have no such code.
+func (r *Repository) UseRecoveryCode(userID, codeHash string, now time.Time) (bool, error) {
+	res, err := r.db.Exec(`UPDATE recovery_codes SET used_at = ?
+		WHERE id = (SELECT id FROM recovery_codes WHERE user_id = ? AND code_hash = ? AND used_at IS NULL LIMIT 1)`, now.UTC(), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("Error using recovery code. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *Repository) CountUnusedRecoveryCodes(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting recovery codes. %w", err)
+	}
+	return count, nil
+}
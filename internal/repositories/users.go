@@ -1,20 +1,52 @@
 package repositories
 
 import (
+	"database/sql"
 	"fmt"
 	"go-todo/internal/models"
+	"strings"
+	"time"
 )
 
 const sqlNoResult = "sql: no rows in result set"
 
+const userColumns = `id, name, email, password, is_paid_user, customer_stripe_id, time_zone, email_verified_at, two_factor_enabled_at, payment_grace_ends_at`
+
+func scanUser(row rowScanner) (*models.User, error) {
+	user := models.User{}
+	var emailVerifiedAt, twoFactorEnabledAt, paymentGraceEndsAt sql.NullTime
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.IsPaidUser, &user.StripeCustomerID, &user.TimeZone, &emailVerifiedAt, &twoFactorEnabledAt, &paymentGraceEndsAt)
+	if err != nil {
+		return nil, err
+	}
+	if emailVerifiedAt.Valid {
+		verifiedAt := emailVerifiedAt.Time.UTC()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	if twoFactorEnabledAt.Valid {
+		enabledAt := twoFactorEnabledAt.Time.UTC()
+		user.TwoFactorEnabledAt = &enabledAt
+	}
+	if paymentGraceEndsAt.Valid {
+		graceEndsAt := paymentGraceEndsAt.Time.UTC()
+		user.PaymentGraceEndsAt = &graceEndsAt
+	}
+	return &user, nil
+}
+
 func (r *Repository) SaveUser(user models.User) error {
-	stmt, err := r.db.Prepare(`INSERT INTO users(id, name, email, password, is_paid_user, customer_stripe_id) VALUES (?, ?, ?, ?, ?, ?)`)
+	stmt, err := r.db.Prepare(`INSERT INTO users(id, name, email, password, is_paid_user, customer_stripe_id, time_zone, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`)
 	if err != nil {
 		return fmt.Errorf("Issue while preparing save user statement. %w", err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(&user.ID, &user.Name, &user.Email, &user.Password, &user.IsPaidUser, &user.StripeCustomerID)
+	timeZone := user.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	_, err = stmt.Exec(&user.ID, &user.Name, &user.Email, &user.Password, &user.IsPaidUser, &user.StripeCustomerID, timeZone)
 	if err != nil {
 		return fmt.Errorf("Error while executing save user statement. %w", err)
 	}
@@ -23,14 +55,13 @@ func (r *Repository) SaveUser(user models.User) error {
 }
 
 func (r *Repository) GetUserByID(ID string) (*models.User, error) {
-	stmt, err := r.db.Prepare(`SELECT id, name, email, password, is_paid_user, customer_stripe_id FROM users WHERE id = ?`)
+	stmt, err := r.db.Prepare(`SELECT ` + userColumns + ` FROM users WHERE id = ?`)
 	if err != nil {
 		return nil, fmt.Errorf("Issue while preparing get user by id statement. %w", err)
 	}
 	defer stmt.Close()
 
-	user := models.User{}
-	err = stmt.QueryRow(ID).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.IsPaidUser, &user.StripeCustomerID)
+	user, err := scanUser(stmt.QueryRow(ID))
 	if err != nil {
 		// TODO perhaps better to include a count() to query and if 0 return nil?
 		if err.Error() == sqlNoResult {
@@ -39,25 +70,29 @@ func (r *Repository) GetUserByID(ID string) (*models.User, error) {
 		return nil, fmt.Errorf("Error shile executing get user by id query. %w", err)
 	}
 
-	return &user, nil
+	err = r.loadUserRoles(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 func (r *Repository) GetUserByEmail(email string) (*models.User, error) {
-	stmt, err := r.db.Prepare(`SELECT id, name, email, password, is_paid_user, customer_stripe_id FROM users WHERE email = ?`)
+	stmt, err := r.db.Prepare(`SELECT ` + userColumns + ` FROM users WHERE email = ?`)
 	if err != nil {
 		return nil, fmt.Errorf("Issue preparing get user by email query. %w", err)
 	}
 	defer stmt.Close()
 
-	user := models.User{}
-	err = stmt.QueryRow(email).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.IsPaidUser, &user.StripeCustomerID)
+	user, err := scanUser(stmt.QueryRow(email))
 	if err != nil {
 		if err.Error() == sqlNoResult {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("Error while executing get user by email query. %w", err)
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (r *Repository) UserEmailExists(email string) (bool, error) {
@@ -79,38 +114,20 @@ func (r *Repository) UserEmailExists(email string) (bool, error) {
 }
 
 func (r *Repository) GetUserByStripeID(customerStripeID string) (*models.User, error) {
-	qry := `SELECT 
-				id, 
-				name, 
-				email, 
-				password, 
-				is_paid_user, 
-				customer_stripe_id 
-			FROM users 
-			WHERE customer_stripe_id = ?`
-
-	stmt, err := r.db.Prepare(qry)
+	stmt, err := r.db.Prepare(`SELECT ` + userColumns + ` FROM users WHERE customer_stripe_id = ?`)
 	if err != nil {
 		return nil, fmt.Errorf("Issue preparing get user by stripe id statement. %w", err)
 	}
 	defer stmt.Close()
 
-	user := models.User{}
-	err = stmt.QueryRow(customerStripeID).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Password,
-		&user.IsPaidUser,
-		&user.StripeCustomerID,
-	)
+	user, err := scanUser(stmt.QueryRow(customerStripeID))
 	if err != nil {
 		if err.Error() == sqlNoResult {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("Error executing get user by stripe id statement. %w", err)
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (r *Repository) AddStripeIDToUser(userID, stripeID string) error {
@@ -141,6 +158,16 @@ func (r *Repository) UpdateUserPaymentStatus(userID string, isPaidUser bool) err
 	return nil
 }
 
+// SetUserPaymentGraceEndsAt records when the user's grace period for a
+// failed payment ends, nil when they are not in one.
+func (r *Repository) SetUserPaymentGraceEndsAt(userID string, graceEndsAt *time.Time) error {
+	_, err := r.db.Exec(`UPDATE users SET payment_grace_ends_at = ? WHERE id = ?`, nullTime(graceEndsAt), userID)
+	if err != nil {
+		return fmt.Errorf("Error setting user payment grace period. %w", err)
+	}
+	return nil
+}
+
 func (r *Repository) UserIsPaidUser(userID string) (bool, error) {
 	stmt, err := r.db.Prepare(`SELECT is_paid_user FROM users WHERE id = ?`)
 	if err != nil {
@@ -155,3 +182,241 @@ func (r *Repository) UserIsPaidUser(userID string) (bool, error) {
 	}
 	return isPaidUser, nil
 }
+
+func (r *Repository) UpdateUserTimeZone(userID, timeZone string) error {
+	stmt, err := r.db.Prepare(`UPDATE users SET time_zone = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing update user time zone statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(timeZone, userID)
+	if err != nil {
+		return fmt.Errorf("Error executing update user time zone statement. %w", err)
+	}
+	return nil
+}
+
+// MarkEmailVerified records that the user verified email, unless their
+// address has changed since. It reports whether the user was found with
+// that email.
+func (r *Repository) MarkEmailVerified(userID, email string, verifiedAt time.Time) (bool, error) {
+	stmt, err := r.db.Prepare(`UPDATE users SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ? AND email = ?`)
+	if err != nil {
+		return false, fmt.Errorf("Issue preparing mark email verified statement. %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(verifiedAt.UTC(), userID, email)
+	if err != nil {
+		return false, fmt.Errorf("Error executing mark email verified statement. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+// ClaimEmailVerificationSend records that a verification email is being
+// sent at now, unless one was already sent after notBefore. It reports
+// whether the email may be sent.
+func (r *Repository) ClaimEmailVerificationSend(userID string, now, notBefore time.Time) (bool, error) {
+	stmt, err := r.db.Prepare(`UPDATE users SET email_verification_sent_at = ?
+		WHERE id = ? AND (email_verification_sent_at IS NULL OR email_verification_sent_at <= ?)`)
+	if err != nil {
+		return false, fmt.Errorf("Issue preparing claim email verification send statement. %w", err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(now.UTC(), userID, notBefore.UTC())
+	if err != nil {
+		return false, fmt.Errorf("Error executing claim email verification send statement. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+// userSearchPattern turns a search query into a LIKE pattern, escaping the
+// LIKE wildcards so they are matched literally.
+func userSearchPattern(query string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(strings.ToLower(query)) + "%"
+}
+
+const userSearchClause = `(? = '' OR lower(name) LIKE ? ESCAPE '\' OR lower(email) LIKE ? ESCAPE '\')`
+
+// ListUsers returns users whose name or email contains query, ordered by
+// email. An empty query matches every user.
+func (r *Repository) ListUsers(query string, limit, offset int) ([]*models.User, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + userColumns + ` FROM users WHERE ` + userSearchClause + ` ORDER BY email LIMIT ? OFFSET ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing list users query. %w", err)
+	}
+	defer stmt.Close()
+
+	pattern := userSearchPattern(query)
+	rows, err := stmt.Query(query, pattern, pattern, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("Error executing list users query. %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning users. %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Issue reading users. %w", err)
+	}
+	rows.Close()
+
+	// roles are loaded once the rows are closed, tests run with one connection
+	for _, user := range users {
+		err = r.loadUserRoles(user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
+}
+
+// GetBillingUsers returns every user with a stripe customer or a
+// subscription, without their roles.
+func (r *Repository) GetBillingUsers() ([]*models.User, error) {
+	rows, err := r.db.Query(`SELECT ` + userColumns + ` FROM users
+		WHERE customer_stripe_id != '' OR id IN (SELECT user_id FROM subscriptions)
+		ORDER BY email`)
+	if err != nil {
+		return nil, fmt.Errorf("Error executing get billing users query. %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning billing users. %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// CountUsers counts the users ListUsers would return for query without a
+// limit.
+func (r *Repository) CountUsers(query string) (int, error) {
+	pattern := userSearchPattern(query)
+
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE `+userSearchClause, query, pattern, pattern).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting users. %w", err)
+	}
+	return count, nil
+}
+
+// UpdateUser saves the user's details. Changing their email means the new
+// address has to be verified again.
+func (r *Repository) UpdateUser(user models.User) error {
+	stmt, err := r.db.Prepare(`UPDATE users SET
+			name = ?,
+			email = ?,
+			email_verified_at = CASE WHEN email = ? THEN email_verified_at ELSE NULL END
+		WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing update user statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(user.Name, user.Email, user.Email, user.ID)
+	if err != nil {
+		return fmt.Errorf("Error executing update user statement. %w", err)
+	}
+	return nil
+}
+
+// DeleteUser removes the user along with their todos, lists, access
+// tokens, password reset tokens, recovery codes, session records,
+// subscriptions and roles.
+func (r *Repository) DeleteUser(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin delete user transaction. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM todos WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting todos for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM lists WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting lists for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM access_tokens WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting access tokens for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM password_reset_tokens WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting password reset tokens for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting recovery codes for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_sessions WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting session records for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM subscriptions WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting subscriptions for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_roles WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting roles for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM invoices WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting invoices for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM plan_changes WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting plan changes for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM login_throttles WHERE kind = ? AND key = ?`, models.LoginThrottleUser, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting login throttles for user. %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM users WHERE id = ?`, userID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Error deleting user. %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Could not commit delete user transaction. %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"go-todo/internal/models"
+	"testing"
+	"time"
+)
+
+func TestListAndCountUsersSearch(t *testing.T) {
+	r := newTestRepository(t)
+
+	for _, user := range []models.User{
+		models.NewUser("u1", "Alice", "alice@example.com", "", false, ""),
+		models.NewUser("u2", "Bob", "bob@example.com", "", false, ""),
+		models.NewUser("u3", "Carol", "carol_100%@example.com", "", false, ""),
+	} {
+		if err := r.SaveUser(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"BOB", 1},
+		{"example.com", 3},
+		{"100%", 1},
+		{"_", 1},
+		{"nobody", 0},
+	}
+
+	for _, test := range tests {
+		count, err := r.CountUsers(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		users, err := r.ListUsers(test.query, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != test.want || len(users) != test.want {
+			t.Errorf("query %q: expected %d users, got count %d and %d listed", test.query, test.want, count, len(users))
+		}
+	}
+
+	page, err := r.ListUsers("", 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Email != "carol_100%@example.com" {
+		t.Errorf("expected the second page to hold carol, got %v", page)
+	}
+}
+
+func TestDeleteUserCascades(t *testing.T) {
+	r := newTestRepository(t)
+
+	if err := r.SaveUser(models.NewUser("u1", "Alice", "alice@example.com", "", false, "")); err != nil {
+		t.Fatal(err)
+	}
+	list := models.NewList("u1", "Work")
+	listID, err := r.CreateList(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todo := models.NewTodo("u1", listID, "write report")
+	if _, err := r.CreateTodo(&todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RecordPlanChange("u1", true, models.PlanChangeSourceReconcile); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	invoice := models.Invoice{ID: "in_1", UserID: "u1", CustomerID: "cus_1", Status: "paid", PeriodStart: now, PeriodEnd: now, CreatedAt: now, LastEventAt: now}
+	if err := r.SaveInvoice(&invoice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RecordLoginFailure(models.LoginThrottleUser, "u1", now, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.DeleteUser("u1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// every table keyed by user_id, including ones added later
+	rows, err := r.db.Query(`SELECT m.name FROM sqlite_master m JOIN pragma_table_info(m.name) c WHERE m.type = 'table' AND c.name = 'user_id'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	for _, table := range tables {
+		var count int
+		if err := r.db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE user_id = ?`, "u1").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("expected no %s rows to remain, %d do", table, count)
+		}
+	}
+	if throttle, _ := r.GetLoginThrottle(models.LoginThrottleUser, "u1"); throttle != nil {
+		t.Error("expected the user's login throttle to be deleted")
+	}
+
+	user, err := r.GetUserByID("u1")
+	if err != nil || user != nil {
+		t.Fatalf("expected user to be deleted, got %v (%v)", user, err)
+	}
+	if count, _ := r.CountTodosByUserID("u1"); count != 0 {
+		t.Errorf("expected todos to be deleted, %d remain", count)
+	}
+	if count, _ := r.CountListsByUserID("u1"); count != 0 {
+		t.Errorf("expected lists to be deleted, %d remain", count)
+	}
+}
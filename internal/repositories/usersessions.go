@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"time"
+)
+
+const userSessionColumns = `session_id, user_id, ip, user_agent, created_at, last_seen_at, expires_at`
+
+func scanUserSession(row rowScanner) (*models.UserSession, error) {
+	session := models.UserSession{}
+	err := row.Scan(&session.ID, &session.UserID, &session.IP, &session.UserAgent, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	session.CreatedAt = session.CreatedAt.UTC()
+	session.LastSeenAt = session.LastSeenAt.UTC()
+	session.ExpiresAt = session.ExpiresAt.UTC()
+	return &session, nil
+}
+
+// SaveUserSession records a session, replacing any record left behind by
+// an old session with the same id as the session store reuses ids.
+func (r *Repository) SaveUserSession(session *models.UserSession) error {
+	stmt, err := r.db.Prepare(`INSERT INTO user_sessions(` + userSessionColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET
+			user_id = excluded.user_id,
+			ip = excluded.ip,
+			user_agent = excluded.user_agent,
+			created_at = excluded.created_at,
+			last_seen_at = excluded.last_seen_at,
+			expires_at = excluded.expires_at`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing save user session statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(session.ID, session.UserID, session.IP, session.UserAgent, session.CreatedAt.UTC(), session.LastSeenAt.UTC(), session.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Error executing save user session statement. %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetUserSession(sessionID string) (*models.UserSession, error) {
+	stmt, err := r.db.Prepare(`SELECT ` + userSessionColumns + ` FROM user_sessions WHERE session_id = ?`)
+	if err != nil {
+		return nil, fmt.Errorf("Issue preparing get user session query. %w", err)
+	}
+	defer stmt.Close()
+
+	session, err := scanUserSession(stmt.QueryRow(sessionID))
+	if err != nil {
+		if err.Error() == sqlNoResult {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Error executing get user session query. %w", err)
+	}
+	return session, nil
+}
+
+func (r *Repository) UpdateUserSessionLastSeen(sessionID, ip string, lastSeenAt, expiresAt time.Time) error {
+	stmt, err := r.db.Prepare(`UPDATE user_sessions SET ip = ?, last_seen_at = ?, expires_at = ? WHERE session_id = ?`)
+	if err != nil {
+		return fmt.Errorf("Issue preparing update user session last seen statement. %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(ip, lastSeenAt.UTC(), expiresAt.UTC(), sessionID)
+	if err != nil {
+		return fmt.Errorf("Error executing update user session last seen statement. %w", err)
+	}
+	return nil
+}
+
+// GetActiveUserSessions returns the user's sessions that have not expired
+// at now, most recently seen first.
+func (r *Repository) GetActiveUserSessions(userID string, now time.Time) ([]*models.UserSession, error) {
+	rows, err := r.db.Query(`SELECT `+userSessionColumns+` FROM user_sessions WHERE user_id = ? AND expires_at > ? ORDER BY last_seen_at DESC, created_at DESC`, userID, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("Error querying user sessions. %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*models.UserSession{}
+	for rows.Next() {
+		session, err := scanUserSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Issue scanning user sessions. %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// DeleteUserSession removes the user's record of a session, reporting
+// whether it existed.
+func (r *Repository) DeleteUserSession(userID, sessionID string) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM user_sessions WHERE user_id = ? AND session_id = ?`, userID, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("Error deleting user session. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *Repository) DeleteUserSessions(userID string) error {
+	_, err := r.db.Exec(`DELETE FROM user_sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("Error deleting user sessions. %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteExpiredUserSessions(userID string, now time.Time) error {
+	_, err := r.db.Exec(`DELETE FROM user_sessions WHERE user_id = ? AND expires_at <= ?`, userID, now.UTC())
+	if err != nil {
+		return fmt.Errorf("Error deleting expired user sessions. %w", err)
+	}
+	return nil
+}
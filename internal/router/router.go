@@ -1,7 +1,11 @@
 package router
 
 import (
+	"errors"
+	"go-todo/internal/config"
 	"go-todo/internal/handlers"
+	"go-todo/internal/models"
+	"go-todo/internal/services"
 	"net/http"
 )
 
@@ -31,33 +35,98 @@ func NewRouter(handler *handlers.Handler) *http.ServeMux {
 	app.Get("/subscription/upgrade", handler.UserMustBeLoggedIn(handler.UpgradePage))
 
 	app.Post("/login", handler.Login)
+	app.Get("/login/two-factor", handler.TwoFactorLoginPage)
+	app.Post("/login/two-factor", handler.TwoFactorLogin)
 	app.Get("/logout", handler.Logout)
 
+	app.Get("/forgot-password", handler.ForgotPasswordPage)
+	app.Post("/forgot-password", handler.ForgotPassword)
+	app.Get("/reset-password", handler.ResetPasswordPage)
+	app.Post("/reset-password", handler.ResetPassword)
+
+	app.Get("/verify-email", handler.VerifyEmail)
+	app.Post("/verify-email/resend", handler.UserMustBeLoggedIn(handler.ResendVerificationEmail))
+
+	verifiedForCheckout := handler.UserMustHaveVerifiedEmail(config.RestrictCheckout)
+	verifiedForAccessTokens := handler.UserMustHaveVerifiedEmail(config.RestrictAccessTokens)
+	entitledToAPI := handler.UserMustBeEntitled(models.EntitlementAPIAccess)
+
 	app.Post("/todo/add", handler.AddTodo)
-	app.Post("/todo/update/description", handler.UpdateTodoDescription)
+	app.Get("/todo/search", handler.UserMustBeLoggedIn(handler.SearchTodos))
+	app.Get("/todo/{id}", handler.UserMustBeLoggedIn(handler.GetTodo))
+	app.Get("/todo/edit/description/{id}", handler.UserMustBeLoggedIn(handler.EditTodoDescription))
+	app.Post("/todo/update/description/{id}", handler.UserMustBeLoggedIn(handler.UpdateTodoDescription))
 	app.Post("/todo/update/status/{id}", handler.UpdateTodoStatus)
 	app.Post("/todo/remove/{id}", handler.RemoveTodo)
+	app.Post("/todo/move/{id}", handler.UserMustBeLoggedIn(handler.MoveTodo))
+	app.Get("/todos/active", handler.UserMustBeLoggedIn(handler.ActiveTodosPage))
+	app.Post("/todos/active", handler.UserMustBeLoggedIn(handler.ChooseActiveTodos))
+
+	app.Get("/lists", handler.UserMustBeLoggedIn(handler.Lists))
+	app.Post("/lists", handler.UserMustBeLoggedIn(handler.CreateList))
+	app.Get("/lists/{id}", handler.UserMustBeLoggedIn(handler.ListPage))
+	app.Put("/lists/{id}", handler.UserMustBeLoggedIn(handler.UpdateList))
+	app.Delete("/lists/{id}", handler.UserMustBeLoggedIn(handler.DeleteList))
+
+	app.Get("/settings/tokens", handler.UserMustBeLoggedIn(handler.AccessTokensPage))
+	app.Post("/settings/tokens", handler.UserMustBeLoggedIn(verifiedForAccessTokens(entitledToAPI(handler.CreateAccessToken))))
+	app.Delete("/settings/tokens/{id}", handler.UserMustBeLoggedIn(handler.RevokeAccessToken))
+
+	app.Get("/settings/two-factor", handler.UserMustBeLoggedIn(handler.TwoFactorPage))
+	app.Post("/settings/two-factor", handler.UserMustBeLoggedIn(handler.EnableTwoFactor))
+	app.Delete("/settings/two-factor", handler.UserMustBeLoggedIn(handler.DisableTwoFactor))
+
+	app.Get("/settings/billing", handler.UserMustBeLoggedIn(handler.BillingPage))
+	app.Get("/settings/billing/invoices/{id}", handler.UserMustBeLoggedIn(handler.InvoiceReceipt))
+
+	app.Get("/settings/security", handler.UserMustBeLoggedIn(handler.SecurityPage))
+	app.Delete("/settings/security/sessions/{id}", handler.UserMustBeLoggedIn(handler.DeleteUserSession))
+	app.Post("/settings/security/sign-out-everywhere", handler.UserMustBeLoggedIn(handler.SignOutEverywhere))
 
-	app.Post("/create-checkout-session", handler.CreateCheckoutSession)
+	app.Post("/create-checkout-session", verifiedForCheckout(handler.CreateCheckoutSession))
 	app.Get("/manage-subscription", handler.CreateCustomerPortalSession)
 	app.Post("/webhook", handler.HandleStripeWebhook)
 
 	admin := app.SubRouter("/admin", false)
 
-	admin.Use(handler.UserMustBeAdmin)
+	// middleware runs in the order it is added
 	admin.Use(handler.AddUserToContext)
-	admin.Use(handler.UserMustBeLoggedIn)
 	admin.Use(handler.PathLogger)
+	admin.Use(handler.UserMustBeLoggedIn)
 
-	admin.Get("/dashboard", handler.AdminDashboard)
-	admin.Get("/analytics", handler.AnalyticsDashboard)
+	canViewUsers := handler.UserMustHavePermission(models.PermissionUsersView)
+	canManageUsers := handler.UserMustHavePermission(models.PermissionUsersManage)
+	canViewAnalytics := handler.UserMustHavePermission(models.PermissionAnalyticsView)
+
+	admin.Get("/dashboard", handler.UserMustBeAdmin(handler.AdminDashboard))
+	admin.Get("/analytics", canViewAnalytics(handler.AnalyticsDashboard))
+	admin.Get("/lockouts", canViewUsers(handler.LoginLockoutsPage))
+	admin.Delete("/lockouts/{id}", canManageUsers(handler.UnlockLogin))
 
 	users := admin.SubRouter("/users", true)
 
-	users.Get("", handler.UsersPage)
-	users.Get("/{user_id}", handler.UserProfilePage)
-	users.Put("/{user_id}", handler.UpdateUser)
-	users.Delete("/{user_id}", handler.DeleteUser)
+	users.Get("", canViewUsers(handler.UsersPage))
+	users.Get("/{user_id}", canViewUsers(handler.UserProfilePage))
+	users.Put("/{user_id}", canManageUsers(handler.UpdateUser))
+	users.Delete("/{user_id}", canManageUsers(handler.DeleteUser))
+
+	api := app.SubRouter("/api/v1", false)
+
+	// JSONErrors comes first so every error, including auth, is JSON
+	api.Use(handler.JSONErrors)
+	api.Use(handler.AddUserToContext)
+	api.Use(handler.AddUserFromAccessToken)
+	api.Use(handler.PathLogger)
+	api.Use(handler.UserMustBeLoggedIn)
+	api.Use(entitledToAPI)
+
+	api.Get("/me", handler.APIMe)
+	api.Get("/todos", handler.APIListTodos)
+	api.Post("/todos", handler.APICreateTodo)
+	api.Get("/todos/{id}", handler.APIGetTodo)
+	api.Patch("/todos/{id}", handler.APIUpdateTodo)
+	api.Delete("/todos/{id}", handler.APIDeleteTodo)
+	api.Handle(api.Prefix+"/", handler.APINotFound)
 
 	return r
 }
@@ -71,23 +140,33 @@ func (parent *router) SubRouter(prefix string, carryMiddleware bool) *router {
 	if carryMiddleware {
 		middleware = parent.Middleware()
 	}
-	return &router{prefix, parent.Mux, middleware}
+	return &router{parent.Prefix + prefix, parent.Mux, middleware}
 }
 
 func (s *router) Handle(path string, fn handlers.HandleFunc) {
 
-	// wrap fn in middleware
-	for i := range s.middleware {
+	// wrap fn in middleware, the first middleware added is outermost
+	for i := len(s.middleware) - 1; i >= 0; i-- {
 		fn = s.middleware[i](fn)
 	}
 
 	s.Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
 		if err := fn(w, r); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), statusCode(err))
 		}
 	})
 
 }
+
+// statusCode uses the code of a client error returned by a handler, any
+// other error is an internal server error.
+func statusCode(err error) int {
+	var clientError *services.ClientError
+	if errors.As(err, &clientError) && clientError.Code != 0 {
+		return clientError.Code
+	}
+	return http.StatusInternalServerError
+}
 func (s *router) Get(path string, fn handlers.HandleFunc) {
 	path = s.Prefix + path
 	s.Handle(http.MethodGet+" "+path, fn)
@@ -100,6 +179,10 @@ func (s *router) Put(path string, fn handlers.HandleFunc) {
 	path = s.Prefix + path
 	s.Handle(http.MethodPut+" "+path, fn)
 }
+func (s *router) Patch(path string, fn handlers.HandleFunc) {
+	path = s.Prefix + path
+	s.Handle(http.MethodPatch+" "+path, fn)
+}
 func (s *router) Delete(path string, fn handlers.HandleFunc) {
 	path = s.Prefix + path
 	s.Handle(http.MethodDelete+" "+path, fn)
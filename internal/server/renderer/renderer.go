@@ -2,9 +2,14 @@ package renderer
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"go-todo/internal/models"
+	"html"
 	"html/template"
+	"math"
+	"strings"
+	"time"
 )
 
 type Renderer struct {
@@ -41,15 +46,23 @@ Homepage
 */
 type HomePageProps struct {
 	BasePageProps
-	TodoListProps  TodoListProps
-	LoginFormProps LoginFormProps
+	ListSwitcherProps ListSwitcherProps
+	TodoListProps     TodoListProps
+	LoginFormProps    LoginFormProps
+	// ArchivedTodoCount of the user's todos are archived because they are
+	// over TodoLimit
+	ArchivedTodoCount int
+	TodoLimit         int
 }
 
-func NewHomePageProps(basePageProps BasePageProps, todoListProps TodoListProps, loginFormProps LoginFormProps) HomePageProps {
+func NewHomePageProps(basePageProps BasePageProps, listSwitcherProps ListSwitcherProps, todoListProps TodoListProps, loginFormProps LoginFormProps, archivedTodoCount, todoLimit int) HomePageProps {
 	return HomePageProps{
-		BasePageProps:  basePageProps,
-		TodoListProps:  todoListProps,
-		LoginFormProps: loginFormProps,
+		BasePageProps:     basePageProps,
+		ListSwitcherProps: listSwitcherProps,
+		TodoListProps:     todoListProps,
+		LoginFormProps:    loginFormProps,
+		ArchivedTodoCount: archivedTodoCount,
+		TodoLimit:         todoLimit,
 	}
 }
 func (r *Renderer) HomePage(p HomePageProps) ([]byte, error) {
@@ -60,6 +73,45 @@ func (r *Renderer) HomePage(p HomePageProps) ([]byte, error) {
 	return bytes, nil
 }
 
+/*
+Active todos page
+*/
+type ActiveTodosPageProps struct {
+	BasePageProps
+	Todos     []TodoProps
+	TodoLimit int
+	Errors    []string
+}
+
+// NewActiveTodosPageProps lists every one of the user's todos with the
+// name of the list it is on, so they can choose which stay active.
+func NewActiveTodosPageProps(basePageProps BasePageProps, todos []*models.Todo, lists []*models.List, todoLimit int, errors []string) ActiveTodosPageProps {
+	listNames := map[int]string{}
+	for _, list := range lists {
+		listNames[list.ID] = list.Name
+	}
+
+	props := ActiveTodosPageProps{
+		BasePageProps: basePageProps,
+		Todos:         []TodoProps{},
+		TodoLimit:     todoLimit,
+		Errors:        errors,
+	}
+	for _, todo := range todos {
+		todoProps := NewTodoProps(todo, nil, basePageProps.User.Location())
+		todoProps.ListName = listNames[todo.ListID]
+		props.Todos = append(props.Todos, todoProps)
+	}
+	return props
+}
+func (r *Renderer) ActiveTodosPage(p ActiveTodosPageProps) ([]byte, error) {
+	bytes, err := r.render("active-todos", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render active todos page. %w", err)
+	}
+	return bytes, nil
+}
+
 /*
 SignupPage
 */
@@ -82,16 +134,169 @@ func (r *Renderer) Signup(p SignupPageProps) ([]byte, error) {
 	return bytes, nil
 }
 
+/*
+Password reset
+*/
+type ForgotPasswordPageProps struct {
+	BasePageProps
+	Sent        bool
+	EmailErrors []string
+}
+
+func NewForgotPasswordPageProps(basePageProps BasePageProps, sent bool, emailErrors []string) ForgotPasswordPageProps {
+	return ForgotPasswordPageProps{
+		BasePageProps: basePageProps,
+		Sent:          sent,
+		EmailErrors:   emailErrors,
+	}
+}
+func (r *Renderer) ForgotPassword(p ForgotPasswordPageProps) ([]byte, error) {
+	bytes, err := r.render("forgot-password", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render forgot password page. %w", err)
+	}
+	return bytes, nil
+}
+
+type ResetPasswordPageProps struct {
+	BasePageProps
+	Token          string
+	Done           bool
+	TokenErrors    []string
+	PasswordErrors []string
+}
+
+func NewResetPasswordPageProps(basePageProps BasePageProps, token string, done bool, clientErrors *models.ResetPasswordClientErrors) ResetPasswordPageProps {
+	p := ResetPasswordPageProps{
+		BasePageProps: basePageProps,
+		Token:         token,
+		Done:          done,
+	}
+	if clientErrors != nil {
+		p.TokenErrors = clientErrors.TokenErrors
+		p.PasswordErrors = clientErrors.PasswordErrors
+	}
+	return p
+}
+func (r *Renderer) ResetPassword(p ResetPasswordPageProps) ([]byte, error) {
+	bytes, err := r.render("reset-password", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render reset password page. %w", err)
+	}
+	return bytes, nil
+}
+
+/*
+Email verification
+*/
+type VerifyEmailPageProps struct {
+	BasePageProps
+	Errors []string
+}
+
+func NewVerifyEmailPageProps(basePageProps BasePageProps, errors []string) VerifyEmailPageProps {
+	return VerifyEmailPageProps{
+		BasePageProps: basePageProps,
+		Errors:        errors,
+	}
+}
+func (r *Renderer) VerifyEmail(p VerifyEmailPageProps) ([]byte, error) {
+	bytes, err := r.render("verify-email", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render verify email page. %w", err)
+	}
+	return bytes, nil
+}
+
+type VerifyEmailStatusProps struct {
+	Message string
+	IsError bool
+}
+
+func NewVerifyEmailStatusProps(message string, isError bool) VerifyEmailStatusProps {
+	return VerifyEmailStatusProps{
+		Message: message,
+		IsError: isError,
+	}
+}
+func (r *Renderer) VerifyEmailStatus(p VerifyEmailStatusProps) ([]byte, error) {
+	bytes, err := r.render("verify-email-status", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render verify email status. %w", err)
+	}
+	return bytes, nil
+}
+
+/*
+Two factor
+*/
+type TwoFactorLoginPageProps struct {
+	BasePageProps
+	CodeErrors []string
+}
+
+func NewTwoFactorLoginPageProps(basePageProps BasePageProps, codeErrors []string) TwoFactorLoginPageProps {
+	return TwoFactorLoginPageProps{
+		BasePageProps: basePageProps,
+		CodeErrors:    codeErrors,
+	}
+}
+func (r *Renderer) TwoFactorLogin(p TwoFactorLoginPageProps) ([]byte, error) {
+	bytes, err := r.render("two-factor-login", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render two factor login page. %w", err)
+	}
+	return bytes, nil
+}
+
+type TwoFactorPageProps struct {
+	BasePageProps
+	Secret              string
+	QRCode              template.URL // data URI of the QR code PNG
+	RecoveryCodes       []string     // only set right after enabling
+	UnusedRecoveryCodes int
+	CodeErrors          []string
+}
+
+func NewTwoFactorPageProps(basePageProps BasePageProps, enrollment *models.TwoFactorEnrollment, recoveryCodes []string, unusedRecoveryCodes int, codeErrors []string) TwoFactorPageProps {
+	p := TwoFactorPageProps{
+		BasePageProps:       basePageProps,
+		RecoveryCodes:       recoveryCodes,
+		UnusedRecoveryCodes: unusedRecoveryCodes,
+		CodeErrors:          codeErrors,
+	}
+	if enrollment != nil {
+		p.Secret = enrollment.Secret
+		p.QRCode = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(enrollment.QRCode))
+	}
+	return p
+}
+func (r *Renderer) TwoFactor(p TwoFactorPageProps) ([]byte, error) {
+	bytes, err := r.render("two-factor", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render two factor page. %w", err)
+	}
+	return bytes, nil
+}
+
 /*
 UpgradePage
 */
 type UpgradePageProps struct {
 	BasePageProps
+	Plans           models.PlanCatalogue
+	CurrentPlan     *models.Plan
+	TrialEligible   bool
+	MustVerifyEmail bool
 }
 
-func NewUpgradePageProps(basePageProps BasePageProps) UpgradePageProps {
+func NewUpgradePageProps(basePageProps BasePageProps, plans models.PlanCatalogue, currentPlan *models.Plan, trialEligible, mustVerifyEmail bool) UpgradePageProps {
 	return UpgradePageProps{
-		BasePageProps: basePageProps,
+		BasePageProps:   basePageProps,
+		Plans:           plans,
+		CurrentPlan:     currentPlan,
+		TrialEligible:   trialEligible,
+		MustVerifyEmail: mustVerifyEmail,
 	}
 }
 func (r *Renderer) Upgrade(p UpgradePageProps) ([]byte, error) {
@@ -102,16 +307,116 @@ func (r *Renderer) Upgrade(p UpgradePageProps) ([]byte, error) {
 	return bytes, nil
 }
 
+/*
+Billing page
+*/
+type InvoiceProps struct {
+	*models.Invoice
+	PlanName    string
+	AmountLabel string
+}
+
+// NewInvoiceProps names the plan the invoice is for from plans, falling
+// back to its price id for plans no longer in the catalogue.
+func NewInvoiceProps(invoice *models.Invoice, plans models.PlanCatalogue) InvoiceProps {
+	props := InvoiceProps{
+		Invoice:     invoice,
+		PlanName:    invoice.PriceID,
+		AmountLabel: models.FormatAmount(invoice.Amount, invoice.Currency),
+	}
+	if plan := plans.ByPriceID(invoice.PriceID); plan != nil {
+		props.PlanName = plan.Name
+	}
+	return props
+}
+
+type BillingPageProps struct {
+	BasePageProps
+	Plan         *models.Plan
+	Subscription *models.Subscription
+	RenewalLabel string
+	Invoices     []InvoiceProps
+}
+
+func NewBillingPageProps(basePageProps BasePageProps, plan *models.Plan, subscription *models.Subscription, invoices []*models.Invoice, plans models.PlanCatalogue) BillingPageProps {
+	props := BillingPageProps{
+		BasePageProps: basePageProps,
+		Plan:          plan,
+		Subscription:  subscription,
+		RenewalLabel:  renewalLabel(subscription),
+		Invoices:      []InvoiceProps{},
+	}
+	for _, invoice := range invoices {
+		props.Invoices = append(props.Invoices, NewInvoiceProps(invoice, plans))
+	}
+	return props
+}
+func (r *Renderer) BillingPage(p BillingPageProps) ([]byte, error) {
+	bytes, err := r.render("billing", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render billing page. %w", err)
+	}
+	return bytes, nil
+}
+
+// renewalLabel says when, or whether, the subscription renews.
+func renewalLabel(subscription *models.Subscription) string {
+	if subscription == nil {
+		return ""
+	}
+	periodEnd := subscription.CurrentPeriodEnd.Format("January 2, 2006")
+
+	switch {
+	case subscription.Status == models.SubscriptionCanceled:
+		return "Your subscription has ended"
+	case subscription.Status == models.SubscriptionPaused:
+		return "Your subscription is paused"
+	case subscription.CancelAtPeriodEnd:
+		return "Ends on " + periodEnd
+	case subscription.Status == models.SubscriptionPastDue:
+		return "Your last payment failed, it renews once it is paid"
+	case subscription.Status == models.SubscriptionTrialing:
+		return "Your trial ends on " + periodEnd + ", then it renews"
+	default:
+		return "Renews on " + periodEnd
+	}
+}
+
+/*
+Receipt page
+*/
+type ReceiptPageProps struct {
+	InvoiceProps
+	User *models.User
+}
+
+func NewReceiptPageProps(user *models.User, invoiceProps InvoiceProps) ReceiptPageProps {
+	return ReceiptPageProps{
+		InvoiceProps: invoiceProps,
+		User:         user,
+	}
+}
+func (r *Renderer) ReceiptPage(p ReceiptPageProps) ([]byte, error) {
+	bytes, err := r.render("receipt", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render receipt page. %w", err)
+	}
+	return bytes, nil
+}
+
 /*
 Success Page
 */
 type SuccessPageProps struct {
 	BasePageProps
+	// IsSubscribed is false until the subscription's webhook arrives
+	IsSubscribed bool
 }
 
-func NewSuccessPageProps(basePageProps BasePageProps) SuccessPageProps {
+func NewSuccessPageProps(basePageProps BasePageProps, isSubscribed bool) SuccessPageProps {
 	return SuccessPageProps{
 		BasePageProps: basePageProps,
+		IsSubscribed:  isSubscribed,
 	}
 }
 func (r *Renderer) Success(p SuccessPageProps) ([]byte, error) {
@@ -144,10 +449,35 @@ func (r *Renderer) Cancel(p CancelPageProps) ([]byte, error) {
 
 // partials
 
-type TodoProps *models.Todo
+type TodoProps struct {
+	*models.Todo
+	DueLabel    string
+	IsOverdue   bool
+	IsDueToday  bool
+	MoveTargets []*models.List
+	ListName    string
+	Snippet     template.HTML
+}
 
-func NewTodoProps(todo *models.Todo) TodoProps {
-	return todo
+// NewTodoProps renders the todo's due date in loc, the viewing user's time
+// zone. lists are the user's lists the todo can be moved to.
+func NewTodoProps(todo *models.Todo, lists []*models.List, loc *time.Location) TodoProps {
+	now := time.Now().In(loc)
+	props := TodoProps{
+		Todo:        todo,
+		IsOverdue:   todo.IsOverdue(now),
+		IsDueToday:  todo.IsDueOn(now),
+		MoveTargets: []*models.List{},
+	}
+	for _, list := range lists {
+		if list.ID != todo.ListID {
+			props.MoveTargets = append(props.MoveTargets, list)
+		}
+	}
+	if todo.DueAt != nil {
+		props.DueLabel = todo.DueAt.In(loc).Format("Mon 2 Jan 2006, 15:04")
+	}
+	return props
 }
 func (r *Renderer) Todo(p TodoProps) ([]byte, error) {
 	bytes, err := r.render("todo", p)
@@ -157,18 +487,112 @@ func (r *Renderer) Todo(p TodoProps) ([]byte, error) {
 	return bytes, nil
 }
 
+type SearchResultsProps struct {
+	Query string
+	Todos []TodoProps
+}
+
+// NewSearchResultsProps renders each matching todo with its highlighted
+// snippet and the name of the list it is on.
+func NewSearchResultsProps(query string, results []*models.SearchResult, lists []*models.List, loc *time.Location) SearchResultsProps {
+	listNames := map[int]string{}
+	for _, list := range lists {
+		listNames[list.ID] = list.Name
+	}
+
+	props := SearchResultsProps{
+		Query: query,
+		Todos: []TodoProps{},
+	}
+	for _, result := range results {
+		todoProps := NewTodoProps(result.Todo, lists, loc)
+		todoProps.ListName = listNames[result.Todo.ListID]
+		todoProps.Snippet = highlightSnippet(result.Snippet)
+		props.Todos = append(props.Todos, todoProps)
+	}
+	return props
+}
+func (r *Renderer) SearchResults(p SearchResultsProps) ([]byte, error) {
+	bytes, err := r.render("search-results", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render search results element. %w", err)
+	}
+	return bytes, nil
+}
+
+// highlightSnippet escapes a search snippet and wraps the matched terms in
+// <mark>. Descriptions are stored escaped so they are unescaped first to
+// avoid escaping them twice.
+func highlightSnippet(snippet string) template.HTML {
+	escaped := html.EscapeString(html.UnescapeString(snippet))
+	escaped = strings.ReplaceAll(escaped, models.SnippetMatchStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, models.SnippetMatchEnd, "</mark>")
+	return template.HTML(escaped)
+}
+
+// Todos renders just the items of a list, used to reset the list after a
+// search is cleared.
+func (r *Renderer) Todos(p []TodoProps) ([]byte, error) {
+	bytes, err := r.render("todos", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render todos element. %w", err)
+	}
+	return bytes, nil
+}
+
+type TodoEditProps struct {
+	*models.Todo
+	Value             string
+	DescriptionErrors []string
+}
+
+// NewTodoEditProps prefills the edit input with value, or with the todo's
+// current description when value is empty.
+func NewTodoEditProps(todo *models.Todo, value string, descriptionErrors []string) TodoEditProps {
+	if value == "" && len(descriptionErrors) == 0 {
+		// descriptions are stored escaped, the input should show the original text
+		value = html.UnescapeString(todo.Description)
+	}
+	return TodoEditProps{
+		Todo:              todo,
+		Value:             value,
+		DescriptionErrors: descriptionErrors,
+	}
+}
+func (r *Renderer) TodoEdit(p TodoEditProps) ([]byte, error) {
+	bytes, err := r.render("todo-edit", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render todo edit element. %w", err)
+	}
+	return bytes, nil
+}
+
 type TodoListProps struct {
-	Todos            []*models.Todo
+	List             *models.List
+	Todos            []TodoProps
 	CanCreateNewTodo bool
 	ClientErrors     *models.CreateTodoClientErrors
+	OverdueCount     int
+	DueTodayCount    int
 }
 
-func NewTodoListProps(todoList []*models.Todo, canCreateNewTodo bool, clientErrors *models.CreateTodoClientErrors) TodoListProps {
-	return TodoListProps{
-		Todos:            todoList,
+func NewTodoListProps(list *models.List, lists []*models.List, todoList []*models.Todo, loc *time.Location, canCreateNewTodo bool, clientErrors *models.CreateTodoClientErrors) TodoListProps {
+	props := TodoListProps{
+		List:             list,
+		Todos:            []TodoProps{},
 		CanCreateNewTodo: canCreateNewTodo,
 		ClientErrors:     clientErrors,
 	}
+	for _, todo := range todoList {
+		todoProps := NewTodoProps(todo, lists, loc)
+		if todoProps.IsOverdue {
+			props.OverdueCount++
+		} else if todoProps.IsDueToday {
+			props.DueTodayCount++
+		}
+		props.Todos = append(props.Todos, todoProps)
+	}
+	return props
 }
 func (r *Renderer) TodoList(p TodoListProps) ([]byte, error) {
 	bytes, err := r.render("todo-list", p)
@@ -178,6 +602,29 @@ func (r *Renderer) TodoList(p TodoListProps) ([]byte, error) {
 	return bytes, nil
 }
 
+type ListSwitcherProps struct {
+	Lists            []*models.List
+	Current          *models.List
+	CanCreateNewList bool
+	Errors           []string
+}
+
+func NewListSwitcherProps(lists []*models.List, current *models.List, canCreateNewList bool, errors []string) ListSwitcherProps {
+	return ListSwitcherProps{
+		Lists:            lists,
+		Current:          current,
+		CanCreateNewList: canCreateNewList,
+		Errors:           errors,
+	}
+}
+func (r *Renderer) ListSwitcher(p ListSwitcherProps) ([]byte, error) {
+	bytes, err := r.render("list-switcher", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render list switcher element. %w", err)
+	}
+	return bytes, nil
+}
+
 type LoginFormProps struct {
 	EmailErrors    []string
 	PasswordErrors []string
@@ -210,3 +657,451 @@ func NewSignupFormProps(usernameErrors, emailErrors, passwordErrors []string) Si
 		PasswordErrors: passwordErrors,
 	}
 }
+
+/*
+Admin users
+*/
+type AdminUsersTableProps struct {
+	Users     []*models.User
+	Query     string
+	Total     int
+	Page      int
+	PageCount int
+	PrevPage  int // 0 when on the first page
+	NextPage  int // 0 when on the last page
+}
+
+func NewAdminUsersTableProps(users []*models.User, query string, total, page, pageSize int) AdminUsersTableProps {
+	pageCount := (total + pageSize - 1) / pageSize
+	if pageCount < 1 {
+		pageCount = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	p := AdminUsersTableProps{
+		Users:     users,
+		Query:     query,
+		Total:     total,
+		Page:      page,
+		PageCount: pageCount,
+	}
+	if page > 1 {
+		p.PrevPage = page - 1
+	}
+	if page < pageCount {
+		p.NextPage = page + 1
+	}
+	return p
+}
+func (r *Renderer) AdminUsersTable(p AdminUsersTableProps) ([]byte, error) {
+	bytes, err := r.render("admin-users-table", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render admin users table element. %w", err)
+	}
+	return bytes, nil
+}
+
+type AdminUsersPageProps struct {
+	BasePageProps
+	AdminUsersTableProps
+}
+
+func NewAdminUsersPageProps(basePageProps BasePageProps, tableProps AdminUsersTableProps) AdminUsersPageProps {
+	return AdminUsersPageProps{
+		BasePageProps:        basePageProps,
+		AdminUsersTableProps: tableProps,
+	}
+}
+func (r *Renderer) AdminUsersPage(p AdminUsersPageProps) ([]byte, error) {
+	bytes, err := r.render("admin-users", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render admin users page. %w", err)
+	}
+	return bytes, nil
+}
+
+type AdminUserFormProps struct {
+	User         *models.User
+	CanManage    bool
+	Saved        bool
+	ClientErrors *models.UpdateUserClientErrors
+}
+
+func NewAdminUserFormProps(user *models.User, canManage, saved bool, clientErrors *models.UpdateUserClientErrors) AdminUserFormProps {
+	if clientErrors == nil {
+		clientErrors = &models.UpdateUserClientErrors{}
+	}
+	return AdminUserFormProps{
+		User:         user,
+		CanManage:    canManage,
+		Saved:        saved,
+		ClientErrors: clientErrors,
+	}
+}
+func (r *Renderer) AdminUserForm(p AdminUserFormProps) ([]byte, error) {
+	bytes, err := r.render("admin-user-form", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render admin user form element. %w", err)
+	}
+	return bytes, nil
+}
+
+type AdminUserPageProps struct {
+	BasePageProps
+	Stats        *models.UserStats
+	Subscription *models.Subscription
+	FormProps    AdminUserFormProps
+}
+
+func NewAdminUserPageProps(basePageProps BasePageProps, stats *models.UserStats, subscription *models.Subscription, formProps AdminUserFormProps) AdminUserPageProps {
+	return AdminUserPageProps{
+		BasePageProps: basePageProps,
+		Stats:         stats,
+		Subscription:  subscription,
+		FormProps:     formProps,
+	}
+}
+func (r *Renderer) AdminUserPage(p AdminUserPageProps) ([]byte, error) {
+	bytes, err := r.render("admin-user", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render admin user page. %w", err)
+	}
+	return bytes, nil
+}
+
+/*
+Admin dashboards
+*/
+// bar chart size in svg user units, the svg scales to its container
+const (
+	chartWidth  = 600
+	chartHeight = 150
+	chartBarGap = 2
+)
+
+type ChartBar struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+	Label  string
+}
+
+type BarChartProps struct {
+	Title    string
+	Total    int
+	Max      int
+	Width    int
+	Height   int
+	FirstDay string
+	LastDay  string
+	Bars     []ChartBar
+}
+
+// two decimal places is plenty for a chart and keeps the markup small
+func roundCoordinate(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// NewBarChartProps lays out one bar per day, scaled so the busiest day
+// fills the chart's height.
+func NewBarChartProps(title string, counts []models.DailyCount) BarChartProps {
+	p := BarChartProps{
+		Title:  title,
+		Total:  models.Total(counts),
+		Width:  chartWidth,
+		Height: chartHeight,
+		Bars:   []ChartBar{},
+	}
+	if len(counts) == 0 {
+		return p
+	}
+
+	for _, count := range counts {
+		if count.Count > p.Max {
+			p.Max = count.Count
+		}
+	}
+
+	p.FirstDay = counts[0].Day.Format("Jan 2")
+	p.LastDay = counts[len(counts)-1].Day.Format("Jan 2")
+
+	slot := float64(chartWidth) / float64(len(counts))
+	for i, count := range counts {
+		height := 0.0
+		if p.Max > 0 {
+			height = float64(count.Count) / float64(p.Max) * chartHeight
+		}
+		p.Bars = append(p.Bars, ChartBar{
+			X:      roundCoordinate(float64(i)*slot + chartBarGap/2),
+			Y:      roundCoordinate(chartHeight - height),
+			Width:  roundCoordinate(slot - chartBarGap),
+			Height: roundCoordinate(height),
+			Label:  fmt.Sprintf("%s: %d", count.Day.Format("Mon Jan 2"), count.Count),
+		})
+	}
+	return p
+}
+
+type AdminDashboardProps struct {
+	BasePageProps
+	Analytics        *models.Analytics
+	SignupsChart     BarChartProps
+	Conversions      int
+	Cancellations    int
+	CanViewUsers     bool
+	CanViewAnalytics bool
+}
+
+func NewAdminDashboardProps(basePageProps BasePageProps, analytics *models.Analytics) AdminDashboardProps {
+	return AdminDashboardProps{
+		BasePageProps:    basePageProps,
+		Analytics:        analytics,
+		SignupsChart:     NewBarChartProps("Signups", analytics.Signups),
+		Conversions:      models.Total(analytics.Conversions),
+		Cancellations:    models.Total(analytics.Cancellations),
+		CanViewUsers:     basePageProps.User.HasPermission(models.PermissionUsersView),
+		CanViewAnalytics: basePageProps.User.HasPermission(models.PermissionAnalyticsView),
+	}
+}
+func (r *Renderer) AdminDashboard(p AdminDashboardProps) ([]byte, error) {
+	bytes, err := r.render("admin-dashboard", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render admin dashboard page. %w", err)
+	}
+	return bytes, nil
+}
+
+type AnalyticsPageProps struct {
+	BasePageProps
+	Analytics *models.Analytics
+	Charts    []BarChartProps
+}
+
+func NewAnalyticsPageProps(basePageProps BasePageProps, analytics *models.Analytics) AnalyticsPageProps {
+	return AnalyticsPageProps{
+		BasePageProps: basePageProps,
+		Analytics:     analytics,
+		Charts: []BarChartProps{
+			NewBarChartProps("Signups", analytics.Signups),
+			NewBarChartProps("Active users", analytics.ActiveUsers),
+			NewBarChartProps("Todos created", analytics.TodosCreated),
+			NewBarChartProps("Todos completed", analytics.TodosCompleted),
+			NewBarChartProps("Conversions to paid", analytics.Conversions),
+			NewBarChartProps("Cancellations", analytics.Cancellations),
+		},
+	}
+}
+func (r *Renderer) AnalyticsPage(p AnalyticsPageProps) ([]byte, error) {
+	bytes, err := r.render("analytics", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render analytics page. %w", err)
+	}
+	return bytes, nil
+}
+
+/*
+Access tokens
+*/
+type AccessTokenProps struct {
+	*models.AccessToken
+	CreatedLabel  string
+	LastUsedLabel string
+}
+
+type AccessTokensProps struct {
+	Tokens       []AccessTokenProps
+	NewToken     *models.AccessToken
+	ClientErrors *models.CreateAccessTokenClientErrors
+}
+
+func NewAccessTokensProps(tokens []*models.AccessToken, newToken *models.AccessToken, loc *time.Location, clientErrors *models.CreateAccessTokenClientErrors) AccessTokensProps {
+	if clientErrors == nil {
+		clientErrors = &models.CreateAccessTokenClientErrors{}
+	}
+
+	p := AccessTokensProps{
+		Tokens:       []AccessTokenProps{},
+		NewToken:     newToken,
+		ClientErrors: clientErrors,
+	}
+	for _, token := range tokens {
+		tokenProps := AccessTokenProps{
+			AccessToken:   token,
+			CreatedLabel:  token.CreatedAt.In(loc).Format("Jan 2, 2006"),
+			LastUsedLabel: "Never used",
+		}
+		if token.LastUsedAt != nil {
+			tokenProps.LastUsedLabel = token.LastUsedAt.In(loc).Format("Jan 2, 2006 15:04")
+		}
+		p.Tokens = append(p.Tokens, tokenProps)
+	}
+	return p
+}
+func (r *Renderer) AccessTokens(p AccessTokensProps) ([]byte, error) {
+	bytes, err := r.render("access-tokens", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render access tokens element. %w", err)
+	}
+	return bytes, nil
+}
+
+type AccessTokensPageProps struct {
+	BasePageProps
+	AccessTokensProps
+}
+
+func NewAccessTokensPageProps(basePageProps BasePageProps, accessTokensProps AccessTokensProps) AccessTokensPageProps {
+	return AccessTokensPageProps{
+		BasePageProps:     basePageProps,
+		AccessTokensProps: accessTokensProps,
+	}
+}
+func (r *Renderer) AccessTokensPage(p AccessTokensPageProps) ([]byte, error) {
+	bytes, err := r.render("access-tokens-page", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render access tokens page. %w", err)
+	}
+	return bytes, nil
+}
+
+/*
+Security
+*/
+type UserSessionProps struct {
+	*models.UserSession
+	Device        string
+	CreatedLabel  string
+	LastSeenLabel string
+	IsCurrent     bool
+}
+
+type UserSessionsProps struct {
+	Sessions []UserSessionProps
+}
+
+func NewUserSessionsProps(sessions []*models.UserSession, currentSessionID string, loc *time.Location) UserSessionsProps {
+	p := UserSessionsProps{
+		Sessions: []UserSessionProps{},
+	}
+	for _, session := range sessions {
+		p.Sessions = append(p.Sessions, UserSessionProps{
+			UserSession:   session,
+			Device:        describeUserAgent(session.UserAgent),
+			CreatedLabel:  session.CreatedAt.In(loc).Format("Jan 2, 2006 15:04"),
+			LastSeenLabel: session.LastSeenAt.In(loc).Format("Jan 2, 2006 15:04"),
+			IsCurrent:     session.ID == currentSessionID,
+		})
+	}
+	return p
+}
+func (r *Renderer) UserSessions(p UserSessionsProps) ([]byte, error) {
+	bytes, err := r.render("user-sessions", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render user sessions element. %w", err)
+	}
+	return bytes, nil
+}
+
+// describeUserAgent names the browser and operating system in a user
+// agent well enough for users to recognise their devices, e.g. "Firefox
+// on Linux".
+func describeUserAgent(userAgent string) string {
+	browser := ""
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+
+	system := ""
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		system = "Android"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		system = "iOS"
+	case strings.Contains(userAgent, "Windows"):
+		system = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		system = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		system = "Linux"
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	case userAgent != "":
+		return userAgent
+	}
+	return "Unknown device"
+}
+
+type SecurityPageProps struct {
+	BasePageProps
+	UserSessionsProps
+}
+
+func NewSecurityPageProps(basePageProps BasePageProps, userSessionsProps UserSessionsProps) SecurityPageProps {
+	return SecurityPageProps{
+		BasePageProps:     basePageProps,
+		UserSessionsProps: userSessionsProps,
+	}
+}
+func (r *Renderer) SecurityPage(p SecurityPageProps) ([]byte, error) {
+	bytes, err := r.render("security-page", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render security page. %w", err)
+	}
+	return bytes, nil
+}
+
+/*
+Login lockouts
+*/
+type LoginLockoutProps struct {
+	*models.LoginThrottle
+	LockedUntilLabel string
+}
+
+type LoginLockoutsPageProps struct {
+	BasePageProps
+	Lockouts  []LoginLockoutProps
+	CanManage bool
+}
+
+func NewLoginLockoutsPageProps(basePageProps BasePageProps, lockouts []*models.LoginThrottle) LoginLockoutsPageProps {
+	loc := basePageProps.User.Location()
+	p := LoginLockoutsPageProps{
+		BasePageProps: basePageProps,
+		Lockouts:      []LoginLockoutProps{},
+		CanManage:     basePageProps.User.HasPermission(models.PermissionUsersManage),
+	}
+	for _, lockout := range lockouts {
+		p.Lockouts = append(p.Lockouts, LoginLockoutProps{
+			LoginThrottle:    lockout,
+			LockedUntilLabel: lockout.LockedUntil.In(loc).Format("Jan 2, 2006 15:04"),
+		})
+	}
+	return p
+}
+func (r *Renderer) LoginLockoutsPage(p LoginLockoutsPageProps) ([]byte, error) {
+	bytes, err := r.render("login-lockouts", p)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not render login lockouts page. %w", err)
+	}
+	return bytes, nil
+}
@@ -0,0 +1,133 @@
+package sessionstore
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// cookie sessions carry their id and expiry in their values under these
+// keys, as there is nowhere else to keep them
+const (
+	cookieIDKey        = "_session_id"
+	cookieExpiresOnKey = "_expires_on"
+)
+
+// cookieStore keeps sessions signed and encrypted in the cookie itself.
+// Nothing is kept on the server, so it can only end the session making the
+// request, ending others is left to whoever records them.
+type cookieStore struct {
+	codecs  []securecookie.Codec
+	options sessions.Options
+}
+
+func newCookieStore(secretKey []byte, options sessions.Options) *cookieStore {
+	codecs := securecookie.CodecsFromPairs(deriveKey(secretKey, "hash"), deriveKey(secretKey, "block"))
+	for _, codec := range codecs {
+		// the expiry is kept in the values, not checked by the cookie
+		if secureCookie, ok := codec.(*securecookie.SecureCookie); ok {
+			secureCookie.MaxAge(0)
+		}
+	}
+	return &cookieStore{
+		codecs:  codecs,
+		options: options,
+	}
+}
+
+func (s *cookieStore) Options() sessions.Options {
+	return s.options
+}
+
+func (s *cookieStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *cookieStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	values, ok := decodeValues(name, cookie.Value, s.codecs)
+	if !ok {
+		return session, nil
+	}
+
+	id, _ := values[cookieIDKey].(string)
+	expiresOn, _ := values[cookieExpiresOnKey].(int64)
+	now := time.Now()
+	if id == "" || !time.Unix(expiresOn, 0).After(now) {
+		return session, nil
+	}
+	delete(values, cookieIDKey)
+	delete(values, cookieExpiresOnKey)
+
+	session.ID = id
+	session.Values = values
+	session.Values[ExpiresOnKey] = time.Unix(expiresOn, 0)
+	session.Options = sessionOptions(s.options, time.Unix(expiresOn, 0), now)
+	session.IsNew = false
+	return session, nil
+}
+
+func (s *cookieStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+
+	expiresOn := sessionExpiry(session, time.Now())
+
+	values := make(map[interface{}]interface{}, len(session.Values)+2)
+	for k, v := range session.Values {
+		values[k] = v
+	}
+	delete(values, ExpiresOnKey)
+	values[cookieIDKey] = session.ID
+	values[cookieExpiresOnKey] = expiresOn.Unix()
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), values, s.codecs...)
+	if err != nil {
+		return err
+	}
+	session.Values[ExpiresOnKey] = expiresOn
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+func (s *cookieStore) Delete(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	expireCookie(w, session)
+	for k := range session.Values {
+		delete(session.Values, k)
+	}
+	return nil
+}
+
+// DeleteSession does nothing, the session only exists in its cookie.
+func (s *cookieStore) DeleteSession(sessionID string) error {
+	return nil
+}
+
+// DeleteUserSessions does nothing, the sessions only exist in their
+// cookies.
+func (s *cookieStore) DeleteUserSessions(sessionName, userID string) (int, error) {
+	return 0, nil
+}
+
+// DeleteExpired does nothing, expired cookies are refused when they are
+// used.
+func (s *cookieStore) DeleteExpired() (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,69 @@
+package sessionstore
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryRecords keeps sessions in memory, they are lost when the server
+// stops.
+type memoryRecords struct {
+	mu       sync.Mutex
+	sessions map[string]record
+}
+
+func newMemoryRecords() *memoryRecords {
+	return &memoryRecords{sessions: map[string]record{}}
+}
+
+func (m *memoryRecords) get(id string) (*record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+func (m *memoryRecords) save(id string, rec record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[id] = rec
+	return nil
+}
+
+func (m *memoryRecords) delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *memoryRecords) all() (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	all := map[string]string{}
+	for id, rec := range m.sessions {
+		all[id] = rec.data
+	}
+	return all, nil
+}
+
+func (m *memoryRecords) deleteExpired(now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for id, rec := range m.sessions {
+		if !rec.expiresOn.After(now) {
+			delete(m.sessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,180 @@
+package sessionstore
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// record is a session as kept by a server side backend, data being its
+// encoded values.
+type record struct {
+	data      string
+	createdOn time.Time
+	expiresOn time.Time
+}
+
+// records is where a serverStore keeps its sessions.
+type records interface {
+	// get returns nil when there is no session with id.
+	get(id string) (*record, error)
+	save(id string, rec record) error
+	delete(id string) error
+	// all returns every session's data by id.
+	all() (map[string]string, error)
+	deleteExpired(now time.Time) (int, error)
+}
+
+// serverStore keeps sessions on the server, the cookie only holds the
+// session's signed id.
+type serverStore struct {
+	records records
+	codecs  []securecookie.Codec
+	options sessions.Options
+}
+
+func newServerStore(records records, secretKey []byte, options sessions.Options) *serverStore {
+	codecs := securecookie.CodecsFromPairs(secretKey)
+	for _, codec := range codecs {
+		// the records know when sessions expire, not the cookie
+		if secureCookie, ok := codec.(*securecookie.SecureCookie); ok {
+			secureCookie.MaxAge(0)
+		}
+	}
+	return &serverStore{
+		records: records,
+		codecs:  codecs,
+		options: options,
+	}
+}
+
+func (s *serverStore) Options() sessions.Options {
+	return s.options
+}
+
+func (s *serverStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the request's session, or a new one when it has none or its
+// session has expired or been deleted.
+func (s *serverStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.codecs...); err != nil {
+		return session, nil
+	}
+
+	rec, err := s.records.get(id)
+	if err != nil {
+		return session, err
+	}
+
+	now := time.Now()
+	if rec == nil || !rec.expiresOn.After(now) {
+		return session, nil
+	}
+
+	values, ok := decodeValues(name, rec.data, s.codecs)
+	if !ok {
+		return session, nil
+	}
+
+	session.ID = id
+	session.Values = values
+	session.Values[ExpiresOnKey] = rec.expiresOn
+	session.Options = sessionOptions(s.options, rec.expiresOn, now)
+	session.IsNew = false
+	return session, nil
+}
+
+func (s *serverStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	now := time.Now()
+	rec := record{createdOn: now, expiresOn: sessionExpiry(session, now)}
+
+	if session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	} else if existing, err := s.records.get(session.ID); err != nil {
+		return err
+	} else if existing != nil {
+		rec.createdOn = existing.createdOn
+	}
+
+	delete(session.Values, ExpiresOnKey)
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.codecs...)
+	session.Values[ExpiresOnKey] = rec.expiresOn
+	if err != nil {
+		return err
+	}
+	rec.data = data
+
+	err = s.records.save(session.ID, rec)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+func (s *serverStore) Delete(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	expireCookie(w, session)
+	for k := range session.Values {
+		delete(session.Values, k)
+	}
+
+	if session.ID == "" {
+		return nil
+	}
+	return s.records.delete(session.ID)
+}
+
+func (s *serverStore) DeleteSession(sessionID string) error {
+	return s.records.delete(sessionID)
+}
+
+// DeleteUserSessions decodes every session to find the user's, as their
+// values are only stored encoded.
+func (s *serverStore) DeleteUserSessions(sessionName, userID string) (int, error) {
+	all, err := s.records.all()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for id, data := range all {
+		values, ok := decodeValues(sessionName, data, s.codecs)
+		// handlers keep the logged in user's id under "user"
+		if !ok || values["user"] != userID {
+			continue
+		}
+		if err := s.records.delete(id); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (s *serverStore) DeleteExpired() (int, error) {
+	return s.records.deleteExpired(time.Now())
+}
@@ -1,36 +1,177 @@
 package sessionstore
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
 	"errors"
 	"fmt"
-	"os"
+	"go-todo/internal/config"
+	"go-todo/internal/logger"
+	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
-	"github.com/michaeljs1990/sqlitestore"
 )
 
-func GetSessionStore(secure bool) (*sqlitestore.SqliteStore, error) {
-	secretKey := os.Getenv("SECRET_KEY")
-	if secretKey == "" {
-		return nil, errors.New("env var SECRET_KEY is blank.")
-	}
+// Store is a sessions.Store that can also end sessions other than the one
+// making the request. Handlers depend on it rather than on a backend.
+type Store interface {
+	sessions.Store
+	// Delete ends the session making the request and expires its cookie.
+	Delete(r *http.Request, w http.ResponseWriter, session *sessions.Session) error
+	// DeleteSession ends a session by id, wherever it is being used.
+	DeleteSession(sessionID string) error
+	// DeleteUserSessions ends every session named sessionName that is
+	// logged in as userID and returns how many were ended.
+	DeleteUserSessions(sessionName, userID string) (int, error)
+	// DeleteExpired removes sessions that have expired and returns how
+	// many were removed.
+	DeleteExpired() (int, error)
+	// Options are the cookie options new sessions start with.
+	Options() sessions.Options
+}
+
+// backends a Store can keep sessions in
+const (
+	BackendSQLite   = "sqlite"   // a table in its own SQLite file
+	BackendDatabase = "database" // the sessions table in the main database
+	BackendCookie   = "cookie"   // encrypted in the cookie itself
+	BackendMemory   = "memory"   // in memory, lost on restart, for tests
+)
 
-	endpoint := "data/sessions.db"
-	tableName := "sessions"
-	path := "/"
-	maxAge := 3600
-	keyPairs := []byte(secretKey)
-	store, err := sqlitestore.NewSqliteStore(endpoint, tableName, path, maxAge, keyPairs)
-	if err != nil {
-		return nil, fmt.Errorf("Error generating new sqlite store. %w", err)
+// ExpiresOnKey is where a loaded session's expiry time is kept in its
+// values. It is not saved with the values.
+const ExpiresOnKey = "expires_on"
+
+// New returns a store for the configured backend. db is the main
+// database, only the database backend uses it.
+func New(config *config.Config, db *sql.DB) (Store, error) {
+	secretKey := []byte(config.SecretKey)
+	if len(secretKey) == 0 {
+		return nil, errors.New("SECRET_KEY is blank.")
 	}
-	sessionOptions := &sessions.Options{
-		Path:     path,
-		MaxAge:   maxAge,
+
+	options := sessions.Options{
+		Path:     "/",
+		MaxAge:   int(config.Sessions.MaxAge.Seconds()),
 		HttpOnly: true,
+		Secure:   config.IsProd(),
+	}
+
+	switch config.Sessions.Store {
+	case BackendSQLite:
+		records, err := openSQLiteRecords(config.Sessions.Path, config.Sessions.Table)
+		if err != nil {
+			return nil, fmt.Errorf("Error opening sqlite session store. %w", err)
+		}
+		return newServerStore(records, secretKey, options), nil
+	case BackendDatabase:
+		if db == nil {
+			return nil, errors.New("the database session store needs a database")
+		}
+		return newServerStore(newSQLRecords(db, config.Sessions.Table), secretKey, options), nil
+	case BackendMemory:
+		return newServerStore(newMemoryRecords(), secretKey, options), nil
+	case BackendCookie:
+		return newCookieStore(secretKey, options), nil
+	default:
+		return nil, fmt.Errorf("unknown session store %q, expected sqlite, database, cookie or memory", config.Sessions.Store)
 	}
-	sessionOptions.Secure = secure
+}
+
+// ExpiresAt is when a loaded session expires, zero for a new session.
+func ExpiresAt(session *sessions.Session) time.Time {
+	expiresOn, _ := session.Values[ExpiresOnKey].(time.Time)
+	return expiresOn
+}
+
+// StartCleanup removes expired sessions from store every interval until
+// the returned stop is called.
+func StartCleanup(store Store, interval time.Duration, logr *logger.Logger) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				count, err := store.DeleteExpired()
+				if err != nil {
+					logr.Error(fmt.Sprintf("Could not delete expired sessions. %s", err))
+					continue
+				}
+				if count > 0 {
+					logr.Info(fmt.Sprintf("Deleted %d expired session(s)", count))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
 
-	store.Options = sessionOptions
-	return store, nil
+// newSessionID returns a random positive id. Ids are never reused, so a
+// cookie for a deleted session can not pick up somebody else's.
+func newSessionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := int64(binary.BigEndian.Uint64(b) >> 1)
+	if id == 0 {
+		id = 1
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// deriveKey turns the secret key into a key of the right length for one
+// use, so the same secret can both sign and encrypt cookies.
+func deriveKey(secretKey []byte, use string) []byte {
+	key := sha256.Sum256(append([]byte(use+":"), secretKey...))
+	return key[:]
+}
+
+// sessionExpiry is when a session saved at now expires. Saving extends a
+// session to its MaxAge but never shortens it.
+func sessionExpiry(session *sessions.Session, now time.Time) time.Time {
+	expiresOn := now.Add(time.Duration(session.Options.MaxAge) * time.Second)
+	if current := ExpiresAt(session); current.After(expiresOn) {
+		return current
+	}
+	return expiresOn
+}
+
+// sessionOptions are the options for a session loaded with expiresOn, so
+// its cookie lasts as long as the session even if it was given longer than
+// the default.
+func sessionOptions(options sessions.Options, expiresOn, now time.Time) *sessions.Options {
+	if remaining := int(expiresOn.Sub(now).Seconds()); remaining > options.MaxAge {
+		options.MaxAge = remaining
+	}
+	return &options
+}
+
+func expireCookie(w http.ResponseWriter, session *sessions.Session) {
+	options := *session.Options
+	options.MaxAge = -1
+	http.SetCookie(w, sessions.NewCookie(session.Name(), "", &options))
+}
+
+// decodeValues decodes a stored session, false when it belongs to another
+// session name or was signed with an old key.
+func decodeValues(sessionName, data string, codecs []securecookie.Codec) (map[interface{}]interface{}, bool) {
+	values := map[interface{}]interface{}{}
+	if err := securecookie.DecodeMulti(sessionName, data, &values, codecs...); err != nil {
+		return nil, false
+	}
+	return values, true
 }
@@ -0,0 +1,169 @@
+package sessionstore
+
+import (
+	"go-todo/internal/config"
+	database "go-todo/internal/db"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+const testSessionName = "test-session"
+
+func newTestStores(t *testing.T) map[string]Store {
+	db, err := database.ConnectMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := createSessionsTable(db, "sessions"); err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]Store{}
+	for _, backend := range []string{BackendSQLite, BackendDatabase, BackendCookie, BackendMemory} {
+		config := config.Defaults()
+		config.SecretKey = "secret"
+		config.Sessions.Store = backend
+		config.Sessions.Path = filepath.Join(t.TempDir(), "sessions.db")
+
+		store, err := New(config, db)
+		if err != nil {
+			t.Fatalf("%s: %v", backend, err)
+		}
+		stores[backend] = store
+	}
+	return stores
+}
+
+// load gets the session a browser holding cookies would send.
+func load(t *testing.T, store Store, cookies []*http.Cookie) *sessions.Session {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	session, err := store.Get(r, testSessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func save(t *testing.T, store Store, session *sessions.Session) []*http.Cookie {
+	w := httptest.NewRecorder()
+	if err := session.Save(httptest.NewRequest(http.MethodGet, "/", nil), w); err != nil {
+		t.Fatal(err)
+	}
+	return w.Result().Cookies()
+}
+
+func TestStoresKeepSessions(t *testing.T) {
+	for backend, store := range newTestStores(t) {
+		session := load(t, store, nil)
+		if !session.IsNew || session.ID != "" {
+			t.Fatalf("%s: expected a new session, got %+v", backend, session)
+		}
+
+		session.Values["user"] = "user-id"
+		session.Options.MaxAge = 30 * 24 * 60 * 60
+		cookies := save(t, store, session)
+
+		loaded := load(t, store, cookies)
+		if loaded.IsNew || loaded.ID != session.ID || loaded.Values["user"] != "user-id" {
+			t.Fatalf("%s: expected the saved session back, got %+v", backend, loaded)
+		}
+		// a longer lived session keeps its cookie's max age when saved again
+		if loaded.Options.MaxAge <= store.Options().MaxAge || !ExpiresAt(loaded).After(time.Now().Add(24*time.Hour)) {
+			t.Errorf("%s: expected the session to last 30 days, got max age %d", backend, loaded.Options.MaxAge)
+		}
+
+		// a tampered cookie starts a new session
+		tampered := *cookies[0]
+		tampered.Value = "x" + tampered.Value
+		if tamperedSession := load(t, store, []*http.Cookie{&tampered}); !tamperedSession.IsNew {
+			t.Errorf("%s: expected a tampered cookie to be refused", backend)
+		}
+
+		w := httptest.NewRecorder()
+		if err := store.Delete(httptest.NewRequest(http.MethodGet, "/", nil), w, loaded); err != nil {
+			t.Fatal(err)
+		}
+		if expired := w.Result().Cookies(); len(expired) != 1 || expired[0].MaxAge >= 0 {
+			t.Errorf("%s: expected delete to expire the cookie, got %v", backend, expired)
+		}
+	}
+}
+
+func TestServerStoresDeleteSessions(t *testing.T) {
+	for backend, store := range newTestStores(t) {
+		if backend == BackendCookie {
+			continue
+		}
+
+		sessionCookies := map[string][]*http.Cookie{}
+		for _, user := range []string{"a", "a", "b"} {
+			session := load(t, store, nil)
+			session.Values["user"] = user
+			cookies := save(t, store, session)
+			sessionCookies[session.ID] = cookies
+		}
+
+		count, err := store.DeleteUserSessions(testSessionName, "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Errorf("%s: expected 2 sessions deleted for a, got %d", backend, count)
+		}
+
+		for id, cookies := range sessionCookies {
+			session := load(t, store, cookies)
+			if session.Values["user"] == "a" {
+				t.Errorf("%s: expected session %s for a to be gone", backend, id)
+			}
+			if session.Values["user"] == "b" {
+				if err := store.DeleteSession(id); err != nil {
+					t.Fatal(err)
+				}
+				if !load(t, store, cookies).IsNew {
+					t.Errorf("%s: expected deleted session %s to be gone", backend, id)
+				}
+			}
+		}
+	}
+}
+
+func TestServerStoresDeleteExpired(t *testing.T) {
+	for backend, store := range newTestStores(t) {
+		if backend == BackendCookie {
+			continue
+		}
+
+		live := load(t, store, nil)
+		liveCookies := save(t, store, live)
+
+		expired := load(t, store, nil)
+		expired.Options.MaxAge = -60
+		expiredCookies := save(t, store, expired)
+
+		if !load(t, store, expiredCookies).IsNew {
+			t.Errorf("%s: expected an expired session not to load", backend)
+		}
+
+		count, err := store.DeleteExpired()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("%s: expected 1 expired session deleted, got %d", backend, count)
+		}
+		if load(t, store, liveCookies).IsNew {
+			t.Errorf("%s: expected the live session to be kept", backend)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package sessionstore
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// sqlRecords keeps sessions in a table laid out as
+// github.com/michaeljs1990/sqlitestore did, so sessions it saved still load.
+type sqlRecords struct {
+	db    *sql.DB
+	table string
+}
+
+func newSQLRecords(db *sql.DB, table string) *sqlRecords {
+	return &sqlRecords{db: db, table: table}
+}
+
+// openSQLiteRecords opens the SQLite file at path for sessions, creating
+// the table when it does not exist.
+func openSQLiteRecords(path, table string) (*sqlRecords, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open session database. %w", err)
+	}
+
+	err = createSessionsTable(db, table)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return newSQLRecords(db, table), nil
+}
+
+// the main database's sessions table is created by a migration instead
+func createSessionsTable(db *sql.DB, table string) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + table + ` (
+		id INTEGER PRIMARY KEY,
+		session_data LONGBLOB,
+		created_on TIMESTAMP DEFAULT 0,
+		modified_on TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_on TIMESTAMP DEFAULT 0
+	)`)
+	if err != nil {
+		return fmt.Errorf("Could not create sessions table. %w", err)
+	}
+	return nil
+}
+
+func (s *sqlRecords) get(id string) (*record, error) {
+	sessionID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	rec := record{}
+	err = s.db.QueryRow(`SELECT session_data, created_on, expires_on FROM `+s.table+` WHERE id = ?`, sessionID).Scan(&rec.data, &rec.createdOn, &rec.expiresOn)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not get session %s. %w", id, err)
+	}
+	return &rec, nil
+}
+
+func (s *sqlRecords) save(id string, rec record) error {
+	sessionID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Session id %q is not a number. %w", id, err)
+	}
+
+	_, err = s.db.Exec(`INSERT INTO `+s.table+`(id, session_data, created_on, modified_on, expires_on) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			session_data = excluded.session_data,
+			modified_on = excluded.modified_on,
+			expires_on = excluded.expires_on`,
+		sessionID, rec.data, rec.createdOn.UTC(), time.Now().UTC(), rec.expiresOn.UTC())
+	if err != nil {
+		return fmt.Errorf("Could not save session %s. %w", id, err)
+	}
+	return nil
+}
+
+func (s *sqlRecords) delete(id string) error {
+	sessionID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	_, err = s.db.Exec(`DELETE FROM `+s.table+` WHERE id = ?`, sessionID)
+	if err != nil {
+		return fmt.Errorf("Could not delete session %s. %w", id, err)
+	}
+	return nil
+}
+
+func (s *sqlRecords) all() (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT id, session_data FROM ` + s.table)
+	if err != nil {
+		return nil, fmt.Errorf("Could not query sessions. %w", err)
+	}
+	defer rows.Close()
+
+	all := map[string]string{}
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("Issue scanning sessions. %w", err)
+		}
+		all[strconv.FormatInt(id, 10)] = data
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Issue reading sessions. %w", err)
+	}
+	return all, nil
+}
+
+func (s *sqlRecords) deleteExpired(now time.Time) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM `+s.table+` WHERE expires_on <= ?`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("Could not delete expired sessions. %w", err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected. %w", err)
+	}
+	return int(count), nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxAccessTokens caps how many unrevoked tokens a user may have.
+const MaxAccessTokens = 20
+
+const maxAccessTokenNameLength = 100
+
+// last used times are only written once per interval so a busy script does
+// not cause a write on every request
+const accessTokenLastUsedInterval = time.Minute
+
+func generateAccessToken() (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	return models.AccessTokenPrefix + token, nil
+}
+
+// CreateAccessToken creates a personal access token for the user. The
+// returned token's Token field holds the only copy of the secret.
+func (s *Service) CreateAccessToken(userID, name, scope string) (*models.AccessToken, *models.CreateAccessTokenClientErrors, error) {
+	clientErrors := models.CreateAccessTokenClientErrors{}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		clientErrors.NameErrors = append(clientErrors.NameErrors, "You must give the token a name.")
+	} else if len(name) > maxAccessTokenNameLength {
+		clientErrors.NameErrors = append(clientErrors.NameErrors, fmt.Sprintf("Token names must be %d characters or fewer.", maxAccessTokenNameLength))
+	}
+
+	if scope != models.AccessTokenScopeRead && scope != models.AccessTokenScopeWrite {
+		clientErrors.ScopeErrors = append(clientErrors.ScopeErrors, "Tokens must be read or write.")
+	}
+
+	count, err := s.repo.CountAccessTokensByUserID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not count access tokens. %w", err)
+	}
+
+	if count >= MaxAccessTokens {
+		clientErrors.NameErrors = append(clientErrors.NameErrors, fmt.Sprintf("You can have at most %d tokens, revoke one first.", MaxAccessTokens))
+	}
+
+	if len(clientErrors.NameErrors) > 0 || len(clientErrors.ScopeErrors) > 0 {
+		return nil, &clientErrors, nil
+	}
+
+	secret, err := generateAccessToken()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not generate access token. %w", err)
+	}
+
+	token := models.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:len(models.AccessTokenPrefix)+6],
+		Scope:     scope,
+		CreatedAt: time.Now().UTC(),
+		Token:     secret,
+	}
+
+	token.ID, err = s.repo.CreateAccessToken(&token, hashToken(secret))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not create access token. %w", err)
+	}
+
+	return &token, nil, nil
+}
+
+func (s *Service) GetUserAccessTokens(userID string) ([]*models.AccessToken, error) {
+	tokens, err := s.repo.GetAccessTokensByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get access tokens. %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *Service) RevokeAccessToken(userID string, tokenID int) (clientError, error) {
+	revoked, err := s.repo.RevokeAccessToken(userID, tokenID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("Could not revoke access token. %w", err)
+	}
+
+	if !revoked {
+		return NewClientError("The token you tried to revoke does not exist", http.StatusNotFound), nil
+	}
+	return nil, nil
+}
+
+// AuthenticateAccessToken returns the user and token for a bearer token,
+// or nil when the token is unknown or revoked.
+func (s *Service) AuthenticateAccessToken(secret string) (*models.User, *models.AccessToken, error) {
+	if !strings.HasPrefix(secret, models.AccessTokenPrefix) {
+		return nil, nil, nil
+	}
+
+	token, err := s.repo.GetAccessTokenByHash(hashToken(secret))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get access token. %w", err)
+	}
+
+	if token == nil {
+		return nil, nil, nil
+	}
+
+	user, err := s.repo.GetUserByID(token.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get user by ID. %w", err)
+	}
+
+	if user == nil {
+		return nil, nil, nil
+	}
+
+	now := time.Now().UTC()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= accessTokenLastUsedInterval {
+		err = s.repo.UpdateAccessTokenLastUsed(token.ID, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not update access token last used. %w", err)
+		}
+		token.LastUsedAt = &now
+	}
+
+	return user, token, nil
+}
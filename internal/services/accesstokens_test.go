@@ -0,0 +1,69 @@
+package services
+
+import (
+	"go-todo/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestAccessTokenLifecycle(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+
+	_, clientErrors, err := s.CreateAccessToken(user.ID, " ", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientErrors == nil || len(clientErrors.NameErrors) == 0 || len(clientErrors.ScopeErrors) == 0 {
+		t.Fatalf("expected name and scope to be rejected, got %v", clientErrors)
+	}
+
+	token, clientErrors, err := s.CreateAccessToken(user.ID, "script", models.AccessTokenScopeRead)
+	if err != nil || clientErrors != nil {
+		t.Fatalf("expected token to be created, got %v %v", clientErrors, err)
+	}
+	if !strings.HasPrefix(token.Token, token.Prefix) || !strings.HasPrefix(token.Prefix, models.AccessTokenPrefix) {
+		t.Errorf("expected token %q to start with prefix %q", token.Token, token.Prefix)
+	}
+
+	authenticated, authenticatedToken, err := s.AuthenticateAccessToken(token.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authenticated == nil || authenticated.ID != user.ID {
+		t.Fatalf("expected token to authenticate its user, got %v", authenticated)
+	}
+	if authenticatedToken.CanWrite() || authenticatedToken.LastUsedAt == nil {
+		t.Errorf("expected a read only token with a last used time, got %+v", authenticatedToken)
+	}
+
+	// the secret itself is never stored
+	tokens, err := s.GetUserAccessTokens(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].Token != "" {
+		t.Fatalf("expected one stored token without its secret, got %+v", tokens)
+	}
+
+	clientError, err := s.RevokeAccessToken("someone else", token.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil {
+		t.Error("expected another user's revoke to be rejected")
+	}
+
+	clientError, err = s.RevokeAccessToken(user.ID, token.ID)
+	if err != nil || clientError != nil {
+		t.Fatalf("expected token to be revoked, got %v %v", clientError, err)
+	}
+
+	authenticated, _, err = s.AuthenticateAccessToken(token.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authenticated != nil {
+		t.Error("expected a revoked token to be rejected")
+	}
+}
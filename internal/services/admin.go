@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"html"
+	"strings"
+)
+
+// UsersPageSize is how many users the admin user listing shows per page.
+const UsersPageSize = 25
+
+// ListUsers returns a page of users whose name or email contains query,
+// along with the total number of matching users. Pages start at 1.
+func (s *Service) ListUsers(query string, page int) ([]*models.User, int, error) {
+	query = strings.TrimSpace(query)
+	if page < 1 {
+		page = 1
+	}
+
+	total, err := s.repo.CountUsers(query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Could not count users. %w", err)
+	}
+
+	users, err := s.repo.ListUsers(query, UsersPageSize, (page-1)*UsersPageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Could not list users. %w", err)
+	}
+
+	return users, total, nil
+}
+
+func (s *Service) GetUserStats(userID string) (*models.UserStats, error) {
+	todoCount, err := s.repo.CountTodosByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not count todos. %w", err)
+	}
+
+	completedTodoCount, err := s.repo.CountCompletedTodosByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not count completed todos. %w", err)
+	}
+
+	archivedTodoCount, err := s.repo.CountArchivedTodosByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not count archived todos. %w", err)
+	}
+
+	listCount, err := s.repo.CountListsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not count lists. %w", err)
+	}
+
+	return &models.UserStats{
+		TodoCount:          todoCount,
+		CompletedTodoCount: completedTodoCount,
+		ArchivedTodoCount:  archivedTodoCount,
+		ListCount:          listCount,
+	}, nil
+}
+
+// UpdateUser lets an admin change a user's name and email. Changing the
+// email clears its verification. Their plan follows their subscription and
+// can not be changed here.
+func (s *Service) UpdateUser(userID, name, email string) (*models.User, *models.UpdateUserClientErrors, error) {
+	clientErrors := models.UpdateUserClientErrors{}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get user by ID. %w", err)
+	}
+
+	if user == nil {
+		return nil, nil, fmt.Errorf("Could not update user (%s), they do not exist", userID)
+	}
+
+	// clean the name and email the same way as at sign up
+	name = strings.TrimSpace(html.EscapeString(name))
+	email = strings.TrimSpace(html.EscapeString(email))
+
+	if name == "" {
+		clientErrors.NameErrors = append(clientErrors.NameErrors, "You must provide a user name.")
+	}
+
+	if !isValidEmail(email) {
+		clientErrors.EmailErrors = append(clientErrors.EmailErrors, "You must provide a valid email.")
+	} else if !strings.EqualFold(email, user.Email) {
+		emailExists, err := s.repo.UserEmailExists(email)
+		if err != nil {
+			return nil, nil, err
+		}
+		if emailExists {
+			clientErrors.EmailErrors = append(clientErrors.EmailErrors, "Another user already has that email.")
+		}
+	}
+
+	if len(clientErrors.NameErrors) > 0 || len(clientErrors.EmailErrors) > 0 {
+		return user, &clientErrors, nil
+	}
+
+	// a new email has to be verified again
+	if email != user.Email {
+		user.EmailVerifiedAt = nil
+	}
+	user.Name = name
+	user.Email = email
+
+	err = s.repo.UpdateUser(*user)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not update user. %w", err)
+	}
+
+	return user, nil, nil
+}
+
+// DeleteUser removes the user and everything they own. Their sessions live
+// in the session store and are removed by the caller.
+func (s *Service) DeleteUser(userID string) error {
+	err := s.repo.DeleteUser(userID)
+	if err != nil {
+		return fmt.Errorf("Could not delete user. %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateUserEmailClearsVerification(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user1", false)
+
+	if _, err := s.repo.MarkEmailVerified(user.ID, user.Email, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// keeping the email keeps it verified
+	updated, clientErrors, err := s.UpdateUser(user.ID, "Renamed", user.Email)
+	if err != nil || clientErrors != nil {
+		t.Fatalf("unexpected errors %v %v", clientErrors, err)
+	}
+	if !updated.EmailIsVerified() {
+		t.Error("expected the unchanged email to stay verified")
+	}
+
+	updated, clientErrors, err = s.UpdateUser(user.ID, "Renamed", "  new@email.com ")
+	if err != nil || clientErrors != nil {
+		t.Fatalf("unexpected errors %v %v", clientErrors, err)
+	}
+	if updated.EmailIsVerified() {
+		t.Error("expected the returned user's new email to be unverified")
+	}
+
+	stored, err := s.repo.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Email != "new@email.com" {
+		t.Errorf("expected the email to be cleaned like at sign up, got %q", stored.Email)
+	}
+	if stored.EmailIsVerified() {
+		t.Error("expected the stored new email to be unverified")
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"time"
+)
+
+// AnalyticsDays is how many days of history the analytics dashboard charts.
+const AnalyticsDays = 30
+
+// fillDays turns counts keyed by YYYY-MM-DD into one entry per day from
+// since for the given number of days, with zero for days without data.
+func fillDays(counts map[string]int, since time.Time, days int) []models.DailyCount {
+	series := make([]models.DailyCount, days)
+	for i := range series {
+		day := since.AddDate(0, 0, i)
+		series[i] = models.DailyCount{
+			Day:   day,
+			Count: counts[day.Format("2006-01-02")],
+		}
+	}
+	return series
+}
+
+// GetAnalytics gathers the admin metrics for the last days days, ending
+// with the UTC day containing now.
+func (s *Service) GetAnalytics(now time.Time, days int) (*models.Analytics, error) {
+	if days < 1 {
+		days = 1
+	}
+
+	now = now.UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(days - 1))
+
+	analytics := models.Analytics{Since: since}
+
+	metrics := []struct {
+		dest  *[]models.DailyCount
+		count func(time.Time) (map[string]int, error)
+	}{
+		{&analytics.Signups, s.repo.CountSignupsByDay},
+		{&analytics.ActiveUsers, s.repo.CountActiveUsersByDay},
+		{&analytics.TodosCreated, s.repo.CountTodosCreatedByDay},
+		{&analytics.TodosCompleted, s.repo.CountTodosCompletedByDay},
+		{&analytics.Conversions, func(since time.Time) (map[string]int, error) {
+			return s.repo.CountPlanChangesByDay(since, true)
+		}},
+		{&analytics.Cancellations, func(since time.Time) (map[string]int, error) {
+			return s.repo.CountPlanChangesByDay(since, false)
+		}},
+	}
+
+	for _, metric := range metrics {
+		counts, err := metric.count(since)
+		if err != nil {
+			return nil, fmt.Errorf("Could not get analytics. %w", err)
+		}
+		*metric.dest = fillDays(counts, since, days)
+	}
+
+	paid, free, err := s.repo.CountUsersByPlan()
+	if err != nil {
+		return nil, fmt.Errorf("Could not get analytics. %w", err)
+	}
+	analytics.PaidUsers = paid
+	analytics.FreeUsers = free
+
+	atLimit, err := s.repo.CountFreeUsersAtTodoLimit(s.Plans().Free().Entitlements.MaxTodos)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get analytics. %w", err)
+	}
+	analytics.FreeUsersAtLimit = atLimit
+
+	return &analytics, nil
+}
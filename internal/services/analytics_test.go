@@ -0,0 +1,48 @@
+package services
+
+import (
+	"go-todo/internal/models"
+	"testing"
+	"time"
+)
+
+func TestGetAnalyticsCountsPlanChanges(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	newTestUser(t, s, "other", false)
+
+	start := time.Now().Add(-time.Hour)
+	receiveTestEvent(t, s, "evt_1", "customer.subscription.created", start, newTestSubscription("sub_1", "cus_1", user.ID, "active"))
+	// an unchanged plan is not a conversion
+	receiveTestEvent(t, s, "evt_2", "customer.subscription.updated", start.Add(time.Minute), newTestSubscription("sub_1", "cus_1", user.ID, "past_due"))
+	receiveTestEvent(t, s, "evt_3", "customer.subscription.deleted", start.Add(2*time.Minute), newTestSubscription("sub_1", "cus_1", user.ID, "canceled"))
+	// the other user stays subscribed
+	receiveTestEvent(t, s, "evt_4", "customer.subscription.created", start, newTestSubscription("sub_2", "cus_2", "other", "active"))
+
+	analytics, err := s.GetAnalytics(time.Now(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(analytics.Conversions) != 3 {
+		t.Fatalf("expected 3 days of conversions, got %d", len(analytics.Conversions))
+	}
+
+	today := analytics.Conversions[2].Day
+	if want := time.Now().UTC().Format("2006-01-02"); today.Format("2006-01-02") != want {
+		t.Errorf("expected the last day to be %s, got %v", want, today)
+	}
+
+	if got := models.Total(analytics.Conversions); got != 2 {
+		t.Errorf("expected 2 conversions, got %d", got)
+	}
+	if got := models.Total(analytics.Cancellations); got != 1 {
+		t.Errorf("expected 1 cancellation, got %d", got)
+	}
+	if got := models.Total(analytics.Signups); got != 2 {
+		t.Errorf("expected 2 signups, got %d", got)
+	}
+	if analytics.PaidUsers != 1 || analytics.FreeUsers != 1 {
+		t.Errorf("expected 1 paid and 1 free user, got %d and %d", analytics.PaidUsers, analytics.FreeUsers)
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+	"time"
+)
+
+// archivedTodoError is returned when a user tries to change an archived
+// todo.
+func archivedTodoError() clientError {
+	return NewClientError("This todo is archived. Upgrade or choose it as one of your active todos to change it", http.StatusForbidden)
+}
+
+// applyTodoLimit fits the user's active todos to their plan after it
+// changes. Todos over the limit are archived rather than deleted, and are
+// restored, as many as fit, when the user moves to a bigger plan.
+func (s *Service) applyTodoLimit(userID string) error {
+	plan, err := s.GetUserPlan(userID)
+	if err != nil {
+		return err
+	}
+	limit := plan.Entitlements.MaxTodos
+
+	if limit == 0 {
+		_, err = s.repo.RestoreArchivedTodos(userID, 0)
+		if err != nil {
+			return fmt.Errorf("Could not restore archived todos. %w", err)
+		}
+		return nil
+	}
+
+	active, err := s.repo.CountActiveTodosByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("Could not count active todos. %w", err)
+	}
+
+	if active > limit {
+		_, err = s.repo.ArchiveTodosOverLimit(userID, limit, time.Now())
+		if err != nil {
+			return fmt.Errorf("Could not archive todos over the limit. %w", err)
+		}
+	} else if active < limit {
+		_, err = s.repo.RestoreArchivedTodos(userID, limit-active)
+		if err != nil {
+			return fmt.Errorf("Could not restore archived todos. %w", err)
+		}
+	}
+	return nil
+}
+
+// GetTodoArchive returns how many of the user's todos are archived and the
+// todo limit of their plan, 0 when it has none.
+func (s *Service) GetTodoArchive(userID string) (archived int, limit int, err error) {
+	archived, err = s.repo.CountArchivedTodosByUserID(userID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Could not count archived todos. %w", err)
+	}
+
+	plan, err := s.GetUserPlan(userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return archived, plan.Entitlements.MaxTodos, nil
+}
+
+// GetAllUserTodos returns every one of the user's todos, archived or not.
+func (s *Service) GetAllUserTodos(userID string) ([]*models.Todo, error) {
+	todos, err := s.repo.GetTodosByUserID(userID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get todos. %w", err)
+	}
+	return todos, nil
+}
+
+// ChooseActiveTodos keeps todoIDs active and archives the user's other
+// todos. Users can choose up to their plan's todo limit.
+func (s *Service) ChooseActiveTodos(user *models.User, todoIDs []int) (clientError, error) {
+	plan, err := s.GetUserPlan(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	limit := plan.Entitlements.MaxTodos
+
+	if limit == 0 {
+		return NewClientError("Your plan has no todo limit, none of your todos need to be archived", http.StatusBadRequest), nil
+	}
+
+	chosen := []int{}
+	seen := map[int]bool{}
+	for _, todoID := range todoIDs {
+		if !seen[todoID] {
+			seen[todoID] = true
+			chosen = append(chosen, todoID)
+		}
+	}
+
+	if len(chosen) > limit {
+		return NewClientError(fmt.Sprintf("You can keep up to %d todos active on the %s plan", limit, plan.Name), http.StatusBadRequest), nil
+	}
+
+	err = s.repo.SetActiveTodos(user.ID, chosen, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("Could not choose active todos. %w", err)
+	}
+	return nil, nil
+}
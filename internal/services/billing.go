@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+)
+
+// Billing is the payment provider. billing.Stripe talks to Stripe,
+// billing.Fake keeps everything in memory for tests.
+type Billing interface {
+	// NewCheckoutSession starts a hosted checkout for a subscription to
+	// priceID, starting with a free trial when trialDays is not zero.
+	// userID is kept on the checkout session and the subscription so their
+	// events can be matched to the user. The user is sent back to
+	// successURL, where {CHECKOUT_SESSION_ID} is replaced with the
+	// session's id, or cancelURL.
+	NewCheckoutSession(userID, customerEmail, priceID string, trialDays int, successURL, cancelURL string) (*models.CheckoutSession, error)
+	GetCheckoutSession(checkoutSessionID string) (*models.CheckoutSession, error)
+	// NewPortalSession returns the url of a page where the customer can
+	// manage their subscription and payment methods.
+	NewPortalSession(customerID, returnURL string) (string, error)
+	// ListSubscriptions returns every subscription the customer has had,
+	// newest first. Their Status is the provider's own, e.g. unpaid, and
+	// UserID is the user in their metadata, if any.
+	ListSubscriptions(customerID string) ([]*models.Subscription, error)
+	// ConstructEvent verifies a webhook's signature and parses it. It fails
+	// for any payload that was not sent by the provider.
+	ConstructEvent(payload []byte, signature string) (*models.BillingEvent, error)
+}
+
+// StartCheckout starts a checkout for the paid plan with planID and
+// returns the url to send the user to. Users who have never subscribed
+// get the plan's free trial.
+func (s *Service) StartCheckout(user *models.User, planID string) (string, clientError, error) {
+	plan := s.Plans().Get(planID)
+	if plan == nil || plan.IsFree() {
+		return "", NewClientError("Choose a plan to upgrade to", http.StatusBadRequest), nil
+	}
+
+	trialDays := 0
+	if plan.TrialDays > 0 {
+		trialEligible, err := s.IsTrialEligible(user.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		if trialEligible {
+			trialDays = plan.TrialDays
+		}
+	}
+
+	successURL := s.config.Domain + "/success?session_id={CHECKOUT_SESSION_ID}"
+	cancelURL := s.config.Domain + "/subscription/cancel"
+
+	checkoutSession, err := s.billing.NewCheckoutSession(user.ID, user.Email, plan.PriceID, trialDays, successURL, cancelURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("Could not create checkout session for user (%s). %w", user.ID, err)
+	}
+	return checkoutSession.URL, nil, nil
+}
+
+// CompleteCheckout links the user to the customer who paid in a checkout
+// session. The paid plan itself starts when the subscription's webhook
+// event arrives, so it returns true only once the user is on it.
+func (s *Service) CompleteCheckout(user *models.User, checkoutSessionID string) (bool, clientError, error) {
+	if checkoutSessionID == "" {
+		return false, NewClientError("No checkout session was given", http.StatusBadRequest), nil
+	}
+
+	checkoutSession, err := s.billing.GetCheckoutSession(checkoutSessionID)
+	if err != nil {
+		return false, nil, fmt.Errorf("Could not get checkout session. %w", err)
+	}
+
+	if checkoutSession == nil {
+		return false, NewClientError("That checkout session does not exist", http.StatusNotFound), nil
+	}
+
+	// the checkout email is only trusted when it is the user's
+	customer, err := s.GetUserByEmail(checkoutSession.CustomerEmail)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if customer == nil || customer.ID != user.ID {
+		return false, NewClientError("This checkout session does not belong to your account", http.StatusForbidden), nil
+	}
+
+	if !checkoutSession.IsPaid() {
+		return false, nil, nil
+	}
+
+	if checkoutSession.CustomerID != "" && checkoutSession.CustomerID != user.StripeCustomerID {
+		err = s.AddStripeIDToUser(user.ID, checkoutSession.CustomerID)
+		if err != nil {
+			return false, nil, err
+		}
+	}
+
+	isPaidUser, err := s.UserIsPaidUser(user.ID)
+	if err != nil {
+		return false, nil, err
+	}
+	return isPaidUser, nil, nil
+}
+
+// StartCustomerPortal returns the url of the page where the user manages
+// their subscription.
+func (s *Service) StartCustomerPortal(user *models.User) (string, clientError, error) {
+	if user.StripeCustomerID == "" {
+		return "", NewClientError("You do not have a subscription to manage", http.StatusBadRequest), nil
+	}
+
+	url, err := s.billing.NewPortalSession(user.StripeCustomerID, s.config.Domain)
+	if err != nil {
+		return "", nil, fmt.Errorf("Could not create customer portal session for user (%s). %w", user.ID, err)
+	}
+	return url, nil, nil
+}
+
+// ParseBillingEvent verifies and parses a webhook sent by the billing
+// provider. Payloads that fail verification are a client error.
+func (s *Service) ParseBillingEvent(payload []byte, signature string) (*models.BillingEvent, clientError) {
+	event, err := s.billing.ConstructEvent(payload, signature)
+	if err != nil {
+		return nil, NewClientError(fmt.Sprintf("Invalid webhook. %s", err), http.StatusBadRequest)
+	}
+	return event, nil
+}
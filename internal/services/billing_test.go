@@ -0,0 +1,168 @@
+package services
+
+import (
+	"go-todo/internal/billing"
+	"go-todo/internal/models"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckoutUpgradesUser(t *testing.T) {
+	s := newTestService(t)
+	s.config.Domain = "http://localhost:8080"
+	fake := s.billing.(*billing.Fake)
+
+	user := newTestUser(t, s, "user", false)
+	other := newTestUser(t, s, "other", false)
+
+	_, clientError, err := s.StartCheckout(user, "free")
+	if err != nil || clientError == nil {
+		t.Fatalf("expected the free plan not to be bought, got %v %v", clientError, err)
+	}
+
+	checkoutURL, clientError, err := s.StartCheckout(user, "pro")
+	if err != nil || clientError != nil {
+		t.Fatal(clientError, err)
+	}
+	checkoutSessionID := checkoutURL[strings.LastIndex(checkoutURL, "/")+1:]
+
+	// returning before paying changes nothing
+	paid, clientError, err := s.CompleteCheckout(user, checkoutSessionID)
+	if err != nil || clientError != nil || paid {
+		t.Fatalf("expected an unpaid checkout to change nothing, got %v %v %v", paid, clientError, err)
+	}
+
+	checkoutSession, err := fake.PayCheckoutSession(checkoutSessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// somebody else can not claim the checkout
+	_, clientError, err = s.CompleteCheckout(other, checkoutSessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil || clientError.Code != http.StatusForbidden {
+		t.Fatalf("expected another user's checkout to be refused, got %v", clientError)
+	}
+
+	// the plan waits for the subscription's webhook
+	paid, clientError, err = s.CompleteCheckout(user, checkoutSessionID)
+	if err != nil || clientError != nil || paid {
+		t.Fatalf("expected the checkout to wait for the subscription, got %v %v %v", paid, clientError, err)
+	}
+
+	payload, signature, err := fake.SubscriptionEvent("customer.subscription.created", checkoutSession.SubscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, clientError := s.ParseBillingEvent(payload, signature)
+	if clientError != nil {
+		t.Fatal(clientError)
+	}
+	if _, err := s.ReceiveStripeEvent(event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ProcessStripeEvent(event.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	paid, clientError, err = s.CompleteCheckout(user, checkoutSessionID)
+	if err != nil || clientError != nil || !paid {
+		t.Fatalf("expected the checkout to be completed, got %v %v %v", paid, clientError, err)
+	}
+
+	user, err = s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsPaidUser || user.StripeCustomerID == "" {
+		t.Fatalf("expected the user to be paid with a stripe customer, got %+v", user)
+	}
+
+	portalURL, clientError, err := s.StartCustomerPortal(user)
+	if err != nil || clientError != nil || portalURL == "" {
+		t.Errorf("expected a customer portal, got %q %v %v", portalURL, clientError, err)
+	}
+
+	_, clientError, err = s.StartCustomerPortal(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil || clientError.Code != http.StatusBadRequest {
+		t.Errorf("expected users without a subscription to have no portal, got %v", clientError)
+	}
+}
+
+func TestCheckoutTrialIsOnlyGivenOnce(t *testing.T) {
+	s := newTestService(t)
+	fake := s.billing.(*billing.Fake)
+	s.config.Plans.Catalogue.Get("pro").TrialDays = 14
+	user := newTestUser(t, s, "user", false)
+
+	checkout := func(paymentStatus string) *models.Subscription {
+		t.Helper()
+		checkoutURL, clientError, err := s.StartCheckout(user, "pro")
+		if err != nil || clientError != nil {
+			t.Fatal(clientError, err)
+		}
+		checkoutSession, err := fake.PayCheckoutSession(checkoutURL[strings.LastIndex(checkoutURL, "/")+1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, signature, err := fake.SubscriptionEvent("customer.subscription.created", checkoutSession.SubscriptionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		event, _ := s.ParseBillingEvent(payload, signature)
+		if _, err := s.ReceiveStripeEvent(event); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ProcessStripeEvent(event.ID); err != nil {
+			t.Fatal(err)
+		}
+		if checkoutSession.PaymentStatus != paymentStatus {
+			t.Errorf("expected the checkout to complete %s, got %s", paymentStatus, checkoutSession.PaymentStatus)
+		}
+
+		// a trial is completed with nothing paid
+		paid, clientError, err := s.CompleteCheckout(user, checkoutSession.ID)
+		if err != nil || clientError != nil || !paid {
+			t.Fatalf("expected the checkout to be completed, got %v %v %v", paid, clientError, err)
+		}
+
+		subscription, err := s.repo.GetSubscription(checkoutSession.SubscriptionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return subscription
+	}
+
+	if first := checkout(models.PaymentStatusNoPaymentRequired); first.Status != models.SubscriptionTrialing {
+		t.Errorf("expected the first subscription to start with a trial, got %s", first.Status)
+	}
+	if second := checkout(models.PaymentStatusPaid); second.Status != models.SubscriptionActive {
+		t.Errorf("expected no second trial, got %s", second.Status)
+	}
+}
+
+func TestParseBillingEventRefusesBadSignatures(t *testing.T) {
+	s := newTestService(t)
+	fake := s.billing.(*billing.Fake)
+
+	payload, signature, err := fake.Event("invoice.paid", map[string]string{"id": "in_1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, clientError := s.ParseBillingEvent(payload, signature)
+	if clientError != nil || event.Type != "invoice.paid" {
+		t.Fatalf("expected the event to be parsed, got %+v %v", event, clientError)
+	}
+
+	_, clientError = s.ParseBillingEvent(payload, "t=1,v1=bad")
+	if clientError == nil || clientError.Code != http.StatusBadRequest {
+		t.Errorf("expected a bad signature to be a bad request, got %v", clientError)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-todo/internal/logger"
+	"go-todo/internal/mailer"
+	"go-todo/internal/models"
+	"time"
+)
+
+const dunningReminderEmailBody = `Hi %s,
+
+We could not take the latest payment for your %s plan. Please update your payment method before %s to keep it:
+
+%s
+
+After that your account moves to the free plan.
+`
+
+// DunningResult is what a dunning run did. Errors holds the subscriptions
+// that could not be chased, the rest of the run carries on without them.
+type DunningResult struct {
+	Reminded   []*models.Subscription
+	Downgraded []*models.Subscription
+	Errors     []error
+}
+
+// RunDunning chases the payments of past due subscriptions at now. It
+// sends each reminder to pay that has come due and takes the plan from
+// users whose grace period has ended.
+func (s *Service) RunDunning(now time.Time) (*DunningResult, error) {
+	pastDue, err := s.repo.GetSubscriptionsByStatus(models.SubscriptionPastDue)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get past due subscriptions. %w", err)
+	}
+
+	result := &DunningResult{}
+	for _, subscription := range pastDue {
+		err = s.dunSubscription(subscription, now, result)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("Could not chase subscription (%s). %w", subscription.ID, err))
+		}
+	}
+
+	return result, errors.Join(result.Errors...)
+}
+
+func (s *Service) dunSubscription(subscription *models.Subscription, now time.Time, result *DunningResult) error {
+	if !subscription.InGracePeriodAt(now) {
+		changed, err := s.syncUserPlan(subscription.UserID, models.PlanChangeSourceDunning, now)
+		if err != nil {
+			return err
+		}
+		if changed {
+			result.Downgraded = append(result.Downgraded, subscription)
+		}
+		return nil
+	}
+
+	if subscription.PastDueSince == nil || subscription.GraceEndsAt == nil {
+		return nil
+	}
+
+	// a run that was missed sends one reminder rather than several
+	due := 0
+	for _, after := range s.config.Dunning.Reminders {
+		if !now.Before(subscription.PastDueSince.Add(after)) {
+			due++
+		}
+	}
+	if due <= subscription.DunningRemindersSent {
+		return nil
+	}
+
+	err := s.sendDunningReminder(subscription)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.SetDunningRemindersSent(subscription.ID, due)
+	if err != nil {
+		return fmt.Errorf("Could not record dunning reminder. %w", err)
+	}
+	result.Reminded = append(result.Reminded, subscription)
+	return nil
+}
+
+func (s *Service) sendDunningReminder(subscription *models.Subscription) error {
+	user, err := s.GetUserByID(subscription.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("subscription %s belongs to user (%s) who does not exist", subscription.ID, subscription.UserID)
+	}
+
+	planName := "paid"
+	if plan := s.Plans().ByPriceID(subscription.PriceID); plan != nil {
+		planName = plan.Name
+	}
+
+	err = s.queueEmail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your go-todo payment failed",
+		Body:    fmt.Sprintf(dunningReminderEmailBody, user.Name, planName, subscription.GraceEndsAt.Format("January 2"), s.config.Domain+"/manage-subscription"),
+	})
+	if err != nil {
+		return fmt.Errorf("Could not queue dunning reminder. %w", err)
+	}
+	return nil
+}
+
+// StartDunning runs dunning every interval until the returned stop is
+// called.
+func (s *Service) StartDunning(interval time.Duration, logr *logger.Logger) (stop func()) {
+	return runEvery(interval, func() {
+		result, err := s.RunDunning(time.Now())
+		if result == nil {
+			logr.Error(fmt.Sprintf("Could not run dunning. %s", err))
+			return
+		}
+		for _, err := range result.Errors {
+			logr.Error(err.Error())
+		}
+		for _, subscription := range result.Reminded {
+			logr.Info(fmt.Sprintf("Reminded user (%s) to pay for past due subscription (%s)", subscription.UserID, subscription.ID))
+		}
+		for _, subscription := range result.Downgraded {
+			logr.Info(fmt.Sprintf("Moved user (%s) to the free plan, the grace period of subscription (%s) ended", subscription.UserID, subscription.ID))
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"go-todo/internal/mailer"
+	"go-todo/internal/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPastDueUsersKeepTheirPlanUntilTheGracePeriodEnds(t *testing.T) {
+	s := newTestService(t)
+	dir := t.TempDir()
+	s.mailer = mailer.NewFileMailer(dir)
+	user := newTestUser(t, s, "user", false)
+
+	pastDue := time.Now().Add(-time.Minute).Truncate(time.Second)
+	receiveTestEvent(t, s, "evt_1", "customer.subscription.created", pastDue.Add(-time.Hour), newTestSubscription("sub_1", "cus_1", user.ID, "active"))
+	receiveTestEvent(t, s, "evt_2", "customer.subscription.updated", pastDue, newTestSubscription("sub_1", "cus_1", user.ID, "past_due"))
+
+	user, err := s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graceEndsAt := pastDue.Add(s.config.Dunning.GracePeriod)
+	if !user.IsPaidUser || user.PaymentGraceEndsAt == nil || !user.PaymentGraceEndsAt.Equal(graceEndsAt) {
+		t.Fatalf("expected a failed payment to start a grace period ending %v, got %+v", graceEndsAt, user)
+	}
+
+	expectDunning := func(at time.Time, reminded, downgraded int) {
+		t.Helper()
+		result, err := s.RunDunning(at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Reminded) != reminded || len(result.Downgraded) != downgraded {
+			t.Errorf("expected dunning at %v to remind %d and downgrade %d, got %d and %d", at, reminded, downgraded, len(result.Reminded), len(result.Downgraded))
+		}
+	}
+
+	expectDunning(time.Now(), 1, 0)
+	expectDunning(time.Now(), 0, 0)
+	// the second reminder was missed, only the third is sent
+	expectDunning(pastDue.Add(150*time.Hour), 1, 0)
+	expectDunning(pastDue.Add(160*time.Hour), 0, 0)
+
+	sendOutbox(t, s)
+	emails, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(emails) != 2 {
+		t.Errorf("expected 2 reminder emails, got %d %v", len(emails), err)
+	}
+
+	expectDunning(graceEndsAt, 0, 1)
+	expectDunning(graceEndsAt.Add(time.Hour), 0, 0)
+
+	user, err = s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.IsPaidUser || user.PaymentGraceEndsAt != nil {
+		t.Errorf("expected the user to be on the free plan once the grace period ended, got %+v", user)
+	}
+}
+
+func TestPaymentEndsTheGracePeriod(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	now := time.Now()
+
+	receiveTestEvent(t, s, "evt_1", "customer.subscription.updated", now.Add(-time.Minute), newTestSubscription("sub_1", "cus_1", user.ID, "past_due"))
+	receiveTestEvent(t, s, "evt_2", "customer.subscription.updated", now, newTestSubscription("sub_1", "cus_1", user.ID, "active"))
+
+	user, err := s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsPaidUser || user.PaymentGraceEndsAt != nil {
+		t.Errorf("expected the paid subscription to end the grace period, got %+v", user)
+	}
+
+	subscription, err := s.GetCurrentSubscription(user.ID)
+	if err != nil || subscription.Status != models.SubscriptionActive || subscription.GraceEndsAt != nil {
+		t.Errorf("expected an active subscription without a grace period, got %+v %v", subscription, err)
+	}
+}
+
+func TestDunningCarriesOnPastABadSubscription(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	now := time.Now().Truncate(time.Second)
+
+	// the user of the oldest subscription is gone, so its reminder fails
+	orphan := models.NewSubscription("sub_0", "ghost", "cus_0", "price_test", models.SubscriptionPastDue, now.Add(time.Hour), false)
+	orphan.CreatedAt = now.Add(-2 * time.Hour)
+	pastDueSince, graceEndsAt := now.Add(-time.Hour), now.Add(s.config.Dunning.GracePeriod)
+	orphan.PastDueSince, orphan.GraceEndsAt = &pastDueSince, &graceEndsAt
+	if err := s.repo.SaveSubscription(&orphan); err != nil {
+		t.Fatal(err)
+	}
+
+	receiveTestEvent(t, s, "evt_1", "customer.subscription.updated", now.Add(-time.Minute), newTestSubscription("sub_1", "cus_1", user.ID, "past_due"))
+
+	result, err := s.RunDunning(now)
+	if err == nil || len(result.Errors) != 1 {
+		t.Fatalf("expected the orphaned subscription to fail, got %v", err)
+	}
+	if len(result.Reminded) != 1 || result.Reminded[0].ID != "sub_1" {
+		t.Errorf("expected the other subscription to still be reminded, got %+v", result.Reminded)
+	}
+}
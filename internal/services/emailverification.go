@@ -0,0 +1,132 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"go-todo/internal/mailer"
+	"go-todo/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailVerificationTokenTTL is how long a verification link works for.
+const EmailVerificationTokenTTL = 48 * time.Hour
+
+// EmailVerificationResendInterval is how long a user has to wait before
+// another verification email is sent.
+const EmailVerificationResendInterval = 5 * time.Minute
+
+const emailVerificationEmailBody = `Hi %s,
+
+Please confirm this is your email address by opening the link below:
+
+%s
+
+The link expires in %d hours. If you did not sign up for go-todo you can
+ignore this email.
+`
+
+// verification tokens are signed rather than stored. They name the email
+// being verified so changing address makes earlier links useless.
+func signEmailVerification(key []byte, userID, email string, expiresAt time.Time) string {
+	payload := strings.Join([]string{userID, email, strconv.FormatInt(expiresAt.Unix(), 10)}, "\n")
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(emailVerificationMAC(key, encoded))
+}
+
+func emailVerificationMAC(key []byte, encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("email-verification\n" + encodedPayload))
+	return mac.Sum(nil)
+}
+
+// parseEmailVerification returns the user id and email a token was signed
+// for, ok is false when it is malformed, tampered with or expired at now.
+func parseEmailVerification(key []byte, token string, now time.Time) (userID, email string, ok bool) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", "", false
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, emailVerificationMAC(key, encoded)) {
+		return "", "", false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+
+	parts := strings.Split(string(payload), "\n")
+	if len(parts) != 3 {
+		return "", "", false
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// SendVerificationEmail emails the user a link to verify their address,
+// at most once every EmailVerificationResendInterval. key signs the link.
+func (s *Service) SendVerificationEmail(user *models.User, baseURL string, key []byte) (clientError, error) {
+	if user.EmailIsVerified() {
+		return NewClientError("Your email address is already verified", http.StatusBadRequest), nil
+	}
+
+	now := time.Now()
+	claimed, err := s.repo.ClaimEmailVerificationSend(user.ID, now, now.Add(-EmailVerificationResendInterval))
+	if err != nil {
+		return nil, fmt.Errorf("Could not record verification email. %w", err)
+	}
+
+	if !claimed {
+		return NewClientError("We sent you an email recently, please wait a few minutes before asking for another", http.StatusTooManyRequests), nil
+	}
+
+	token := signEmailVerification(key, user.ID, user.Email, now.Add(EmailVerificationTokenTTL))
+	link := strings.TrimSuffix(baseURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+
+	err = s.queueEmail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your go-todo email address",
+		Body:    fmt.Sprintf(emailVerificationEmailBody, user.Name, link, int(EmailVerificationTokenTTL.Hours())),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not queue verification email. %w", err)
+	}
+
+	return nil, nil
+}
+
+// VerifyEmail marks the address a verification link was sent to as
+// verified and returns the user's id.
+func (s *Service) VerifyEmail(token string, key []byte) (string, clientError, error) {
+	invalidLink := NewClientError("This verification link is invalid or has expired", http.StatusBadRequest)
+
+	userID, email, ok := parseEmailVerification(key, token, time.Now())
+	if !ok {
+		return "", invalidLink, nil
+	}
+
+	verified, err := s.repo.MarkEmailVerified(userID, email, time.Now())
+	if err != nil {
+		return "", nil, fmt.Errorf("Could not verify email. %w", err)
+	}
+
+	// the user changed their email or was deleted after the link was sent
+	if !verified {
+		return "", invalidLink, nil
+	}
+
+	return userID, nil, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"go-todo/internal/mailer"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var verificationTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_.%-]+)`)
+
+func TestEmailVerification(t *testing.T) {
+	s := newTestService(t)
+	dir := t.TempDir()
+	s.mailer = mailer.NewFileMailer(dir)
+	key := []byte("secret")
+	user := newTestUser(t, s, "new", false)
+
+	clientError, err := s.SendVerificationEmail(user, "http://localhost", key)
+	if err != nil || clientError != nil {
+		t.Fatalf("expected verification email to be sent, got %v %v", clientError, err)
+	}
+
+	clientError, err = s.SendVerificationEmail(user, "http://localhost", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil || clientError.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected resending straight away to be throttled, got %v", clientError)
+	}
+
+	sendOutbox(t, s)
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one email, got %d %v", len(files), err)
+	}
+	body, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := verificationTokenPattern.FindSubmatch(body)
+	if match == nil {
+		t.Fatalf("no verification link in email:\n%s", body)
+	}
+	token, err := url.QueryUnescape(string(match[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, clientError, _ := s.VerifyEmail(token, []byte("other secret")); clientError == nil {
+		t.Error("expected a token signed with another key to be rejected")
+	}
+
+	userID, clientError, err := s.VerifyEmail(token, key)
+	if err != nil || clientError != nil {
+		t.Fatalf("expected email to be verified, got %v %v", clientError, err)
+	}
+	if userID != user.ID {
+		t.Errorf("expected user %s, got %s", user.ID, userID)
+	}
+
+	loaded, err := s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.EmailIsVerified() {
+		t.Error("expected the user's email to be verified")
+	}
+}
+
+func TestEmailVerificationTokenIsTiedToEmail(t *testing.T) {
+	s := newTestService(t)
+	key := []byte("secret")
+	user := newTestUser(t, s, "mover", false)
+
+	expired := signEmailVerification(key, user.ID, user.Email, time.Now().Add(-time.Minute))
+	if _, clientError, _ := s.VerifyEmail(expired, key); clientError == nil {
+		t.Error("expected an expired token to be rejected")
+	}
+
+	token := signEmailVerification(key, user.ID, user.Email, time.Now().Add(time.Hour))
+
+	user.Email = "moved@email.com"
+	if err := s.repo.UpdateUser(*user); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, clientError, _ := s.VerifyEmail(token, key); clientError == nil {
+		t.Error("expected a token for the old email to be rejected")
+	}
+}
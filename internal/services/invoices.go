@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+	"time"
+
+	"github.com/stripe/stripe-go/v75"
+)
+
+// invoiceStatuses maps the invoice events that are recorded onto the
+// status they leave the invoice in.
+var invoiceStatuses = map[string]string{
+	"invoice.paid":           models.InvoicePaid,
+	"invoice.payment_failed": models.InvoicePaymentFailed,
+}
+
+// applyInvoiceEvent records the invoice an invoice.paid or
+// invoice.payment_failed event is about. A paid invoice stays paid, and
+// events older than the last one applied are ignored.
+func (s *Service) applyInvoiceEvent(event *models.StripeEvent) error {
+	var stripeInvoice stripe.Invoice
+	err := json.Unmarshal([]byte(event.Payload), &stripeInvoice)
+	if err != nil {
+		return fmt.Errorf("Failed to parse %s webhook, %w", event.Type, err)
+	}
+
+	existing, err := s.repo.GetInvoice(stripeInvoice.ID)
+	if err != nil {
+		return fmt.Errorf("Could not get invoice. %w", err)
+	}
+	if existing != nil && (existing.IsPaid() || event.CreatedAt.Before(existing.LastEventAt)) {
+		return nil
+	}
+
+	if stripeInvoice.Customer == nil || stripeInvoice.Customer.ID == "" {
+		return fmt.Errorf("invoice %s has no customer", stripeInvoice.ID)
+	}
+
+	var subscriptionID string
+	if stripeInvoice.Subscription != nil {
+		subscriptionID = stripeInvoice.Subscription.ID
+	}
+
+	userID, err := s.invoiceUserID(subscriptionID, stripeInvoice.Customer.ID)
+	if err != nil {
+		return err
+	}
+	if userID == "" {
+		return fmt.Errorf("no user has the subscription (%s) or stripe ID (%s) of invoice %s", subscriptionID, stripeInvoice.Customer.ID, stripeInvoice.ID)
+	}
+
+	invoice := models.Invoice{
+		ID:             stripeInvoice.ID,
+		UserID:         userID,
+		CustomerID:     stripeInvoice.Customer.ID,
+		SubscriptionID: subscriptionID,
+		Number:         stripeInvoice.Number,
+		Status:         invoiceStatuses[event.Type],
+		Amount:         stripeInvoice.AmountDue,
+		Currency:       string(stripeInvoice.Currency),
+		PeriodStart:    time.Unix(stripeInvoice.PeriodStart, 0),
+		PeriodEnd:      time.Unix(stripeInvoice.PeriodEnd, 0),
+		CreatedAt:      time.Unix(stripeInvoice.Created, 0),
+		LastEventAt:    event.CreatedAt,
+	}
+
+	// an invoice's own period is the one before it was created, the period
+	// it bills for is on its subscription line
+	if stripeInvoice.Lines != nil && len(stripeInvoice.Lines.Data) > 0 {
+		line := stripeInvoice.Lines.Data[0]
+		if line.Period != nil {
+			invoice.PeriodStart = time.Unix(line.Period.Start, 0)
+			invoice.PeriodEnd = time.Unix(line.Period.End, 0)
+		}
+		if line.Price != nil {
+			invoice.PriceID = line.Price.ID
+		}
+	}
+
+	if invoice.IsPaid() {
+		paidAt := event.CreatedAt
+		if stripeInvoice.StatusTransitions != nil && stripeInvoice.StatusTransitions.PaidAt != 0 {
+			paidAt = time.Unix(stripeInvoice.StatusTransitions.PaidAt, 0)
+		}
+		invoice.PaidAt = &paidAt
+	}
+
+	err = s.repo.SaveInvoice(&invoice)
+	if err != nil {
+		return fmt.Errorf("Could not save invoice. %w", err)
+	}
+	return nil
+}
+
+// invoiceUserID returns the id of the user an invoice belongs to, the
+// owner of its subscription or else the user with its customer, or "" when
+// there is none.
+func (s *Service) invoiceUserID(subscriptionID, customerID string) (string, error) {
+	if subscriptionID != "" {
+		subscription, err := s.repo.GetSubscription(subscriptionID)
+		if err != nil {
+			return "", fmt.Errorf("Could not get subscription. %w", err)
+		}
+		if subscription != nil {
+			return subscription.UserID, nil
+		}
+	}
+
+	user, err := s.GetUserByStripeID(customerID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", nil
+	}
+	return user.ID, nil
+}
+
+// GetInvoices returns the user's invoices, newest first.
+func (s *Service) GetInvoices(userID string) ([]*models.Invoice, error) {
+	invoices, err := s.repo.GetInvoicesByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get invoices. %w", err)
+	}
+	return invoices, nil
+}
+
+// GetUserInvoice returns one of the user's invoices.
+func (s *Service) GetUserInvoice(user *models.User, invoiceID string) (*models.Invoice, clientError, error) {
+	invoice, err := s.repo.GetInvoice(invoiceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get invoice. %w", err)
+	}
+
+	if invoice == nil || invoice.UserID != user.ID {
+		return nil, NewClientError("The invoice you requested does not exist", http.StatusNotFound), nil
+	}
+	return invoice, nil, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"go-todo/internal/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestInvoice(id, customerID, subscriptionID string, amount int64) map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"id":           id,
+		"customer":     customerID,
+		"subscription": subscriptionID,
+		"number":       "INV-" + id,
+		"amount_due":   amount,
+		"currency":     "usd",
+		"created":      now.Unix(),
+		"lines": map[string]any{"data": []map[string]any{{
+			"period": map[string]int64{"start": now.Unix(), "end": now.AddDate(0, 1, 0).Unix()},
+			"price":  map[string]string{"id": "price_test"},
+		}}},
+	}
+}
+
+func TestInvoiceEventsAreKeptForBillingHistory(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	other := newTestUser(t, s, "other", false)
+	now := time.Now()
+
+	receiveTestEvent(t, s, "evt_1", "customer.subscription.created", now.Add(-time.Hour), newTestSubscription("sub_1", "cus_1", user.ID, "active"))
+	receiveTestEvent(t, s, "evt_2", "invoice.payment_failed", now.Add(-30*time.Minute), newTestInvoice("in_1", "cus_1", "sub_1", 500))
+	receiveTestEvent(t, s, "evt_3", "invoice.paid", now.Add(-20*time.Minute), newTestInvoice("in_1", "cus_1", "sub_1", 500))
+	// a failure delivered late does not undo the payment
+	receiveTestEvent(t, s, "evt_4", "invoice.payment_failed", now.Add(-10*time.Minute), newTestInvoice("in_1", "cus_1", "sub_1", 500))
+
+	invoices, err := s.GetInvoices(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invoices) != 1 || invoices[0].Status != models.InvoicePaid || invoices[0].PaidAt == nil || invoices[0].PriceID != "price_test" {
+		t.Fatalf("expected one paid invoice, got %+v", invoices)
+	}
+	if amount := models.FormatAmount(invoices[0].Amount, invoices[0].Currency); amount != "$5.00" {
+		t.Errorf("expected the invoice to be for $5.00, got %s", amount)
+	}
+
+	_, clientError, err := s.GetUserInvoice(other, "in_1")
+	if err != nil || clientError == nil || clientError.Code != http.StatusNotFound {
+		t.Errorf("expected other users not to see the invoice, got %v %v", clientError, err)
+	}
+
+	// invoices for customers no user has are retried
+	event := receiveTestEvent(t, s, "evt_5", "invoice.paid", now, newTestInvoice("in_2", "cus_unknown", "", 500))
+	if event.Status != models.StripeEventPending || event.LastError == "" {
+		t.Errorf("expected an invoice without a user to be retried, got %s", event.Status)
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"html"
+	"net/http"
+	"strings"
+)
+
+const DefaultListName = "My Todos"
+const maxListNameLength = 50
+
+func validateListName(name string) (string, clientError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", NewClientError("List name cannot be empty", http.StatusBadRequest)
+	}
+	if len(name) > maxListNameLength {
+		return "", NewClientError(fmt.Sprintf("List name cannot be longer than %d characters", maxListNameLength), http.StatusBadRequest)
+	}
+	return html.EscapeString(name), nil
+}
+
+// GetUserLists returns the user's lists, creating the default list the
+// first time a user without one asks for them.
+func (s *Service) GetUserLists(userID string) ([]*models.List, error) {
+	lists, err := s.repo.GetListsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get lists for user. %w", err)
+	}
+
+	if len(lists) > 0 {
+		return lists, nil
+	}
+
+	list := models.NewList(userID, DefaultListName)
+	list.ID, err = s.repo.CreateList(&list)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create default list for user. %w", err)
+	}
+
+	return []*models.List{&list}, nil
+}
+
+func (s *Service) GetListByID(listID int, userID string) (*models.List, clientError, error) {
+	list, err := s.repo.GetListByID(listID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get list by ID. %w", err)
+	}
+
+	if list == nil {
+		return nil, NewClientError("The list you requested does not exist", http.StatusNotFound), nil
+	}
+
+	if list.UserID != userID {
+		return nil, NewClientError("User not authorized", http.StatusUnauthorized), nil
+	}
+
+	return list, nil, nil
+}
+
+func (s *Service) CreateList(user *models.User, name string) (*models.List, clientError, error) {
+	name, clientError := validateListName(name)
+	if clientError != nil {
+		return nil, clientError, nil
+	}
+
+	canCreateNewList, err := s.UserHasEntitlement(user, models.EntitlementCreateList)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !canCreateNewList {
+		return nil, NewClientError("You've reached your list limit. Upgrade to create more lists.", http.StatusForbidden), nil
+	}
+
+	list := models.NewList(user.ID, name)
+	list.ID, err = s.repo.CreateList(&list)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not create list. %w", err)
+	}
+
+	return &list, nil, nil
+}
+
+func (s *Service) RenameList(userID string, listID int, name string) (*models.List, clientError, error) {
+	list, clientError, err := s.GetListByID(listID, userID)
+	if err != nil || clientError != nil {
+		return nil, clientError, err
+	}
+
+	name, clientError = validateListName(name)
+	if clientError != nil {
+		return nil, clientError, nil
+	}
+
+	list.Name = name
+
+	err = s.repo.UpdateList(*list)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not rename list. %w", err)
+	}
+
+	return list, nil, nil
+}
+
+// DeleteList deletes a list and its todos. Users always keep at least one
+// list.
+func (s *Service) DeleteList(userID string, listID int) (clientError, error) {
+	_, clientError, err := s.GetListByID(listID, userID)
+	if err != nil || clientError != nil {
+		return clientError, err
+	}
+
+	count, err := s.repo.CountListsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not count lists for user. %w", err)
+	}
+
+	if count <= 1 {
+		return NewClientError("You cannot delete your only list", http.StatusBadRequest), nil
+	}
+
+	err = s.repo.DeleteList(listID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not delete list. %w", err)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import "testing"
+
+func TestFreeUserListLimit(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "free", false)
+
+	lists, err := s.GetUserLists(user.ID)
+	if err != nil || len(lists) != 1 || lists[0].Name != DefaultListName {
+		t.Fatalf("expected a default list to be created, got %v (%v)", lists, err)
+	}
+
+	for i := 1; i < s.Plans().Free().Entitlements.MaxLists; i++ {
+		_, clientError, err := s.CreateList(user, "list")
+		if err != nil || clientError != nil {
+			t.Fatalf("expected list %d to be created, got %v %v", i, clientError, err)
+		}
+	}
+
+	_, clientError, err := s.CreateList(user, "one too many")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil {
+		t.Error("expected free user to be stopped at the list limit")
+	}
+}
+
+func TestCannotDeleteOnlyList(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "paid", true)
+
+	lists, err := s.GetUserLists(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientError, err := s.DeleteList(user.ID, lists[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil {
+		t.Error("expected deleting the only list to be rejected")
+	}
+
+	other := newTestUser(t, s, "other", true)
+	_, clientError, err = s.RenameList(other.ID, lists[0].ID, "mine now")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil {
+		t.Error("expected renaming another user's list to be rejected")
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// failed logins allowed before an email address or ip is locked out
+const (
+	LoginFailuresPerEmail = 5
+	LoginFailuresPerIP    = 20
+)
+
+// password reset requests allowed before an email address or ip is locked
+// out of asking for more, so the form cannot flood someone's inbox
+const (
+	PasswordResetsPerEmail = 3
+	PasswordResetsPerIP    = 20
+)
+
+// the first lockout lasts loginLockoutBase and each further failure
+// doubles it, up to loginLockoutMax. Failures are forgotten after a quiet
+// loginFailureWindow.
+const (
+	loginLockoutBase   = time.Minute
+	loginLockoutMax    = time.Hour
+	loginFailureWindow = 24 * time.Hour
+)
+
+// the same message whether or not an account exists for the email
+const (
+	loginFailedMessage = "Incorrect email or password."
+	loginLockedMessage = "Too many failed login attempts, please try again later."
+)
+
+// loginLockout is how long to lock logins for after failures failures,
+// zero while under threshold.
+func loginLockout(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	lockout := loginLockoutBase
+	for i := threshold; i < failures && lockout < loginLockoutMax; i++ {
+		lockout *= 2
+	}
+	return min(lockout, loginLockoutMax)
+}
+
+var (
+	dummyPasswordHashOnce sync.Once
+	dummyPasswordHash     []byte
+)
+
+// logins for unknown emails still check a password so they take as long as
+// logins for real accounts
+func compareDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), passwordHashCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
+type loginThrottleKey struct {
+	kind      string
+	key       string
+	threshold int
+}
+
+func loginThrottleKeys(email, ip string) []loginThrottleKey {
+	keys := []loginThrottleKey{{models.LoginThrottleEmail, strings.ToLower(email), LoginFailuresPerEmail}}
+	if ip != "" {
+		keys = append(keys, loginThrottleKey{models.LoginThrottleIP, ip, LoginFailuresPerIP})
+	}
+	return keys
+}
+
+func passwordResetThrottleKeys(email, ip string) []loginThrottleKey {
+	keys := []loginThrottleKey{{models.LoginThrottleResetEmail, strings.ToLower(strings.TrimSpace(email)), PasswordResetsPerEmail}}
+	if ip != "" {
+		keys = append(keys, loginThrottleKey{models.LoginThrottleResetIP, ip, PasswordResetsPerIP})
+	}
+	return keys
+}
+
+// loginRetryAfter returns how long logins for email from ip are locked
+// for, zero when they are allowed.
+func (s *Service) loginRetryAfter(email, ip string, now time.Time) (time.Duration, error) {
+	return s.throttleRetryAfter(loginThrottleKeys(email, ip), now)
+}
+
+// throttleRetryAfter returns how long the longest lockout of keys has
+// left, zero when none are locked.
+func (s *Service) throttleRetryAfter(keys []loginThrottleKey, now time.Time) (time.Duration, error) {
+	var retryAfter time.Duration
+	for _, k := range keys {
+		throttle, err := s.repo.GetLoginThrottle(k.kind, k.key)
+		if err != nil {
+			return 0, fmt.Errorf("Could not get login throttle. %w", err)
+		}
+		if throttle.IsLocked(now) {
+			retryAfter = max(retryAfter, throttle.LockedUntil.Sub(now))
+		}
+	}
+	return retryAfter, nil
+}
+
+// recordLoginFailure counts a failed login for email and ip, locking them
+// out when there have been too many. It returns how long logins are now
+// locked for.
+func (s *Service) recordLoginFailure(email, ip string, now time.Time) (time.Duration, error) {
+	return s.recordThrottleFailure(loginThrottleKeys(email, ip), now)
+}
+
+// recordThrottleFailure counts a failure against each of keys, locking
+// those that have had too many. It returns how long the longest lockout
+// is.
+func (s *Service) recordThrottleFailure(keys []loginThrottleKey, now time.Time) (time.Duration, error) {
+	var lockedFor time.Duration
+	for _, k := range keys {
+		failures, err := s.repo.RecordLoginFailure(k.kind, k.key, now, now.Add(-loginFailureWindow))
+		if err != nil {
+			return 0, fmt.Errorf("Could not record login failure. %w", err)
+		}
+
+		lockout := loginLockout(failures, k.threshold)
+		if lockout == 0 {
+			continue
+		}
+
+		err = s.repo.LockLogin(k.kind, k.key, now.Add(lockout))
+		if err != nil {
+			return 0, fmt.Errorf("Could not lock login. %w", err)
+		}
+		lockedFor = max(lockedFor, lockout)
+	}
+	return lockedFor, nil
+}
+
+// VerifyTwoFactorLogin checks the second factor of a login. Wrong codes
+// are counted against the user so a known password does not buy unlimited
+// guesses. It returns how long the user is locked out for when the code is
+// refused because of too many failures. The email is only cleared once
+// both factors pass.
+func (s *Service) VerifyTwoFactorLogin(userID, code string) (bool, time.Duration, error) {
+	now := time.Now()
+	throttle, err := s.repo.GetLoginThrottle(models.LoginThrottleUser, userID)
+	if err != nil {
+		return false, 0, fmt.Errorf("Could not get login throttle. %w", err)
+	}
+
+	if throttle.IsLocked(now) {
+		return false, throttle.LockedUntil.Sub(now), nil
+	}
+
+	ok, err := s.VerifyTwoFactor(userID, code)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if !ok {
+		failures, err := s.repo.RecordLoginFailure(models.LoginThrottleUser, userID, now, now.Add(-loginFailureWindow))
+		if err != nil {
+			return false, 0, fmt.Errorf("Could not record login failure. %w", err)
+		}
+
+		lockout := loginLockout(failures, LoginFailuresPerEmail)
+		if lockout == 0 {
+			return false, 0, nil
+		}
+
+		err = s.repo.LockLogin(models.LoginThrottleUser, userID, now.Add(lockout))
+		if err != nil {
+			return false, 0, fmt.Errorf("Could not lock login. %w", err)
+		}
+		return false, lockout, nil
+	}
+
+	err = s.repo.ClearLoginThrottle(models.LoginThrottleUser, userID)
+	if err != nil {
+		return false, 0, fmt.Errorf("Could not clear login throttle. %w", err)
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return false, 0, fmt.Errorf("Could not get user by id. %w", err)
+	}
+
+	if user != nil {
+		err = s.repo.ClearLoginThrottle(models.LoginThrottleEmail, strings.ToLower(user.Email))
+		if err != nil {
+			return false, 0, fmt.Errorf("Could not clear login throttle. %w", err)
+		}
+	}
+	return true, 0, nil
+}
+
+// GetLoginLockouts returns the email addresses and ips currently locked
+// out.
+func (s *Service) GetLoginLockouts() ([]*models.LoginThrottle, error) {
+	throttles, err := s.repo.GetLockedLoginThrottles(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("Could not get login lockouts. %w", err)
+	}
+	return throttles, nil
+}
+
+// UnlockLogin lifts a lockout and forgets its failures.
+func (s *Service) UnlockLogin(throttleID int) (clientError, error) {
+	deleted, err := s.repo.DeleteLoginThrottle(throttleID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unlock login. %w", err)
+	}
+
+	if !deleted {
+		return NewClientError("That lockout does not exist", http.StatusNotFound), nil
+	}
+	return nil, nil
+}
+
+// UnlockEmail lifts any lockout on logins for email.
+func (s *Service) UnlockEmail(email string) error {
+	err := s.repo.ClearLoginThrottle(models.LoginThrottleEmail, strings.ToLower(email))
+	if err != nil {
+		return fmt.Errorf("Could not unlock email. %w", err)
+	}
+	return nil
+}
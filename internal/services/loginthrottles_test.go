@@ -0,0 +1,175 @@
+package services
+
+import (
+	"go-todo/internal/models"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		lockout  time.Duration
+	}{
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{8, 8 * time.Minute},
+		{20, loginLockoutMax},
+	}
+	for _, test := range tests {
+		if lockout := loginLockout(test.failures, 5); lockout != test.lockout {
+			t.Errorf("after %d failures expected %s, got %s", test.failures, test.lockout, lockout)
+		}
+	}
+}
+
+func TestLoginLocksOutAfterRepeatedFailures(t *testing.T) {
+	s := newTestService(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.NewUser("guessed", "guessed", "Guessed@email.com", string(hash), false, "")
+	if err := s.repo.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < LoginFailuresPerEmail; i++ {
+		_, loginErrors, err := s.Login(user.Email, "wrong", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loginErrors == nil || loginErrors.LockedFor != 0 || loginErrors.PasswordErrors[0] != loginFailedMessage {
+			t.Fatalf("attempt %d: expected a plain failure, got %+v", i, loginErrors)
+		}
+	}
+
+	_, loginErrors, err := s.Login(user.Email, "wrong", "10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loginErrors == nil || loginErrors.LockedFor != loginLockoutBase {
+		t.Fatalf("expected the email to be locked out, got %+v", loginErrors)
+	}
+
+	// the lockout is per email, whatever its case, and holds for the right
+	// password too
+	_, loginErrors, err = s.Login("guessed@email.com", "correct", "10.0.0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loginErrors == nil || loginErrors.LockedFor == 0 {
+		t.Fatalf("expected the login to be refused while locked out, got %+v", loginErrors)
+	}
+
+	if err := s.UnlockEmail(user.Email); err != nil {
+		t.Fatal(err)
+	}
+
+	loggedIn, loginErrors, err := s.Login(user.Email, "correct", "10.0.0.1")
+	if err != nil || loginErrors != nil {
+		t.Fatalf("expected login after unlocking, got %+v %v", loginErrors, err)
+	}
+	if loggedIn.ID != user.ID {
+		t.Errorf("expected user %s, got %s", user.ID, loggedIn.ID)
+	}
+}
+
+func TestLoginFailsTheSameWayForUnknownEmails(t *testing.T) {
+	s := newTestService(t)
+
+	_, loginErrors, err := s.Login("nobody@email.com", "password", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loginErrors == nil || len(loginErrors.EmailErrors) != 0 || loginErrors.PasswordErrors[0] != loginFailedMessage {
+		t.Fatalf("expected the same failure as a wrong password, got %+v", loginErrors)
+	}
+
+	throttle, err := s.repo.GetLoginThrottle(models.LoginThrottleEmail, "nobody@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if throttle == nil || throttle.Failures != 1 {
+		t.Errorf("expected the failure to be counted, got %+v", throttle)
+	}
+}
+
+func TestWrongTwoFactorCodesLockOutTheUser(t *testing.T) {
+	s := newTestService(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.NewUser("phished", "phished", "phished@email.com", string(hash), false, "")
+	if err := s.repo.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	enrollment, _, err := s.BeginTwoFactorEnrollment(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, clientError, err := s.EnableTwoFactor(&user, currentTOTPCode(t, enrollment.Secret, time.Now().Add(-totpPeriod)))
+	if err != nil || clientError != nil {
+		t.Fatalf("expected two factor to be enabled, got %v %v", clientError, err)
+	}
+
+	if _, _, err := s.Login(user.Email, "wrong", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a fresh password login each time does not reset the count
+	for i := 1; i <= LoginFailuresPerEmail; i++ {
+		_, loginErrors, err := s.Login(user.Email, "correct", "10.0.0.1")
+		if err != nil || loginErrors != nil {
+			t.Fatalf("attempt %d: expected the password to pass, got %+v %v", i, loginErrors, err)
+		}
+
+		ok, lockedFor, err := s.VerifyTwoFactorLogin(user.ID, "000000")
+		if err != nil || ok {
+			t.Fatalf("attempt %d: expected a wrong code to fail, got %v %v", i, ok, err)
+		}
+		if i < LoginFailuresPerEmail && lockedFor != 0 {
+			t.Fatalf("attempt %d: expected no lockout yet, got %s", i, lockedFor)
+		}
+		if i == LoginFailuresPerEmail && lockedFor != loginLockoutBase {
+			t.Fatalf("expected the user to be locked out, got %s", lockedFor)
+		}
+	}
+
+	throttle, err := s.repo.GetLoginThrottle(models.LoginThrottleEmail, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if throttle == nil || throttle.Failures != 1 {
+		t.Errorf("expected the password step to leave the email throttle alone, got %+v", throttle)
+	}
+
+	ok, lockedFor, err := s.VerifyTwoFactorLogin(user.ID, currentTOTPCode(t, enrollment.Secret, time.Now()))
+	if err != nil || ok || lockedFor == 0 {
+		t.Fatalf("expected the right code to be refused while locked out, got %v %s %v", ok, lockedFor, err)
+	}
+
+	if err := s.repo.ClearLoginThrottle(models.LoginThrottleUser, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, _, err = s.VerifyTwoFactorLogin(user.ID, currentTOTPCode(t, enrollment.Secret, time.Now()))
+	if err != nil || !ok {
+		t.Fatalf("expected the right code to pass, got %v %v", ok, err)
+	}
+
+	throttle, err = s.repo.GetLoginThrottle(models.LoginThrottleEmail, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if throttle != nil {
+		t.Errorf("expected a full login to clear the email throttle, got %d failures", throttle.Failures)
+	}
+}
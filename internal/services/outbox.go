@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/logger"
+	"go-todo/internal/mailer"
+	"go-todo/internal/models"
+	"time"
+)
+
+// how long an attempt at an email has before it may be taken again, in
+// case the process dies part way through
+const outboxEmailClaim = 5 * time.Minute
+
+// how many due emails each outbox pass sends
+const outboxBatch = 50
+
+// queueEmail saves msg to the outbox, it is sent by the outbox worker.
+func (s *Service) queueEmail(msg mailer.Message) error {
+	email := models.NewOutboxEmail(msg.To, msg.Subject, msg.Body, time.Now().UTC())
+	_, err := s.repo.SaveOutboxEmail(&email)
+	if err != nil {
+		return fmt.Errorf("Could not save outbox email. %w", err)
+	}
+	return nil
+}
+
+// SendOutboxEmail makes one attempt at sending an email that is due and
+// returns it as it is afterwards. It returns nil when the email is not
+// due. A send that fails is recorded on the email and retried later, it is
+// not an error.
+func (s *Service) SendOutboxEmail(id int) (*models.OutboxEmail, error) {
+	now := time.Now().UTC()
+
+	claimed, err := s.repo.ClaimOutboxEmail(id, now, now.Add(outboxEmailClaim))
+	if err != nil {
+		return nil, fmt.Errorf("Could not claim outbox email. %w", err)
+	}
+	if !claimed {
+		return nil, nil
+	}
+
+	email, err := s.repo.GetOutboxEmail(id)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get outbox email. %w", err)
+	}
+
+	sendErr := s.mailer.Send(mailer.Message{To: email.To, Subject: email.Subject, Body: email.Body})
+	if sendErr == nil {
+		err = s.repo.MarkOutboxEmailSent(id, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("Could not mark outbox email sent. %w", err)
+		}
+	} else {
+		var nextAttemptAt *time.Time
+		if email.Attempts < s.config.Mail.OutboxMaxAttempts {
+			retryAt := time.Now().UTC().Add(retryDelay(email.Attempts))
+			nextAttemptAt = &retryAt
+		}
+		err = s.repo.MarkOutboxEmailFailed(id, sendErr.Error(), nextAttemptAt)
+		if err != nil {
+			return nil, fmt.Errorf("Could not mark outbox email failed. %w", err)
+		}
+	}
+
+	email, err = s.repo.GetOutboxEmail(id)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get outbox email. %w", err)
+	}
+	return email, nil
+}
+
+// SendDueEmails makes an attempt at every email in the outbox that is due
+// and returns the emails attempted.
+func (s *Service) SendDueEmails() ([]*models.OutboxEmail, error) {
+	due, err := s.repo.GetDueOutboxEmails(time.Now(), outboxBatch)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get due outbox emails. %w", err)
+	}
+
+	attempted := []*models.OutboxEmail{}
+	for _, email := range due {
+		email, err = s.SendOutboxEmail(email.ID)
+		if err != nil {
+			return attempted, err
+		}
+		if email != nil {
+			attempted = append(attempted, email)
+		}
+	}
+	return attempted, nil
+}
+
+// StartOutbox sends the email in the outbox every interval until the
+// returned stop is called.
+func (s *Service) StartOutbox(interval time.Duration, logr *logger.Logger) (stop func()) {
+	return runEvery(interval, func() {
+		emails, err := s.SendDueEmails()
+		if err != nil {
+			logr.Error(fmt.Sprintf("Could not send outbox emails. %s", err))
+		}
+		for _, email := range emails {
+			if email.Status != models.OutboxEmailSent {
+				logr.Warning(fmt.Sprintf("Email (%d) %q failed attempt %d, it is %s. %s", email.ID, email.Subject, email.Attempts, email.Status, email.LastError))
+			}
+		}
+	})
+}
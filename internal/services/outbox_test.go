@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"go-todo/internal/mailer"
+	"go-todo/internal/models"
+	"testing"
+	"time"
+)
+
+// sendOutbox sends every email that is due in the outbox.
+func sendOutbox(t *testing.T, s *Service) {
+	t.Helper()
+	if _, err := s.SendDueEmails(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(msg mailer.Message) error {
+	return errors.New("smtp is down")
+}
+
+func TestOutboxRetriesFailedSends(t *testing.T) {
+	s := newTestService(t)
+	s.config.Mail.OutboxMaxAttempts = 2
+	s.mailer = failingMailer{}
+
+	if err := s.queueEmail(mailer.Message{To: "someone@email.com", Subject: "Hello", Body: "Hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	emails, err := s.SendDueEmails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].Status != models.OutboxEmailPending || emails[0].NextAttemptAt == nil || emails[0].LastError == "" {
+		t.Fatalf("expected a failed send to be retried later, got %+v", emails)
+	}
+	id := emails[0].ID
+
+	// the retry is not due yet
+	emails, err = s.SendDueEmails()
+	if err != nil || len(emails) != 0 {
+		t.Fatalf("expected nothing due, got %+v %v", emails, err)
+	}
+
+	// make the retry due now
+	now := time.Now()
+	if err := s.repo.MarkOutboxEmailFailed(id, "smtp is down", &now); err != nil {
+		t.Fatal(err)
+	}
+	email, err := s.SendOutboxEmail(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.Status != models.OutboxEmailFailed || email.NextAttemptAt != nil {
+		t.Errorf("expected the email to be given up on after its last attempt, got %+v", email)
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/mailer"
+	"go-todo/internal/models"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordResetTokenTTL is how long a password reset link works for.
+const PasswordResetTokenTTL = time.Hour
+
+const passwordResetEmailBody = `Hi %s,
+
+Someone asked to reset the password for your go-todo account. If it was
+you, choose a new password here:
+
+%s
+
+The link works once and expires in %d minutes. If you did not ask to reset
+your password you can ignore this email.
+`
+
+// RequestPasswordReset emails the user with email a link to reset their
+// password. Unknown emails are ignored so the form cannot be used to find
+// out who has an account. Requests are throttled per email and per ip, it
+// returns how long they are locked out for when refused.
+func (s *Service) RequestPasswordReset(email, baseURL, ip string) (time.Duration, error) {
+	now := time.Now()
+	keys := passwordResetThrottleKeys(email, ip)
+	retryAfter, err := s.throttleRetryAfter(keys, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if retryAfter > 0 {
+		return retryAfter, nil
+	}
+
+	// unknown emails count too, a lockout gives nothing away
+	_, err = s.recordThrottleFailure(keys, now)
+	if err != nil {
+		return 0, err
+	}
+
+	user, err := s.repo.GetUserByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return 0, fmt.Errorf("Could not get user by email. %w", err)
+	}
+
+	if user == nil {
+		return 0, nil
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return 0, fmt.Errorf("Could not generate password reset token. %w", err)
+	}
+
+	err = s.repo.CreatePasswordResetToken(user.ID, hashToken(token), now.Add(PasswordResetTokenTTL))
+	if err != nil {
+		return 0, fmt.Errorf("Could not create password reset token. %w", err)
+	}
+
+	link := strings.TrimSuffix(baseURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+
+	err = s.queueEmail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your go-todo password",
+		Body:    fmt.Sprintf(passwordResetEmailBody, user.Name, link, int(PasswordResetTokenTTL.Minutes())),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Could not queue password reset email. %w", err)
+	}
+
+	return 0, nil
+}
+
+// PasswordResetTokenIsValid reports whether token can still be used to
+// reset a password.
+func (s *Service) PasswordResetTokenIsValid(token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+
+	userID, err := s.repo.GetPasswordResetUserID(hashToken(token), time.Now())
+	if err != nil {
+		return false, fmt.Errorf("Could not get password reset token. %w", err)
+	}
+	return userID != "", nil
+}
+
+// ResetPassword redeems token and sets the password of the user it was
+// sent to, returning their id so their sessions can be ended.
+func (s *Service) ResetPassword(token, password, confirmPassword string) (string, *models.ResetPasswordClientErrors, error) {
+	clientErrors := models.ResetPasswordClientErrors{}
+
+	password = strings.TrimSpace(password)
+	confirmPassword = strings.TrimSpace(confirmPassword)
+
+	if password == "" {
+		clientErrors.PasswordErrors = append(clientErrors.PasswordErrors, "You must provide a password.")
+	} else if password != confirmPassword {
+		clientErrors.PasswordErrors = append(clientErrors.PasswordErrors, "The passwords you entered do not match.")
+	}
+
+	if len(clientErrors.PasswordErrors) > 0 {
+		return "", &clientErrors, nil
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return "", nil, fmt.Errorf("Could not hash password. %w", err)
+	}
+
+	userID, err := s.repo.ResetPassword(hashToken(token), string(hashedPassword), time.Now())
+	if err != nil {
+		return "", nil, fmt.Errorf("Could not reset password. %w", err)
+	}
+
+	if userID == "" {
+		clientErrors.TokenErrors = append(clientErrors.TokenErrors, "This password reset link has expired or was already used. Please request a new one.")
+		return "", &clientErrors, nil
+	}
+
+	return userID, nil, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"go-todo/internal/mailer"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var resetTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// sentResetToken reads the token from the only email in dir.
+func sentResetToken(t *testing.T, dir string) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected one email, got %d", len(files))
+	}
+	body, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := resetTokenPattern.FindSubmatch(body)
+	if match == nil {
+		t.Fatalf("no reset link in email:\n%s", body)
+	}
+	return string(match[1])
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestService(t)
+	dir := t.TempDir()
+	s.mailer = mailer.NewFileMailer(dir)
+	user := newTestUser(t, s, "forgetful", false)
+
+	if _, err := s.RequestPasswordReset("nobody@email.com", "http://localhost", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	sendOutbox(t, s)
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.eml")); len(files) != 0 {
+		t.Fatal("expected no email for an unknown address")
+	}
+
+	if _, err := s.RequestPasswordReset(user.Email, "http://localhost", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	sendOutbox(t, s)
+	token := sentResetToken(t, dir)
+
+	valid, err := s.PasswordResetTokenIsValid(token)
+	if err != nil || !valid {
+		t.Fatalf("expected token to be valid, got %v %v", valid, err)
+	}
+
+	_, clientErrors, err := s.ResetPassword(token, "new password", "different")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientErrors == nil || len(clientErrors.PasswordErrors) == 0 {
+		t.Fatal("expected mismatched passwords to be rejected")
+	}
+
+	userID, clientErrors, err := s.ResetPassword(token, "new password", "new password")
+	if err != nil || clientErrors != nil {
+		t.Fatalf("expected password to be reset, got %v %v", clientErrors, err)
+	}
+	if userID != user.ID {
+		t.Errorf("expected user %s, got %s", user.ID, userID)
+	}
+
+	loaded, err := s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(loaded.Password), []byte("new password")) != nil {
+		t.Error("expected the new password to be stored")
+	}
+
+	_, clientErrors, err = s.ResetPassword(token, "another password", "another password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientErrors == nil || len(clientErrors.TokenErrors) == 0 {
+		t.Error("expected a used token to be rejected")
+	}
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "slow", false)
+
+	token, err := generateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.repo.CreatePasswordResetToken(user.ID, hashToken(token), time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid, err := s.PasswordResetTokenIsValid(token)
+	if err != nil || valid {
+		t.Fatalf("expected expired token to be invalid, got %v %v", valid, err)
+	}
+}
+
+func TestPasswordResetRequestsAreThrottled(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "flooded", false)
+
+	for i := 1; i <= PasswordResetsPerEmail; i++ {
+		retryAfter, err := s.RequestPasswordReset(user.Email, "http://localhost", "10.0.0.1")
+		if err != nil || retryAfter != 0 {
+			t.Fatalf("request %d: expected it to be allowed, got %s %v", i, retryAfter, err)
+		}
+	}
+
+	// the lockout is per email, from any ip
+	retryAfter, err := s.RequestPasswordReset(strings.ToUpper(user.Email), "http://localhost", "10.0.0.2")
+	if err != nil || retryAfter == 0 {
+		t.Fatalf("expected the email to be locked out, got %s %v", retryAfter, err)
+	}
+
+	emails, err := s.SendDueEmails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != PasswordResetsPerEmail {
+		t.Errorf("expected %d emails, got %d", PasswordResetsPerEmail, len(emails))
+	}
+}
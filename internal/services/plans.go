@@ -0,0 +1,86 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+)
+
+// Plans returns the plan catalogue.
+func (s *Service) Plans() models.PlanCatalogue {
+	return s.config.Plans.Catalogue
+}
+
+// GetUserPlan returns the plan the user is on. Paid users are on the plan
+// of their subscription's price, or when no plan has it, e.g. because it
+// was bought before there was a catalogue, the plan of STRIPE_PRICE_ID.
+func (s *Service) GetUserPlan(userID string) (*models.Plan, error) {
+	plans := s.Plans()
+
+	isPaidUser, err := s.UserIsPaidUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isPaidUser {
+		return plans.Free(), nil
+	}
+
+	subscription, err := s.GetCurrentSubscription(userID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription != nil {
+		if plan := plans.ByPriceID(subscription.PriceID); plan != nil {
+			return plan, nil
+		}
+	}
+
+	if plan := plans.ByPriceID(s.config.Stripe.PriceID); plan != nil {
+		return plan, nil
+	}
+	if paid := plans.Paid(); len(paid) > 0 {
+		return &paid[0], nil
+	}
+	return plans.Free(), nil
+}
+
+// UserHasEntitlement reports whether the user's plan grants entitlement.
+// Creating todos and lists is checked against what the user already has,
+// archived todos do not count.
+func (s *Service) UserHasEntitlement(user *models.User, entitlement string) (bool, error) {
+	plan, err := s.GetUserPlan(user.ID)
+	if err != nil {
+		return false, err
+	}
+	entitlements := plan.Entitlements
+
+	switch entitlement {
+	case models.EntitlementCreateTodo:
+		if entitlements.MaxTodos == 0 {
+			return true, nil
+		}
+		count, err := s.repo.CountActiveTodosByUserID(user.ID)
+		if err != nil {
+			return false, fmt.Errorf("Could not count todos for user. %w", err)
+		}
+		return count < entitlements.MaxTodos, nil
+
+	case models.EntitlementCreateList:
+		if entitlements.MaxLists == 0 {
+			return true, nil
+		}
+		count, err := s.repo.CountListsByUserID(user.ID)
+		if err != nil {
+			return false, fmt.Errorf("Could not count lists for user. %w", err)
+		}
+		return count < entitlements.MaxLists, nil
+
+	case models.EntitlementAttachments:
+		return entitlements.Attachments, nil
+
+	case models.EntitlementAPIAccess:
+		return entitlements.APIAccess, nil
+
+	default:
+		return false, fmt.Errorf("%q is not an entitlement", entitlement)
+	}
+}
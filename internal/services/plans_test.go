@@ -0,0 +1,63 @@
+package services
+
+import (
+	"go-todo/internal/models"
+	"testing"
+	"time"
+)
+
+func TestEntitlementsFollowPlan(t *testing.T) {
+	s := newTestService(t)
+	s.config.Plans.Catalogue = models.PlanCatalogue{
+		{ID: "free", Name: "Free", Entitlements: models.Entitlements{MaxTodos: 1, MaxLists: 1}},
+		{ID: "pro", Name: "Pro", PriceID: s.config.Stripe.PriceID, Entitlements: models.Entitlements{APIAccess: true}},
+		{ID: "team", Name: "Team", PriceID: "price_team", Entitlements: models.Entitlements{APIAccess: true, Attachments: true}},
+	}
+
+	user := newTestUser(t, s, "user", false)
+	lists, err := s.GetUserLists(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEntitlements := func(who *models.User, want map[string]bool) {
+		t.Helper()
+		for entitlement, entitled := range want {
+			got, err := s.UserHasEntitlement(who, entitlement)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != entitled {
+				t.Errorf("expected %s to be %v for %s, got %v", entitlement, entitled, who.ID, got)
+			}
+		}
+	}
+
+	expectEntitlements(user, map[string]bool{models.EntitlementCreateTodo: true, models.EntitlementCreateList: false, models.EntitlementAPIAccess: false})
+	if _, _, err := s.CreateTodo(user.ID, lists[0].ID, "only todo", "", time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	expectEntitlements(user, map[string]bool{models.EntitlementCreateTodo: false})
+
+	subscription := newTestSubscription("sub_1", "cus_1", user.ID, "active")
+	subscription["items"] = map[string]any{"data": []map[string]any{{"price": map[string]string{"id": "price_team"}}}}
+	receiveTestEvent(t, s, "evt_1", "customer.subscription.created", time.Now(), subscription)
+
+	plan, err := s.GetUserPlan(user.ID)
+	if err != nil || plan.ID != "team" {
+		t.Fatalf("expected the subscription's price to decide the plan, got %+v %v", plan, err)
+	}
+	expectEntitlements(user, map[string]bool{models.EntitlementCreateTodo: true, models.EntitlementCreateList: true, models.EntitlementAttachments: true, models.EntitlementAPIAccess: true})
+
+	// paid before there were subscriptions
+	legacy := newTestUser(t, s, "legacy", true)
+	plan, err = s.GetUserPlan(legacy.ID)
+	if err != nil || plan.ID != "pro" {
+		t.Errorf("expected a paid user without a subscription to be on the STRIPE_PRICE_ID plan, got %+v %v", plan, err)
+	}
+	expectEntitlements(legacy, map[string]bool{models.EntitlementAPIAccess: true, models.EntitlementAttachments: false})
+
+	if _, err := s.UserHasEntitlement(user, "teleport"); err == nil {
+		t.Error("expected an unknown entitlement to be an error")
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/stripe/stripe-go/v75"
+)
+
+// ReconcileBilling compares every user with a stripe customer or a
+// subscription with their subscriptions at stripe, which are the truth
+// when they disagree, e.g. because a webhook event was missed. The
+// differences are only fixed when apply is true, then subscriptions are
+// saved as stripe has them and each user's customer and plan follow. A
+// user that cannot be compared is recorded as a failure and skipped.
+func (s *Service) ReconcileBilling(apply bool, now time.Time) (*models.BillingReconciliation, error) {
+	users, err := s.repo.GetBillingUsers()
+	if err != nil {
+		return nil, fmt.Errorf("Could not get billing users. %w", err)
+	}
+
+	reconciliation := &models.BillingReconciliation{
+		Users:       len(users),
+		Differences: []models.BillingDifference{},
+		Failures:    []models.BillingFailure{},
+		Applied:     apply,
+	}
+	for _, user := range users {
+		differences, err := s.reconcileUser(user, apply, now)
+		if err != nil {
+			reconciliation.Failures = append(reconciliation.Failures, models.BillingFailure{UserID: user.ID, Email: user.Email, Err: err})
+			continue
+		}
+		reconciliation.Differences = append(reconciliation.Differences, differences...)
+	}
+	return reconciliation, nil
+}
+
+func (s *Service) reconcileUser(user *models.User, apply bool, now time.Time) ([]models.BillingDifference, error) {
+	// with apply, differences are only returned once they have been fixed
+	differences := []models.BillingDifference{}
+	differ := func(field, local, remote string) {
+		differences = append(differences, models.BillingDifference{UserID: user.ID, Email: user.Email, Field: field, Local: local, Remote: remote, Fixed: apply})
+	}
+
+	local, err := s.GetSubscriptions(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	customerIDs := []string{}
+	if user.StripeCustomerID != "" {
+		customerIDs = append(customerIDs, user.StripeCustomerID)
+	}
+	localByID := map[string]*models.Subscription{}
+	for _, subscription := range local {
+		localByID[subscription.ID] = subscription
+		if !slices.Contains(customerIDs, subscription.CustomerID) {
+			customerIDs = append(customerIDs, subscription.CustomerID)
+		}
+	}
+
+	// the user's subscriptions at stripe, newest first
+	remote := []*models.Subscription{}
+	for _, customerID := range customerIDs {
+		subscriptions, err := s.billing.ListSubscriptions(customerID)
+		if err != nil {
+			return nil, fmt.Errorf("Could not list subscriptions of customer %s. %w", customerID, err)
+		}
+		for _, subscription := range subscriptions {
+			if localByID[subscription.ID] != nil || subscription.UserID == user.ID || (subscription.UserID == "" && customerID == user.StripeCustomerID) {
+				remote = append(remote, subscription)
+			}
+		}
+	}
+
+	// what the user's subscriptions are once the differences are fixed
+	reconciled := map[string]*models.Subscription{}
+	for id, subscription := range localByID {
+		reconciled[id] = subscription
+	}
+
+	changed := []*models.Subscription{}
+	for _, remoteSubscription := range remote {
+		status, ok := subscriptionStatuses[stripe.SubscriptionStatus(remoteSubscription.Status)]
+		if !ok {
+			// incomplete, not paid for yet
+			continue
+		}
+
+		existing := localByID[remoteSubscription.ID]
+		subscription := s.reconciledSubscription(user, existing, remoteSubscription, status, now)
+		reconciled[subscription.ID] = subscription
+
+		field := "subscription " + subscription.ID
+		if existing == nil {
+			differ(field, "missing", subscription.Status)
+			changed = append(changed, subscription)
+			continue
+		}
+
+		before := len(differences)
+		if existing.Status != subscription.Status {
+			differ(field+" status", existing.Status, subscription.Status)
+		}
+		if existing.PriceID != subscription.PriceID {
+			differ(field+" price", existing.PriceID, subscription.PriceID)
+		}
+		if !existing.CurrentPeriodEnd.Equal(subscription.CurrentPeriodEnd) {
+			differ(field+" period end", existing.CurrentPeriodEnd.Format(time.RFC3339), subscription.CurrentPeriodEnd.Format(time.RFC3339))
+		}
+		if existing.CancelAtPeriodEnd != subscription.CancelAtPeriodEnd {
+			differ(field+" cancel at period end", strconv.FormatBool(existing.CancelAtPeriodEnd), strconv.FormatBool(subscription.CancelAtPeriodEnd))
+		}
+		if len(differences) > before {
+			changed = append(changed, subscription)
+		}
+	}
+
+	remoteIDs := map[string]bool{}
+	for _, subscription := range remote {
+		remoteIDs[subscription.ID] = true
+	}
+	for _, subscription := range local {
+		if !remoteIDs[subscription.ID] {
+			// left alone, it may belong to another stripe account
+			differ("subscription "+subscription.ID, subscription.Status, "not found at stripe")
+			differences[len(differences)-1].Fixed = false
+		}
+	}
+
+	customerID := user.StripeCustomerID
+	if len(remote) > 0 && remote[0].CustomerID != user.StripeCustomerID {
+		customerID = remote[0].CustomerID
+		differ("stripe customer id", user.StripeCustomerID, customerID)
+	}
+
+	isPaidUser := false
+	for _, subscription := range reconciled {
+		if subscription.IsEntitledAt(now) {
+			isPaidUser = true
+		}
+	}
+	if isPaidUser != user.IsPaidUser {
+		differ("is_paid_user", strconv.FormatBool(user.IsPaidUser), strconv.FormatBool(isPaidUser))
+	}
+
+	if !apply || len(differences) == 0 {
+		return differences, nil
+	}
+
+	for _, subscription := range changed {
+		err = s.repo.SaveSubscription(subscription)
+		if err != nil {
+			return nil, fmt.Errorf("Could not save subscription. %w", err)
+		}
+	}
+
+	if customerID != user.StripeCustomerID {
+		err = s.AddStripeIDToUser(user.ID, customerID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.syncUserPlan(user.ID, models.PlanChangeSourceReconcile, now)
+	if err != nil {
+		return nil, err
+	}
+	return differences, nil
+}
+
+// reconciledSubscription is remote as it would be saved for user. A
+// subscription found past due here starts its grace period now, as the
+// event that should have started it was missed.
+func (s *Service) reconciledSubscription(user *models.User, existing, remote *models.Subscription, status string, now time.Time) *models.Subscription {
+	subscription := models.NewSubscription(remote.ID, user.ID, remote.CustomerID, remote.PriceID, status, remote.CurrentPeriodEnd, remote.CancelAtPeriodEnd)
+	subscription.CreatedAt = remote.CreatedAt
+	subscription.UpdatedAt = now
+	// events created before now are older than what stripe has
+	subscription.LastEventAt = now
+
+	if existing != nil {
+		subscription.CreatedAt = existing.CreatedAt
+	}
+
+	if status == models.SubscriptionPastDue {
+		if existing != nil && existing.Status == models.SubscriptionPastDue {
+			subscription.PastDueSince = existing.PastDueSince
+			subscription.GraceEndsAt = existing.GraceEndsAt
+			subscription.DunningRemindersSent = existing.DunningRemindersSent
+		} else {
+			graceEndsAt := now.Add(s.config.Dunning.GracePeriod)
+			subscription.PastDueSince = &now
+			subscription.GraceEndsAt = &graceEndsAt
+		}
+	}
+	return &subscription
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"go-todo/internal/billing"
+	"go-todo/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCheckout pays for a checkout by user at fake and returns the paid
+// session, no webhook events are sent.
+func fakeCheckout(t *testing.T, s *Service, fake *billing.Fake, user *models.User) *models.CheckoutSession {
+	t.Helper()
+	checkoutURL, clientError, err := s.StartCheckout(user, "pro")
+	if err != nil || clientError != nil {
+		t.Fatal(clientError, err)
+	}
+	checkoutSession, err := fake.PayCheckoutSession(checkoutURL[strings.LastIndex(checkoutURL, "/")+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checkoutSession
+}
+
+func TestReconcileBillingFixesMissedEvents(t *testing.T) {
+	s := newTestService(t)
+	fake := s.billing.(*billing.Fake)
+
+	// paid but the subscription's events never arrived
+	missedStart := newTestUser(t, s, "missed-start", false)
+	started := fakeCheckout(t, s, fake, missedStart)
+	if err := s.AddStripeIDToUser(missedStart.ID, started.CustomerID); err != nil {
+		t.Fatal(err)
+	}
+
+	// canceled but the deleted event never arrived
+	missedCancel := newTestUser(t, s, "missed-cancel", false)
+	canceled := fakeCheckout(t, s, fake, missedCancel)
+	payload, signature, err := fake.SubscriptionEvent("customer.subscription.created", canceled.SubscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, _ := s.ParseBillingEvent(payload, signature)
+	if _, err := s.ReceiveStripeEvent(event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ProcessStripeEvent(event.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = fake.UpdateSubscription(canceled.SubscriptionID, func(subscription *models.Subscription) {
+		subscription.Status = models.SubscriptionCanceled
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectPaid := func(user *models.User, want bool) {
+		t.Helper()
+		isPaidUser, err := s.UserIsPaidUser(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isPaidUser != want {
+			t.Errorf("expected %s to be paid %v, got %v", user.ID, want, isPaidUser)
+		}
+	}
+	expectPaid(missedStart, false)
+	expectPaid(missedCancel, true)
+
+	dryRun, err := s.ReconcileBilling(false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dryRun.Users != 2 || len(dryRun.Differences) != 4 {
+		t.Fatalf("expected a missing subscription, a status and two plans to differ, got %+v", dryRun)
+	}
+	expectPaid(missedStart, false)
+	expectPaid(missedCancel, true)
+
+	applied, err := s.ReconcileBilling(true, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applied.Applied || len(applied.Differences) != 4 {
+		t.Fatalf("expected the same differences to be fixed, got %+v", applied)
+	}
+	for _, difference := range applied.Differences {
+		if !difference.Fixed {
+			t.Errorf("expected %s to be fixed", difference.Field)
+		}
+	}
+	expectPaid(missedStart, true)
+	expectPaid(missedCancel, false)
+
+	again, err := s.ReconcileBilling(false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Differences) != 0 {
+		t.Errorf("expected billing to match once fixed, got %+v", again.Differences)
+	}
+}
+
+func TestReconcileBillingCarriesOnPastAFailingUser(t *testing.T) {
+	s := newTestService(t)
+	fake := s.billing.(*billing.Fake)
+
+	// their customer was deleted at stripe
+	stale := newTestUser(t, s, "stale", false)
+	if err := s.AddStripeIDToUser(stale.ID, "cus_deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	missedStart := newTestUser(t, s, "missed-start", false)
+	started := fakeCheckout(t, s, fake, missedStart)
+	if err := s.AddStripeIDToUser(missedStart.ID, started.CustomerID); err != nil {
+		t.Fatal(err)
+	}
+
+	reconciliation, err := s.ReconcileBilling(true, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reconciliation.Failures) != 1 || reconciliation.Failures[0].UserID != stale.ID {
+		t.Fatalf("expected the stale user to fail, got %+v", reconciliation.Failures)
+	}
+
+	isPaidUser, err := s.UserIsPaidUser(missedStart.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isPaidUser {
+		t.Error("expected the other user to still be fixed")
+	}
+}
+
+func TestReconcileBillingLeavesSubscriptionsStripeDoesNotHave(t *testing.T) {
+	s := newTestService(t)
+	fake := s.billing.(*billing.Fake)
+
+	user := newTestUser(t, s, "user", false)
+	started := fakeCheckout(t, s, fake, user)
+	if err := s.AddStripeIDToUser(user.ID, started.CustomerID); err != nil {
+		t.Fatal(err)
+	}
+	unknown := models.NewSubscription("sub_unknown", user.ID, started.CustomerID, "price_test", models.SubscriptionCanceled, time.Now(), false)
+	if err := s.repo.SaveSubscription(&unknown); err != nil {
+		t.Fatal(err)
+	}
+
+	reconciliation, err := s.ReconcileBilling(true, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed := 0
+	for _, difference := range reconciliation.Differences {
+		if difference.Field == "subscription sub_unknown" && difference.Fixed {
+			t.Error("expected the subscription stripe does not have to be left alone")
+		}
+		if difference.Fixed {
+			fixed++
+		}
+	}
+	if fixed != len(reconciliation.Differences)-1 {
+		t.Errorf("expected every other difference to be fixed, got %+v", reconciliation.Differences)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-todo/internal/logger"
+	"go-todo/internal/mailer"
+	"go-todo/internal/models"
+	"strings"
+	"time"
+)
+
+const todoReminderEmailBody = `Hi %s,
+
+These todos are coming due:
+
+%s
+See them at %s
+`
+
+// RemindersResult is what a reminder run did. Errors holds the users who
+// could not be reminded, the rest of the run carries on without them.
+type RemindersResult struct {
+	Reminded []*models.Todo
+	Errors   []error
+}
+
+// RunTodoReminders emails users about their open todos coming due within
+// the configured time of now. Each todo is reminded about once, unless its
+// due date changes.
+func (s *Service) RunTodoReminders(now time.Time) (*RemindersResult, error) {
+	due, err := s.repo.GetTodosDueForReminder(now, now.Add(s.config.Reminders.Before))
+	if err != nil {
+		return nil, fmt.Errorf("Could not get todos due for reminder. %w", err)
+	}
+
+	byUser := map[string][]*models.Todo{}
+	userIDs := []string{}
+	for _, todo := range due {
+		if byUser[todo.UserID] == nil {
+			userIDs = append(userIDs, todo.UserID)
+		}
+		byUser[todo.UserID] = append(byUser[todo.UserID], todo)
+	}
+
+	result := &RemindersResult{}
+	for _, userID := range userIDs {
+		reminded, err := s.remindUser(userID, byUser[userID], now)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("Could not remind user (%s). %w", userID, err))
+			continue
+		}
+		result.Reminded = append(result.Reminded, reminded...)
+	}
+
+	return result, errors.Join(result.Errors...)
+}
+
+// remindUser sends one email for the user's todos that were not already
+// reminded about.
+func (s *Service) remindUser(userID string, todos []*models.Todo, now time.Time) ([]*models.Todo, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("todos belong to user (%s) who does not exist", userID)
+	}
+
+	reminded := []*models.Todo{}
+	var lines strings.Builder
+	for _, todo := range todos {
+		claimed, err := s.repo.ClaimTodoReminder(todo.ID, now)
+		if err != nil {
+			return reminded, err
+		}
+		if !claimed {
+			continue
+		}
+		reminded = append(reminded, todo)
+		fmt.Fprintf(&lines, "- %s, due %s\n", todo.Description, todo.DueAt.In(user.Location()).Format("Mon 2 Jan 2006, 15:04"))
+	}
+	if len(reminded) == 0 {
+		return reminded, nil
+	}
+
+	err = s.queueEmail(mailer.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("%d go-todo todo(s) coming due", len(reminded)),
+		Body:    fmt.Sprintf(todoReminderEmailBody, user.Name, lines.String(), s.config.Domain),
+	})
+	if err != nil {
+		return reminded, fmt.Errorf("Could not queue todo reminder. %w", err)
+	}
+	return reminded, nil
+}
+
+// StartTodoReminders runs todo reminders every interval until the
+// returned stop is called.
+func (s *Service) StartTodoReminders(interval time.Duration, logr *logger.Logger) (stop func()) {
+	return runEvery(interval, func() {
+		result, err := s.RunTodoReminders(time.Now())
+		if result == nil {
+			logr.Error(fmt.Sprintf("Could not run todo reminders. %s", err))
+			return
+		}
+		for _, err := range result.Errors {
+			logr.Error(err.Error())
+		}
+		if len(result.Reminded) > 0 {
+			logr.Info(fmt.Sprintf("Reminded users about %d todo(s) coming due", len(result.Reminded)))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"go-todo/internal/mailer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTodoRemindersAreSentOnce(t *testing.T) {
+	s := newTestService(t)
+	dir := t.TempDir()
+	s.mailer = mailer.NewFileMailer(dir)
+	user := newTestUser(t, s, "busy", false)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	lists, err := s.GetUserLists(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dueAt := func(description string, due time.Time) int {
+		t.Helper()
+		todo, _, err := s.CreateTodo(user.ID, lists[0].ID, description, "", time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		todo.DueAt = &due
+		if err := s.repo.UpdateTodo(*todo); err != nil {
+			t.Fatal(err)
+		}
+		return todo.ID
+	}
+	soon := dueAt("file taxes", now.Add(2*time.Hour))
+	dueAt("plan holiday", now.Add(72*time.Hour))
+
+	expectReminded := func(want int) {
+		t.Helper()
+		result, err := s.RunTodoReminders(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Reminded) != want {
+			t.Errorf("expected %d todo(s) reminded, got %d", want, len(result.Reminded))
+		}
+	}
+
+	expectReminded(1)
+	expectReminded(0)
+
+	sendOutbox(t, s)
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one reminder email, got %d %v", len(files), err)
+	}
+	body, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "file taxes") || strings.Contains(string(body), "plan holiday") {
+		t.Errorf("expected only the todo coming due in the email, got:\n%s", body)
+	}
+
+	// a new due date gets a new reminder
+	todo, err := s.repo.GetTodoByID(soon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := now.Add(3 * time.Hour)
+	todo.DueAt = &later
+	if err := s.repo.UpdateTodo(*todo); err != nil {
+		t.Fatal(err)
+	}
+	expectReminded(1)
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+)
+
+func (s *Service) GetRoles() ([]*models.Role, error) {
+	roles, err := s.repo.GetRoles()
+	if err != nil {
+		return nil, fmt.Errorf("Could not get roles. %w", err)
+	}
+	return roles, nil
+}
+
+func (s *Service) getRoleByName(name string) (*models.Role, error) {
+	role, err := s.repo.GetRoleByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get role by name. %w", err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("Role %q does not exist", name)
+	}
+	return role, nil
+}
+
+func (s *Service) GrantRole(userID string, roleName string) error {
+	role, err := s.getRoleByName(roleName)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.GrantRole(userID, role.ID)
+	if err != nil {
+		return fmt.Errorf("Could not grant role %s. %w", roleName, err)
+	}
+	return nil
+}
+
+func (s *Service) RevokeRole(userID string, roleName string) error {
+	role, err := s.getRoleByName(roleName)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.RevokeRole(userID, role.ID)
+	if err != nil {
+		return fmt.Errorf("Could not revoke role %s. %w", roleName, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"go-todo/internal/models"
+	"net/http"
+	"testing"
+)
+
+func TestGrantAndRevokeRole(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "support", false)
+
+	if err := s.GrantRole(user.ID, "support"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.HasRole("support") || !loaded.HasPermission(models.PermissionUsersView) {
+		t.Fatalf("expected support role and its permissions, got %v %v", loaded.Roles, loaded.Permissions)
+	}
+	if loaded.HasPermission(models.PermissionUsersManage) {
+		t.Error("support should not be able to manage users")
+	}
+
+	if err := s.RevokeRole(user.ID, "support"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err = s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.HasRole("support") || loaded.HasPermission(models.PermissionUsersView) {
+		t.Errorf("expected role to be revoked, got %v %v", loaded.Roles, loaded.Permissions)
+	}
+
+	if err := s.GrantRole(user.ID, "superuser"); err == nil {
+		t.Error("expected granting an unknown role to fail")
+	}
+}
+
+func TestDeleteTodoRequiresOwnershipOrPermission(t *testing.T) {
+	s := newTestService(t)
+	owner := newTestUser(t, s, "owner", false)
+	other := newTestUser(t, s, "other", false)
+
+	lists, err := s.GetUserLists(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	todo, clientErrors, err := s.CreateTodo(owner.ID, lists[0].ID, "someone else's todo", "", nil)
+	if err != nil || clientErrors != nil {
+		t.Fatalf("expected todo to be created, got %v %v", clientErrors, err)
+	}
+
+	clientError, err := s.DeleteTodo(todo.ID, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil || clientError.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a user without permission, got %v", clientError)
+	}
+
+	if err := s.GrantRole(other.ID, "support"); err != nil {
+		t.Fatal(err)
+	}
+	other, err = s.GetUserByID(other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientError, err = s.DeleteTodo(todo.ID, other)
+	if err != nil || clientError != nil {
+		t.Fatalf("expected support user to delete the todo, got %v %v", clientError, err)
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"regexp"
+	"strings"
+)
+
+const searchResultLimit = 50
+
+var searchTermRegex = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// buildMatchQuery turns free text typed into the search box into an FTS5
+// query. Every word must appear, and the last word may be a prefix so
+// results update while the user is still typing. Quoting each term keeps
+// FTS5 operators in user input from being interpreted.
+func buildMatchQuery(query string) string {
+	terms := searchTermRegex.FindAllString(query, -1)
+	if len(terms) == 0 {
+		return ""
+	}
+
+	quoted := []string{}
+	for i, term := range terms {
+		q := `"` + term + `"`
+		if i == len(terms)-1 {
+			q += "*"
+		}
+		quoted = append(quoted, q)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SearchTodos searches the descriptions of the user's own todos across all
+// of their lists.
+func (s *Service) SearchTodos(userID string, query string) ([]*models.SearchResult, error) {
+	matchQuery := buildMatchQuery(query)
+	if matchQuery == "" {
+		return []*models.SearchResult{}, nil
+	}
+
+	results, err := s.repo.SearchTodos(userID, matchQuery, searchResultLimit)
+	if err != nil {
+		return nil, fmt.Errorf("Could not search todos. %w", err)
+	}
+
+	return results, nil
+}
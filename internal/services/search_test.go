@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildMatchQuery(t *testing.T) {
+	cases := map[string]string{
+		"":                 "",
+		"   ":              "",
+		"milk":             `"milk"*`,
+		"buy  milk":        `"buy" "milk"*`,
+		`"milk" OR eggs*`:  `"milk" "OR" "eggs"*`,
+		"café -au-lait":    `"café" "au" "lait"*`,
+		"NEAR(a b) ^title": `"NEAR" "a" "b" "title"*`,
+	}
+	for query, want := range cases {
+		if got := buildMatchQuery(query); got != want {
+			t.Errorf("buildMatchQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestSearchTodosOnlyReturnsOwnTodos(t *testing.T) {
+	s := newTestService(t)
+	owner := newTestUser(t, s, "owner", false)
+	other := newTestUser(t, s, "other", false)
+
+	for _, user := range []string{owner.ID, other.ID} {
+		lists, err := s.GetUserLists(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.CreateTodo(user, lists[0].ID, "buy oat milk", "", time.UTC); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := s.SearchTodos(owner.ID, "mil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Todo.UserID != owner.ID {
+		t.Fatalf("expected only the owner's todo, got %+v", results)
+	}
+}
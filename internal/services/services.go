@@ -1,22 +1,49 @@
 package services
 
 import (
+	"go-todo/internal/config"
+	"go-todo/internal/mailer"
 	"go-todo/internal/repositories"
-	"go-todo/internal/server/cache"
+	"time"
 )
 
-const DefaultLimit = 10
-
 type clientError *ClientError
 
+// runEvery calls run every interval in the background until the returned
+// stop is called.
+func runEvery(interval time.Duration, run func()) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				run()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
 type Service struct {
-	repo   *repositories.Repository
-	caches *cache.Caches
+	repo    *repositories.Repository
+	mailer  mailer.Mailer
+	billing Billing
+	config  *config.Config
 }
 
-func NewService(r *repositories.Repository, caches *cache.Caches) *Service {
+func NewService(r *repositories.Repository, m mailer.Mailer, b Billing, c *config.Config) *Service {
 	return &Service{
-		repo:   r,
-		caches: caches,
+		repo:    r,
+		mailer:  m,
+		billing: b,
+		config:  c,
 	}
 }
@@ -1 +1,42 @@
 package services
+
+import (
+	"go-todo/internal/billing"
+	"go-todo/internal/config"
+	database "go-todo/internal/db"
+	"go-todo/internal/mailer"
+	"go-todo/internal/migrations"
+	"go-todo/internal/models"
+	"go-todo/internal/repositories"
+	"testing"
+)
+
+func newTestService(t *testing.T) *Service {
+	db, err := database.ConnectMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if !database.SupportsFTS5(db) {
+		t.Fatal("sqlite3 was built without FTS5, run tests with -tags sqlite_fts5")
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewService(repositories.NewRepository(db), mailer.NewFileMailer(t.TempDir()), billing.NewFake(), config.Defaults())
+}
+
+func newTestUser(t *testing.T, s *Service, id string, isPaidUser bool) *models.User {
+	user := models.NewUser(id, id, id+"@email.com", "password", isPaidUser, "")
+	if err := s.repo.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
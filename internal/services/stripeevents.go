@@ -0,0 +1,204 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-todo/internal/logger"
+	"go-todo/internal/models"
+	"net/http"
+	"time"
+
+	"github.com/stripe/stripe-go/v75"
+)
+
+// how long an attempt at an event has before it may be taken again, in
+// case the process dies part way through
+const stripeEventClaim = 5 * time.Minute
+
+// how many due events each retry pass takes
+const stripeEventRetryBatch = 50
+
+// retryDelay is how long to wait before retrying a stripe event or an
+// email that has failed attempts times, doubling from a minute up to six
+// hours.
+func retryDelay(attempts int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempts && delay < 6*time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, 6*time.Hour)
+}
+
+// ReceiveStripeEvent stores a verified webhook event so it is processed
+// even if processing it now fails. It returns false when the event was
+// already received, Stripe delivers events at least once.
+func (s *Service) ReceiveStripeEvent(event *models.BillingEvent) (bool, error) {
+	now := time.Now().UTC()
+	stripeEvent := models.NewStripeEvent(event.ID, event.Type, string(event.Data), event.Created, now, now)
+
+	saved, err := s.repo.SaveStripeEvent(&stripeEvent)
+	if err != nil {
+		return false, fmt.Errorf("Could not save stripe event. %w", err)
+	}
+	return saved, nil
+}
+
+// ProcessStripeEvent makes one attempt at an event that is due and returns
+// it as it is afterwards. It returns nil when the event is not due, e.g.
+// because another attempt at it is running. An attempt that fails is
+// recorded on the event and retried later, it is not an error.
+func (s *Service) ProcessStripeEvent(id string) (*models.StripeEvent, error) {
+	now := time.Now().UTC()
+
+	claimed, err := s.repo.ClaimStripeEvent(id, now, now.Add(stripeEventClaim))
+	if err != nil {
+		return nil, fmt.Errorf("Could not claim stripe event. %w", err)
+	}
+	if !claimed {
+		return nil, nil
+	}
+
+	event, err := s.repo.GetStripeEvent(id)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get stripe event. %w", err)
+	}
+
+	processErr := s.applyStripeEvent(event)
+	if processErr == nil {
+		err = s.repo.MarkStripeEventProcessed(id, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("Could not mark stripe event processed. %w", err)
+		}
+	} else {
+		var nextAttemptAt *time.Time
+		if event.Attempts < s.config.Stripe.EventMaxAttempts {
+			retryAt := time.Now().UTC().Add(retryDelay(event.Attempts))
+			nextAttemptAt = &retryAt
+		}
+		err = s.repo.MarkStripeEventFailed(id, processErr.Error(), nextAttemptAt)
+		if err != nil {
+			return nil, fmt.Errorf("Could not mark stripe event failed. %w", err)
+		}
+	}
+
+	event, err = s.repo.GetStripeEvent(id)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get stripe event. %w", err)
+	}
+	return event, nil
+}
+
+// RetryStripeEvents makes an attempt at every event that is due and
+// returns the events attempted.
+func (s *Service) RetryStripeEvents() ([]*models.StripeEvent, error) {
+	due, err := s.repo.GetDueStripeEvents(time.Now(), stripeEventRetryBatch)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get due stripe events. %w", err)
+	}
+
+	attempted := []*models.StripeEvent{}
+	for _, event := range due {
+		event, err = s.ProcessStripeEvent(event.ID)
+		if err != nil {
+			return attempted, err
+		}
+		if event != nil {
+			attempted = append(attempted, event)
+		}
+	}
+	return attempted, nil
+}
+
+// ReplayStripeEvent processes an event again whatever its status, e.g.
+// once the bug that made it fail is fixed.
+func (s *Service) ReplayStripeEvent(id string) (*models.StripeEvent, clientError, error) {
+	event, err := s.repo.GetStripeEvent(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get stripe event. %w", err)
+	}
+	if event == nil {
+		return nil, NewClientError(fmt.Sprintf("No stripe event has the id %s", id), http.StatusNotFound), nil
+	}
+
+	err = s.repo.ReplayStripeEvent(id, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not replay stripe event. %w", err)
+	}
+
+	event, err = s.ProcessStripeEvent(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if event == nil {
+		return nil, NewClientError("The stripe event is being processed, try again shortly", http.StatusConflict), nil
+	}
+	return event, nil, nil
+}
+
+// GetStripeEvents returns up to limit events with status, or any status
+// when it is blank, most recent first.
+func (s *Service) GetStripeEvents(status string, limit int) ([]*models.StripeEvent, error) {
+	events, err := s.repo.GetStripeEvents(status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get stripe events. %w", err)
+	}
+	return events, nil
+}
+
+// StartStripeEventRetries retries failed stripe events every interval
+// until the returned stop is called.
+func (s *Service) StartStripeEventRetries(interval time.Duration, logr *logger.Logger) (stop func()) {
+	return runEvery(interval, func() {
+		events, err := s.RetryStripeEvents()
+		if err != nil {
+			logr.Error(fmt.Sprintf("Could not retry stripe events. %s", err))
+		}
+		for _, event := range events {
+			if event.Status == models.StripeEventProcessed {
+				logr.Info(fmt.Sprintf("Processed stripe event (%s) %s on attempt %d", event.ID, event.Type, event.Attempts))
+			} else {
+				logr.Warning(fmt.Sprintf("Stripe event (%s) %s failed attempt %d, it is %s. %s", event.ID, event.Type, event.Attempts, event.Status, event.LastError))
+			}
+		}
+	})
+}
+
+// applyStripeEvent makes the changes an event calls for. Applying an event
+// twice has the same effect as applying it once.
+func (s *Service) applyStripeEvent(event *models.StripeEvent) error {
+	switch event.Type {
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted",
+		"customer.subscription.paused", "customer.subscription.resumed":
+		return s.applySubscriptionEvent(event)
+
+	case "checkout.session.completed":
+		// links the customer to the user who started the checkout, in case
+		// the user leaves before the success page
+		var checkoutSession stripe.CheckoutSession
+		err := json.Unmarshal([]byte(event.Payload), &checkoutSession)
+		if err != nil {
+			return fmt.Errorf("Failed to parse checkout session completed webhook, %w", err)
+		}
+
+		if checkoutSession.ClientReferenceID == "" || checkoutSession.Customer == nil || checkoutSession.Customer.ID == "" {
+			return nil
+		}
+
+		user, err := s.GetUserByID(checkoutSession.ClientReferenceID)
+		if err != nil {
+			return err
+		}
+		if user == nil || user.StripeCustomerID == checkoutSession.Customer.ID {
+			return nil
+		}
+		return s.AddStripeIDToUser(user.ID, checkoutSession.Customer.ID)
+
+	case "invoice.paid", "invoice.payment_failed":
+		// invoices are kept for the billing history, the subscription
+		// events they cause change the plan
+		return s.applyInvoiceEvent(event)
+
+	default:
+		return nil
+	}
+}
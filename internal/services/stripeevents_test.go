@@ -0,0 +1,117 @@
+package services
+
+import (
+	"encoding/json"
+	"go-todo/internal/models"
+	"testing"
+	"time"
+)
+
+func newTestBillingEvent(t *testing.T, id, eventType string, object any) *models.BillingEvent {
+	data, err := json.Marshal(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.BillingEvent{ID: id, Type: eventType, Created: time.Now(), Data: data}
+}
+
+func TestStripeEventsAreProcessedOnce(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+
+	event := newTestBillingEvent(t, "evt_1", "customer.subscription.created", newTestSubscription("sub_1", "cus_1", user.ID, "active"))
+
+	received, err := s.ReceiveStripeEvent(event)
+	if err != nil || !received {
+		t.Fatalf("expected the event to be received, got %v %v", received, err)
+	}
+
+	processed, err := s.ProcessStripeEvent(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed.Status != models.StripeEventProcessed || processed.Attempts != 1 || processed.NextAttemptAt != nil {
+		t.Fatalf("expected the event to be processed, got %+v", processed)
+	}
+
+	user, err = s.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsPaidUser || user.StripeCustomerID != "cus_1" {
+		t.Fatalf("expected the active subscription to upgrade the user, got %+v", user)
+	}
+
+	// a redelivery is acknowledged but not processed again
+	received, err = s.ReceiveStripeEvent(event)
+	if err != nil || received {
+		t.Fatalf("expected the redelivery to be recognised, got %v %v", received, err)
+	}
+	again, err := s.ProcessStripeEvent(event.ID)
+	if err != nil || again != nil {
+		t.Errorf("expected a processed event not to be processed again, got %+v %v", again, err)
+	}
+}
+
+func TestFailedStripeEventsAreRetried(t *testing.T) {
+	s := newTestService(t)
+	s.config.Stripe.EventMaxAttempts = 2
+
+	// nobody has the customer yet
+	event := newTestBillingEvent(t, "evt_1", "customer.subscription.deleted", newTestSubscription("sub_1", "cus_1", "", "canceled"))
+	if _, err := s.ReceiveStripeEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	failed, err := s.ProcessStripeEvent(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != models.StripeEventPending || failed.LastError == "" || failed.NextAttemptAt == nil {
+		t.Fatalf("expected the event to wait for a retry, got %+v", failed)
+	}
+
+	// not due yet
+	attempted, err := s.RetryStripeEvents()
+	if err != nil || len(attempted) != 0 {
+		t.Fatalf("expected no events to be due, got %d %v", len(attempted), err)
+	}
+
+	if err := s.repo.ReplayStripeEvent(event.ID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	attempted, err = s.RetryStripeEvents()
+	if err != nil || len(attempted) != 1 {
+		t.Fatalf("expected the due event to be retried, got %d %v", len(attempted), err)
+	}
+	if attempted[0].Status != models.StripeEventFailed || attempted[0].NextAttemptAt != nil {
+		t.Fatalf("expected the event to be given up on after its last attempt, got %+v", attempted[0])
+	}
+
+	// once the customer exists a replay succeeds
+	user := newTestUser(t, s, "user", true)
+	if err := s.AddStripeIDToUser(user.ID, "cus_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, clientError, err := s.ReplayStripeEvent(event.ID)
+	if err != nil || clientError != nil {
+		t.Fatal(clientError, err)
+	}
+	if replayed.Status != models.StripeEventProcessed {
+		t.Fatalf("expected the replay to process the event, got %+v", replayed)
+	}
+
+	isPaidUser, err := s.UserIsPaidUser(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isPaidUser {
+		t.Error("expected the replayed cancellation to downgrade the user")
+	}
+
+	events, err := s.GetStripeEvents(models.StripeEventProcessed, 10)
+	if err != nil || len(events) != 1 {
+		t.Errorf("expected the event to be listed as processed, got %d %v", len(events), err)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-todo/internal/models"
+	"time"
+
+	"github.com/stripe/stripe-go/v75"
+)
+
+// subscriptionStatuses maps Stripe's subscription statuses onto ours.
+// Incomplete subscriptions are left out, they have not been paid for yet
+// and become active or incomplete_expired within a day.
+var subscriptionStatuses = map[stripe.SubscriptionStatus]string{
+	stripe.SubscriptionStatusTrialing:          models.SubscriptionTrialing,
+	stripe.SubscriptionStatusActive:            models.SubscriptionActive,
+	stripe.SubscriptionStatusPastDue:           models.SubscriptionPastDue,
+	stripe.SubscriptionStatusUnpaid:            models.SubscriptionPastDue,
+	stripe.SubscriptionStatusPaused:            models.SubscriptionPaused,
+	stripe.SubscriptionStatusCanceled:          models.SubscriptionCanceled,
+	stripe.SubscriptionStatusIncompleteExpired: models.SubscriptionCanceled,
+}
+
+// GetSubscriptions returns the user's subscriptions, newest first.
+func (s *Service) GetSubscriptions(userID string) ([]*models.Subscription, error) {
+	subscriptions, err := s.repo.GetSubscriptionsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get subscriptions. %w", err)
+	}
+	return subscriptions, nil
+}
+
+// GetCurrentSubscription returns the subscription that decides the user's
+// plan, the newest one that is entitled to it or else the newest one. It
+// returns nil when the user has never subscribed.
+func (s *Service) GetCurrentSubscription(userID string) (*models.Subscription, error) {
+	return s.currentSubscriptionAt(userID, time.Now())
+}
+
+func (s *Service) currentSubscriptionAt(userID string, now time.Time) (*models.Subscription, error) {
+	subscriptions, err := s.GetSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(subscriptions) == 0 {
+		return nil, nil
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.IsEntitledAt(now) {
+			return subscription, nil
+		}
+	}
+	return subscriptions[0], nil
+}
+
+// applySubscriptionEvent records the subscription a customer.subscription.*
+// event is about. Events older than the last one applied to the
+// subscription are ignored, and a status the subscription can not move to
+// fails the event so it shows up to be looked at.
+func (s *Service) applySubscriptionEvent(event *models.StripeEvent) error {
+	var stripeSubscription stripe.Subscription
+	err := json.Unmarshal([]byte(event.Payload), &stripeSubscription)
+	if err != nil {
+		return fmt.Errorf("Failed to parse %s webhook, %w", event.Type, err)
+	}
+
+	status, ok := subscriptionStatuses[stripeSubscription.Status]
+	if !ok {
+		// incomplete, wait for the event that completes it
+		return nil
+	}
+
+	existing, err := s.repo.GetSubscription(stripeSubscription.ID)
+	if err != nil {
+		return fmt.Errorf("Could not get subscription. %w", err)
+	}
+
+	if existing != nil {
+		if event.CreatedAt.Before(existing.LastEventAt) {
+			return nil
+		}
+		err = existing.CanTransitionTo(status)
+		if err != nil {
+			return err
+		}
+	}
+
+	user, err := s.subscriptionUser(existing, &stripeSubscription)
+	if err != nil {
+		return err
+	}
+
+	var priceID string
+	if stripeSubscription.Items != nil && len(stripeSubscription.Items.Data) > 0 && stripeSubscription.Items.Data[0].Price != nil {
+		priceID = stripeSubscription.Items.Data[0].Price.ID
+	}
+
+	subscription := models.NewSubscription(stripeSubscription.ID, user.ID, stripeSubscription.Customer.ID, priceID, status,
+		time.Unix(stripeSubscription.CurrentPeriodEnd, 0), stripeSubscription.CancelAtPeriodEnd)
+	subscription.CreatedAt = time.Unix(stripeSubscription.Created, 0)
+	if existing != nil {
+		subscription.CreatedAt = existing.CreatedAt
+	}
+	subscription.UpdatedAt = time.Now()
+	subscription.LastEventAt = event.CreatedAt
+
+	// the grace period starts when the subscription first becomes past due
+	if status == models.SubscriptionPastDue {
+		if existing != nil && existing.Status == models.SubscriptionPastDue {
+			subscription.PastDueSince = existing.PastDueSince
+			subscription.GraceEndsAt = existing.GraceEndsAt
+			subscription.DunningRemindersSent = existing.DunningRemindersSent
+		} else {
+			pastDueSince := event.CreatedAt
+			graceEndsAt := pastDueSince.Add(s.config.Dunning.GracePeriod)
+			subscription.PastDueSince = &pastDueSince
+			subscription.GraceEndsAt = &graceEndsAt
+		}
+	}
+
+	err = s.repo.SaveSubscription(&subscription)
+	if err != nil {
+		return fmt.Errorf("Could not save subscription. %w", err)
+	}
+
+	if user.StripeCustomerID != subscription.CustomerID {
+		err = s.AddStripeIDToUser(user.ID, subscription.CustomerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.syncUserPlan(user.ID, event.Type, time.Now())
+	return err
+}
+
+// subscriptionUser returns the user a subscription belongs to: the user it
+// was recorded for, the user in its metadata, set when checkout started,
+// or the user with its customer. It is an error when there is none, the
+// event is retried in case the user is still being linked.
+func (s *Service) subscriptionUser(existing *models.Subscription, stripeSubscription *stripe.Subscription) (*models.User, error) {
+	if stripeSubscription.Customer == nil || stripeSubscription.Customer.ID == "" {
+		return nil, fmt.Errorf("subscription %s has no customer", stripeSubscription.ID)
+	}
+
+	userID := stripeSubscription.Metadata[models.UserIDMetadataKey]
+	if existing != nil {
+		userID = existing.UserID
+	}
+
+	if userID != "" {
+		user, err := s.GetUserByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			return user, nil
+		}
+	}
+
+	user, err := s.GetUserByStripeID(stripeSubscription.Customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("no user has the id (%s) or stripe ID (%s) of subscription %s", userID, stripeSubscription.Customer.ID, stripeSubscription.ID)
+	}
+	return user, nil
+}
+
+// syncUserPlan puts the user on the paid plan while any of their
+// subscriptions is entitled to it and on the free plan otherwise, and
+// records whether they are in a grace period. When their plan actually
+// changes the change is recorded for analytics along with its source, e.g.
+// the stripe webhook event type, their todos are fitted to the new plan's
+// limit and true is returned.
+func (s *Service) syncUserPlan(userID, source string, now time.Time) (bool, error) {
+	subscription, err := s.currentSubscriptionAt(userID, now)
+	if err != nil {
+		return false, err
+	}
+
+	isPaidUser := subscription != nil && subscription.IsEntitledAt(now)
+
+	var graceEndsAt *time.Time
+	if subscription != nil && subscription.InGracePeriodAt(now) {
+		graceEndsAt = subscription.GraceEndsAt
+	}
+	internalErr := s.repo.SetUserPaymentGraceEndsAt(userID, graceEndsAt)
+	if internalErr != nil {
+		return false, fmt.Errorf("Could not update user payment status. %w", internalErr)
+	}
+
+	wasPaidUser, internalErr := s.repo.UserIsPaidUser(userID)
+	if internalErr != nil {
+		return false, fmt.Errorf("Could not update user payment status. %w", internalErr)
+	}
+	if wasPaidUser == isPaidUser {
+		return false, nil
+	}
+
+	internalErr = s.repo.UpdateUserPaymentStatus(userID, isPaidUser)
+	if internalErr != nil {
+		return false, fmt.Errorf("Could not update user payment status. %w", internalErr)
+	}
+
+	internalErr = s.repo.RecordPlanChange(userID, isPaidUser, source)
+	if internalErr != nil {
+		return false, fmt.Errorf("Could not record plan change. %w", internalErr)
+	}
+
+	err = s.applyTodoLimit(userID)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IsTrialEligible reports whether the user can have a free trial, only
+// users who have never subscribed can.
+func (s *Service) IsTrialEligible(userID string) (bool, error) {
+	subscriptions, err := s.GetSubscriptions(userID)
+	if err != nil {
+		return false, err
+	}
+	return len(subscriptions) == 0, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"go-todo/internal/models"
+	"testing"
+	"time"
+)
+
+// newTestSubscription is a subscription as it is sent in Stripe's
+// customer.subscription.* events.
+func newTestSubscription(id, customerID, userID, status string) map[string]any {
+	return map[string]any{
+		"id":                 id,
+		"customer":           customerID,
+		"status":             status,
+		"created":            time.Now().Unix(),
+		"current_period_end": time.Now().AddDate(0, 1, 0).Unix(),
+		"metadata":           map[string]string{models.UserIDMetadataKey: userID},
+		"items":              map[string]any{"data": []map[string]any{{"price": map[string]string{"id": "price_test"}}}},
+	}
+}
+
+// receiveTestEvent receives and processes an event created at created.
+func receiveTestEvent(t *testing.T, s *Service, id, eventType string, created time.Time, object any) *models.StripeEvent {
+	event := newTestBillingEvent(t, id, eventType, object)
+	event.Created = created
+	if _, err := s.ReceiveStripeEvent(event); err != nil {
+		t.Fatal(err)
+	}
+
+	processed, err := s.ProcessStripeEvent(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return processed
+}
+
+func TestSubscriptionEventsDecidePlan(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	start := time.Now().Add(-time.Hour)
+
+	steps := []struct {
+		eventType string
+		status    string
+		paid      bool
+	}{
+		{"customer.subscription.created", "incomplete", false},
+		{"customer.subscription.updated", "active", true},
+		{"customer.subscription.updated", "past_due", true},
+		{"customer.subscription.paused", "paused", false},
+		{"customer.subscription.resumed", "active", true},
+		{"customer.subscription.deleted", "canceled", false},
+	}
+	for i, step := range steps {
+		event := receiveTestEvent(t, s, "evt_"+step.status+step.eventType, step.eventType, start.Add(time.Duration(i)*time.Minute),
+			newTestSubscription("sub_1", "cus_1", user.ID, step.status))
+		if event.Status != models.StripeEventProcessed {
+			t.Fatalf("expected %s to %s to be processed, got %+v", step.eventType, step.status, event)
+		}
+
+		isPaidUser, err := s.UserIsPaidUser(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isPaidUser != step.paid {
+			t.Errorf("expected a %s subscription to make the user paid %v, got %v", step.status, step.paid, isPaidUser)
+		}
+	}
+
+	subscription, err := s.GetCurrentSubscription(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subscription.Status != models.SubscriptionCanceled || subscription.PriceID != "price_test" || subscription.CustomerID != "cus_1" {
+		t.Errorf("expected the canceled subscription, got %+v", subscription)
+	}
+
+	user, err = s.GetUserByID(user.ID)
+	if err != nil || user.StripeCustomerID != "cus_1" {
+		t.Errorf("expected the user to be linked to the subscription's customer, got %+v %v", user, err)
+	}
+}
+
+func TestStaleSubscriptionEventsAreIgnored(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	now := time.Now()
+
+	receiveTestEvent(t, s, "evt_2", "customer.subscription.deleted", now, newTestSubscription("sub_1", "cus_1", user.ID, "canceled"))
+	// the created event was delivered after the later deleted one
+	stale := receiveTestEvent(t, s, "evt_1", "customer.subscription.created", now.Add(-time.Minute), newTestSubscription("sub_1", "cus_1", user.ID, "active"))
+	if stale.Status != models.StripeEventProcessed {
+		t.Fatalf("expected the stale event to be processed, got %+v", stale)
+	}
+
+	subscription, err := s.GetCurrentSubscription(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subscription.Status != models.SubscriptionCanceled {
+		t.Errorf("expected the stale event not to reactivate the subscription, got %s", subscription.Status)
+	}
+
+	isPaidUser, err := s.UserIsPaidUser(user.ID)
+	if err != nil || isPaidUser {
+		t.Errorf("expected the user to stay free, got %v %v", isPaidUser, err)
+	}
+}
+
+func TestInvalidSubscriptionTransitionsFail(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	now := time.Now()
+
+	receiveTestEvent(t, s, "evt_1", "customer.subscription.deleted", now.Add(-time.Minute), newTestSubscription("sub_1", "cus_1", user.ID, "canceled"))
+	event := receiveTestEvent(t, s, "evt_2", "customer.subscription.updated", now, newTestSubscription("sub_1", "cus_1", user.ID, "active"))
+	if event.Status != models.StripeEventPending || event.LastError == "" {
+		t.Fatalf("expected a canceled subscription becoming active to fail, got %+v", event)
+	}
+
+	subscription := models.NewSubscription("sub_1", user.ID, "cus_1", "price_test", models.SubscriptionActive, now, false)
+	if err := subscription.CanTransitionTo("unknown"); err == nil {
+		t.Error("expected an unknown status to be refused")
+	}
+	if err := subscription.CanTransitionTo(models.SubscriptionTrialing); err == nil {
+		t.Error("expected an active subscription not to go back to trialing")
+	}
+	if err := subscription.CanTransitionTo(models.SubscriptionActive); err != nil {
+		t.Errorf("expected a renewal to keep the status, got %v", err)
+	}
+}
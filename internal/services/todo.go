@@ -5,21 +5,77 @@ import (
 	"go-todo/internal/models"
 	"html"
 	"net/http"
+	"strings"
+	"time"
 )
 
-func (s *Service) CreateTodo(userID, description string) (*models.Todo, *models.CreateTodoClientErrors, error) {
+// accepted formats for due dates submitted from the todo form
+const (
+	dueAtDateTimeLayout = "2006-01-02T15:04"
+	dueAtDateLayout     = "2006-01-02"
+)
+
+// parseDueAt interprets a submitted due date in the user's location and
+// returns it in UTC. An empty value means the todo has no due date. A date
+// without a time is due at the end of that day. RFC 3339 timestamps, as
+// sent to the API, carry their own offset.
+func parseDueAt(value string, loc *time.Location) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	dueAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		dueAt, err = time.ParseInLocation(dueAtDateTimeLayout, value, loc)
+	}
+	if err != nil {
+		dueAt, err = time.ParseInLocation(dueAtDateLayout, value, loc)
+		if err != nil {
+			return nil, err
+		}
+		dueAt = dueAt.Add(24*time.Hour - time.Minute)
+	}
+
+	dueAt = dueAt.UTC()
+	return &dueAt, nil
+}
+
+func validateDescription(description string) []string {
+	descriptionErrors := []string{}
+	if strings.TrimSpace(description) == "" {
+		descriptionErrors = append(descriptionErrors, "cannot supply an empty description")
+	}
+	return descriptionErrors
+}
+
+func (s *Service) CreateTodo(userID string, listID int, description, dueAt string, loc *time.Location) (*models.Todo, *models.CreateTodoClientErrors, error) {
 	clientErrors := models.CreateTodoClientErrors{}
-	if description == "" {
-		clientErrors.DescriptionErrors = append(clientErrors.DescriptionErrors, "cannot supply an empty description")
+
+	list, err := s.repo.GetListByID(listID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get list for new todo. %w", err)
 	}
 
-	if len(clientErrors.DescriptionErrors) > 0 {
+	if list == nil || list.UserID != userID {
+		clientErrors.ListErrors = append(clientErrors.ListErrors, "cannot add a todo to this list")
+	}
+
+	clientErrors.DescriptionErrors = validateDescription(description)
+
+	parsedDueAt, err := parseDueAt(dueAt, loc)
+	if err != nil {
+		clientErrors.DueAtErrors = append(clientErrors.DueAtErrors, "due date must be a valid date and time")
+	}
+
+	if len(clientErrors.ListErrors) > 0 || len(clientErrors.DescriptionErrors) > 0 || len(clientErrors.DueAtErrors) > 0 {
 		return nil, &clientErrors, nil
 	}
 
 	sanitizedDescription := html.EscapeString(description)
 
-	todo := models.NewTodo(userID, sanitizedDescription)
+	todo := models.NewTodo(userID, listID, sanitizedDescription)
+	todo.DueAt = parsedDueAt
 
 	lastInsertedTodoID, err := s.repo.CreateTodo(&todo)
 	if err != nil {
@@ -31,33 +87,24 @@ func (s *Service) CreateTodo(userID, description string) (*models.Todo, *models.
 	return &todo, nil, nil
 }
 
-func (s *Service) GetUserTodoList(userID string) ([]*models.Todo, error) {
-	user := s.caches.UserCache.GetUserByID(userID)
-
-	if user == nil {
-		u, err := s.repo.GetUserByID(userID)
-		if err != nil {
-			return nil, fmt.Errorf("Could not get user by ID. %w", err)
-		}
-		user = u
-	}
-
-	// Redundant?
-	if user == nil {
-		return nil, fmt.Errorf("Could not get user by id.")
+// GetUserTodoList returns the todos on one of the user's lists. Todos
+// archived because they are over the user's plan limit come last.
+func (s *Service) GetUserTodoList(userID string, listID int) ([]*models.Todo, clientError, error) {
+	list, err := s.repo.GetListByID(listID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get list by ID. %w", err)
 	}
 
-	limit := DefaultLimit
-	if user.IsPaidUser {
-		limit = 0
+	if list == nil || list.UserID != userID {
+		return nil, NewClientError("The list you requested does not exist", http.StatusNotFound), nil
 	}
 
-	todoList, err := s.repo.GetTodosByUserID(userID, limit)
+	todoList, err := s.repo.GetTodosByListID(listID)
 	if err != nil {
-		return nil, fmt.Errorf("Could not get user by ID. %w", err)
+		return nil, nil, fmt.Errorf("Could not get todos for list. %w", err)
 	}
 
-	return todoList, nil
+	return todoList, nil, nil
 }
 
 func (s *Service) GetTodoByID(ID int, userID string) (*models.Todo, clientError, error) {
@@ -68,6 +115,10 @@ func (s *Service) GetTodoByID(ID int, userID string) (*models.Todo, clientError,
 	}
 
 	// client error
+	if todo == nil {
+		return nil, NewClientError("The todo you requested does not exist", http.StatusNotFound), nil
+	}
+
 	if todo.UserID != userID {
 		return nil, NewClientError("User not authorized", http.StatusUnauthorized), nil
 	}
@@ -75,8 +126,9 @@ func (s *Service) GetTodoByID(ID int, userID string) (*models.Todo, clientError,
 	return todo, nil, nil
 }
 
-func (s *Service) DeleteTodo(todoID int, userID string) (clientError, error) {
-	// TODO run auth check
+// DeleteTodo deletes one of the user's todos. Users with the
+// todos:delete:any permission may delete anyone's todo.
+func (s *Service) DeleteTodo(todoID int, user *models.User) (clientError, error) {
 	todo, err := s.repo.GetTodoByID(todoID)
 	if err != nil {
 		return nil, fmt.Errorf("Could not get todo by ID. %w", err)
@@ -87,8 +139,8 @@ func (s *Service) DeleteTodo(todoID int, userID string) (clientError, error) {
 		return clientError, nil
 	}
 
-	if todo.UserID != userID {
-		clientError := NewClientError("You do not have permission to delete this todo", http.StatusUnauthorized)
+	if todo.UserID != user.ID && !user.HasPermission(models.PermissionTodosDeleteAny) {
+		clientError := NewClientError("You do not have permission to delete this todo", http.StatusForbidden)
 		return clientError, nil
 	}
 
@@ -135,6 +187,10 @@ func (s *Service) UpdateTodoStatus(userID string, todoID int) (*models.Todo, cli
 		return nil, clientError, nil
 	}
 
+	if todo.IsArchived() {
+		return nil, archivedTodoError(), nil
+	}
+
 	updatedStatus := !todo.IsComplete
 
 	todo.IsComplete = updatedStatus
@@ -147,6 +203,174 @@ func (s *Service) UpdateTodoStatus(userID string, todoID int) (*models.Todo, cli
 	return todo, nil, nil
 }
 
+// UpdateTodoDescription replaces the description of one of the user's
+// todos, applying the same validation as CreateTodo.
+func (s *Service) UpdateTodoDescription(userID string, todoID int, description string) (*models.Todo, *models.UpdateTodoClientErrors, error) {
+	clientErrors := models.UpdateTodoClientErrors{}
+
+	todo, err := s.repo.GetTodoByID(todoID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get todo by ID. %w", err)
+	}
+
+	if todo == nil {
+		clientErrors.TodoErrors = append(clientErrors.TodoErrors, "The todo you are updating does not exist")
+	} else if todo.UserID != userID {
+		clientErrors.TodoErrors = append(clientErrors.TodoErrors, "You are not authorized to update this todo")
+	} else if todo.IsArchived() {
+		clientErrors.TodoErrors = append(clientErrors.TodoErrors, archivedTodoError().Message)
+	}
+
+	if len(clientErrors.TodoErrors) > 0 {
+		return nil, &clientErrors, nil
+	}
+
+	clientErrors.DescriptionErrors = validateDescription(description)
+	if len(clientErrors.DescriptionErrors) > 0 {
+		return todo, &clientErrors, nil
+	}
+
+	todo.Description = html.EscapeString(description)
+
+	err = s.repo.UpdateTodo(*todo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not update todo description. %w", err)
+	}
+
+	return todo, nil, nil
+}
+
 func (s *Service) DeleteUnattributedTodos() error {
 	return s.repo.DeleteUnattributedTodos()
 }
+
+// MoveTodo moves a todo onto another of the same user's lists.
+func (s *Service) MoveTodo(userID string, todoID int, listID int) (*models.Todo, clientError, error) {
+	todo, err := s.repo.GetTodoByID(todoID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get todo by ID. %w", err)
+	}
+
+	if todo == nil || todo.UserID != userID {
+		return nil, NewClientError("You are not authorized to move this todo", http.StatusUnauthorized), nil
+	}
+
+	if todo.IsArchived() {
+		return nil, archivedTodoError(), nil
+	}
+
+	list, err := s.repo.GetListByID(listID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get list by ID. %w", err)
+	}
+
+	if list == nil || list.UserID != userID {
+		return nil, NewClientError("You can only move todos to your own lists", http.StatusBadRequest), nil
+	}
+
+	todo.ListID = list.ID
+
+	err = s.repo.UpdateTodo(*todo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not move todo. %w", err)
+	}
+
+	return todo, nil, nil
+}
+
+// ListTodos returns a page of the user's todos, optionally only those on
+// listID, along with the total number of todos. Pages start at 1.
+func (s *Service) ListTodos(userID string, listID int, page int, perPage int) ([]*models.Todo, int, clientError, error) {
+	if listID != 0 {
+		list, err := s.repo.GetListByID(listID)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("Could not get list by ID. %w", err)
+		}
+		if list == nil || list.UserID != userID {
+			return nil, 0, NewClientError("The list you requested does not exist", http.StatusNotFound), nil
+		}
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	total, err := s.repo.CountTodos(userID, listID)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("Could not count todos. %w", err)
+	}
+
+	todos, err := s.repo.GetTodosPage(userID, listID, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("Could not get todos. %w", err)
+	}
+
+	return todos, total, nil, nil
+}
+
+// UpdateTodo applies every change in update to one of the user's todos.
+// Nothing is saved unless all of the changes are valid.
+func (s *Service) UpdateTodo(userID string, todoID int, update models.TodoUpdate, loc *time.Location) (*models.Todo, *models.UpdateTodoClientErrors, error) {
+	clientErrors := models.UpdateTodoClientErrors{}
+
+	todo, err := s.repo.GetTodoByID(todoID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get todo by ID. %w", err)
+	}
+
+	if todo == nil || todo.UserID != userID {
+		clientErrors.TodoErrors = append(clientErrors.TodoErrors, "The todo you are updating does not exist")
+		return nil, &clientErrors, nil
+	}
+
+	if todo.IsArchived() {
+		clientErrors.TodoErrors = append(clientErrors.TodoErrors, archivedTodoError().Message)
+		return todo, &clientErrors, nil
+	}
+
+	if update.Description != nil {
+		clientErrors.DescriptionErrors = validateDescription(*update.Description)
+	}
+
+	if update.ListID != nil {
+		list, err := s.repo.GetListByID(*update.ListID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not get list by ID. %w", err)
+		}
+		if list == nil || list.UserID != userID {
+			clientErrors.ListErrors = append(clientErrors.ListErrors, "You can only move todos to your own lists")
+		}
+	}
+
+	var dueAt *time.Time
+	if update.DueAt != nil {
+		dueAt, err = parseDueAt(*update.DueAt, loc)
+		if err != nil {
+			clientErrors.DueAtErrors = append(clientErrors.DueAtErrors, "due date must be a valid date and time")
+		}
+	}
+
+	if len(clientErrors.DescriptionErrors) > 0 || len(clientErrors.ListErrors) > 0 || len(clientErrors.DueAtErrors) > 0 {
+		return todo, &clientErrors, nil
+	}
+
+	if update.Description != nil {
+		todo.Description = html.EscapeString(*update.Description)
+	}
+	if update.IsComplete != nil {
+		todo.IsComplete = *update.IsComplete
+	}
+	if update.ListID != nil {
+		todo.ListID = *update.ListID
+	}
+	if update.DueAt != nil {
+		todo.DueAt = dueAt
+	}
+
+	err = s.repo.UpdateTodo(*todo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not update todo. %w", err)
+	}
+
+	return todo, nil, nil
+}
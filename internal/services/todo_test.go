@@ -1 +1,259 @@
 package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseDueAt(t *testing.T) {
+	dublin, err := time.LoadLocation("Europe/Dublin")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+
+	dueAt, err := parseDueAt("2024-07-01T09:30", dublin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Dublin is UTC+1 in July
+	if want := time.Date(2024, 7, 1, 8, 30, 0, 0, time.UTC); !dueAt.Equal(want) || dueAt.Location() != time.UTC {
+		t.Errorf("expected %v, got %v", want, dueAt)
+	}
+
+	dueAt, err = parseDueAt("2024-07-01", dublin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 7, 1, 22, 59, 0, 0, time.UTC); !dueAt.Equal(want) {
+		t.Errorf("expected date only to be due at end of day, got %v", dueAt)
+	}
+
+	// RFC 3339 keeps its own offset regardless of the user's zone
+	dueAt, err = parseDueAt("2024-07-01T09:30:00-04:00", dublin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 7, 1, 13, 30, 0, 0, time.UTC); !dueAt.Equal(want) {
+		t.Errorf("expected %v, got %v", want, dueAt)
+	}
+
+	dueAt, err = parseDueAt("", dublin)
+	if err != nil || dueAt != nil {
+		t.Errorf("expected no due date, got %v (%v)", dueAt, err)
+	}
+
+	if _, err := parseDueAt("tomorrow", dublin); err == nil {
+		t.Error("expected an error for an invalid due date")
+	}
+}
+
+func TestUpdateTodoDescription(t *testing.T) {
+	s := newTestService(t)
+	owner := newTestUser(t, s, "owner", false)
+	other := newTestUser(t, s, "other", false)
+
+	lists, err := s.GetUserLists(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	todo, _, err := s.CreateTodo(owner.ID, lists[0].ID, "before", "", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, clientErrors, err := s.UpdateTodoDescription(other.ID, todo.ID, "stolen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientErrors == nil || len(clientErrors.TodoErrors) == 0 {
+		t.Error("expected another user's edit to be rejected")
+	}
+
+	_, clientErrors, err = s.UpdateTodoDescription(owner.ID, todo.ID, "   ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientErrors == nil || len(clientErrors.DescriptionErrors) == 0 {
+		t.Error("expected an empty description to be rejected")
+	}
+
+	updated, clientErrors, err := s.UpdateTodoDescription(owner.ID, todo.ID, "after")
+	if err != nil || clientErrors != nil {
+		t.Fatalf("expected update to succeed, got %v %v", clientErrors, err)
+	}
+	if updated.Description != "after" {
+		t.Errorf("expected description to be updated, got %q", updated.Description)
+	}
+}
+
+func TestUpdateTodoIsAllOrNothing(t *testing.T) {
+	s := newTestService(t)
+	owner := newTestUser(t, s, "owner", true)
+
+	lists, err := s.GetUserLists(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	todo, _, err := s.CreateTodo(owner.ID, lists[0].ID, "before", "2030-01-01", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	description := "after"
+	badDueAt := "not a date"
+	_, clientErrors, err := s.UpdateTodo(owner.ID, todo.ID, models.TodoUpdate{Description: &description, DueAt: &badDueAt}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientErrors == nil || len(clientErrors.DueAtErrors) == 0 {
+		t.Fatal("expected an invalid due date to be rejected")
+	}
+
+	unchanged, _, err := s.GetTodoByID(todo.ID, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unchanged.Description != "before" {
+		t.Errorf("expected nothing to be saved, description is %q", unchanged.Description)
+	}
+
+	isComplete := true
+	noDueAt := ""
+	updated, clientErrors, err := s.UpdateTodo(owner.ID, todo.ID, models.TodoUpdate{Description: &description, IsComplete: &isComplete, DueAt: &noDueAt}, time.UTC)
+	if err != nil || clientErrors != nil {
+		t.Fatalf("expected update to succeed, got %v %v", clientErrors, err)
+	}
+	if updated.Description != "after" || !updated.IsComplete || updated.DueAt != nil {
+		t.Errorf("expected every change to be applied, got %+v", updated)
+	}
+}
+
+func TestGetUserTodoListChecksTheOwner(t *testing.T) {
+	s := newTestService(t)
+	owner := newTestUser(t, s, "owner", false)
+	other := newTestUser(t, s, "other", false)
+
+	lists, err := s.GetUserLists(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.CreateTodo(owner.ID, lists[0].ID, "private", "", time.UTC); err != nil {
+		t.Fatal(err)
+	}
+
+	todos, clientError, err := s.GetUserTodoList(owner.ID, lists[0].ID)
+	if err != nil || clientError != nil || len(todos) != 1 {
+		t.Fatalf("expected the owner's todo, got %d %v %v", len(todos), clientError, err)
+	}
+
+	todos, clientError, err = s.GetUserTodoList(other.ID, lists[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil || len(todos) != 0 {
+		t.Error("expected another user's list to be rejected")
+	}
+}
+
+func TestListTodosPaginates(t *testing.T) {
+	s := newTestService(t)
+	owner := newTestUser(t, s, "owner", true)
+
+	lists, err := s.GetUserLists(owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, _, err := s.CreateTodo(owner.ID, lists[0].ID, "todo", "", time.UTC); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todos, total, clientError, err := s.ListTodos(owner.ID, 0, 3, 2)
+	if err != nil || clientError != nil {
+		t.Fatalf("expected todos, got %v %v", clientError, err)
+	}
+	if total != 5 || len(todos) != 1 {
+		t.Errorf("expected the last of 5 todos on page 3, got %d of %d", len(todos), total)
+	}
+
+	other := newTestUser(t, s, "other", true)
+	_, _, clientError, err = s.ListTodos(other.ID, lists[0].ID, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil {
+		t.Error("expected another user's list to be rejected")
+	}
+}
+
+func TestDowngradeArchivesTodosOverTheLimit(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	limit := s.Plans().Free().Entitlements.MaxTodos
+	now := time.Now()
+
+	receiveTestEvent(t, s, "evt_1", "customer.subscription.created", now.Add(-time.Hour), newTestSubscription("sub_1", "cus_1", user.ID, "active"))
+
+	lists, err := s.GetUserLists(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todoIDs := []int{}
+	for i := 0; i < limit+2; i++ {
+		todo, _, err := s.CreateTodo(user.ID, lists[0].ID, "todo", "", time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		todoIDs = append(todoIDs, todo.ID)
+	}
+	// completed todos are the first to be archived
+	if _, _, err := s.UpdateTodoStatus(user.ID, todoIDs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	expectArchived := func(want ...int) {
+		t.Helper()
+		archived := []int{}
+		todos, err := s.GetAllUserTodos(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, todo := range todos {
+			if todo.IsArchived() {
+				archived = append(archived, todo.ID)
+			}
+		}
+		if len(todos) != limit+2 || fmt.Sprint(archived) != fmt.Sprint(want) {
+			t.Errorf("expected todos %v of %d to be archived, got %v of %d", want, limit+2, archived, len(todos))
+		}
+	}
+
+	receiveTestEvent(t, s, "evt_2", "customer.subscription.deleted", now.Add(-time.Minute), newTestSubscription("sub_1", "cus_1", user.ID, "canceled"))
+	expectArchived(todoIDs[0], todoIDs[limit+1])
+
+	_, clientError, err := s.UpdateTodoStatus(user.ID, todoIDs[0])
+	if err != nil || clientError == nil || clientError.Code != http.StatusForbidden {
+		t.Errorf("expected archived todos to be read only, got %v %v", clientError, err)
+	}
+
+	clientError, err = s.ChooseActiveTodos(user, todoIDs)
+	if err != nil || clientError == nil {
+		t.Errorf("expected choosing more todos than the limit to fail, got %v %v", clientError, err)
+	}
+
+	clientError, err = s.ChooseActiveTodos(user, todoIDs[:limit])
+	if err != nil || clientError != nil {
+		t.Fatal(clientError, err)
+	}
+	expectArchived(todoIDs[limit], todoIDs[limit+1])
+
+	receiveTestEvent(t, s, "evt_3", "customer.subscription.created", now, newTestSubscription("sub_2", "cus_1", user.ID, "active"))
+	expectArchived()
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// TwoFactorIssuer names the account in authenticator apps.
+const TwoFactorIssuer = "go-todo"
+
+// RecoveryCodeCount is how many recovery codes a user is given when they
+// enable two factor.
+const RecoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// recovery codes look like abcde-fghij
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// recovery codes are compared without case, spaces or dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
+
+func totpURI(email, secret string) string {
+	label := url.PathEscape(TwoFactorIssuer + ":" + email)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", TwoFactorIssuer)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// BeginTwoFactorEnrollment returns the secret the user should add to their
+// authenticator app, creating one unless enrollment was already started.
+func (s *Service) BeginTwoFactorEnrollment(user *models.User) (*models.TwoFactorEnrollment, clientError, error) {
+	if user.TwoFactorEnabled() {
+		return nil, NewClientError("Two factor authentication is already enabled", http.StatusBadRequest), nil
+	}
+
+	secret, err := s.repo.GetTOTPSecret(user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get totp secret. %w", err)
+	}
+
+	if secret == "" {
+		secret, err = generateTOTPSecret()
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not generate totp secret. %w", err)
+		}
+
+		err = s.repo.SetPendingTOTPSecret(user.ID, secret)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not save totp secret. %w", err)
+		}
+	}
+
+	uri := totpURI(user.Email, secret)
+	qrCode, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not render totp qr code. %w", err)
+	}
+
+	return &models.TwoFactorEnrollment{Secret: secret, URI: uri, QRCode: qrCode}, nil, nil
+}
+
+// EnableTwoFactor finishes enrollment once the user proves their app is
+// set up with a first code. It returns their recovery codes, which are not
+// stored and cannot be shown again.
+func (s *Service) EnableTwoFactor(user *models.User, code string) ([]string, clientError, error) {
+	if user.TwoFactorEnabled() {
+		return nil, NewClientError("Two factor authentication is already enabled", http.StatusBadRequest), nil
+	}
+
+	secret, err := s.repo.GetTOTPSecret(user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not get totp secret. %w", err)
+	}
+
+	if secret == "" {
+		return nil, NewClientError("Start setting up two factor authentication first", http.StatusBadRequest), nil
+	}
+
+	now := time.Now()
+	step, ok := matchTOTP(secret, code, now)
+	if !ok {
+		return nil, NewClientError("That code is not right, check your authenticator app and try again", http.StatusBadRequest), nil
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		codes[i], err = generateRecoveryCode()
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not generate recovery code. %w", err)
+		}
+		hashes[i] = hashToken(codes[i])
+	}
+
+	err = s.repo.EnableTwoFactor(user.ID, step, hashes, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not enable two factor. %w", err)
+	}
+
+	return codes, nil, nil
+}
+
+// VerifyTwoFactor checks a code from the user's authenticator app, or one
+// of their recovery codes, using it up.
+func (s *Service) VerifyTwoFactor(userID, code string) (bool, error) {
+	secret, err := s.repo.GetTOTPSecret(userID)
+	if err != nil {
+		return false, fmt.Errorf("Could not get totp secret. %w", err)
+	}
+
+	if step, ok := matchTOTP(secret, code, time.Now()); ok {
+		used, err := s.repo.UseTOTPStep(userID, step)
+		if err != nil {
+			return false, fmt.Errorf("Could not use totp code. %w", err)
+		}
+		return used, nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(userID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return false, fmt.Errorf("Could not use recovery code. %w", err)
+	}
+	return used, nil
+}
+
+// DisableTwoFactor turns two factor off once the user proves they still
+// have their authenticator or a recovery code.
+func (s *Service) DisableTwoFactor(user *models.User, code string) (clientError, error) {
+	if !user.TwoFactorEnabled() {
+		return NewClientError("Two factor authentication is not enabled", http.StatusBadRequest), nil
+	}
+
+	ok, err := s.VerifyTwoFactor(user.ID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return NewClientError("That code is not right, check your authenticator app and try again", http.StatusBadRequest), nil
+	}
+
+	return nil, s.ResetTwoFactor(user.ID)
+}
+
+// ResetTwoFactor turns two factor off without a code, for users who have
+// lost their authenticator and recovery codes.
+func (s *Service) ResetTwoFactor(userID string) error {
+	err := s.repo.DisableTwoFactor(userID)
+	if err != nil {
+		return fmt.Errorf("Could not disable two factor. %w", err)
+	}
+	return nil
+}
+
+func (s *Service) CountUnusedRecoveryCodes(userID string) (int, error) {
+	count, err := s.repo.CountUnusedRecoveryCodes(userID)
+	if err != nil {
+		return 0, fmt.Errorf("Could not count recovery codes. %w", err)
+	}
+	return count, nil
+}
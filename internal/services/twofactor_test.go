@@ -0,0 +1,105 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, test := range tests {
+		if code := totpCode(key, totpStep(time.Unix(test.unix, 0))); code != test.code {
+			t.Errorf("at %d expected %s, got %s", test.unix, test.code, code)
+		}
+	}
+}
+
+func currentTOTPCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totpCode(key, totpStep(at))
+}
+
+func TestTwoFactorLifecycle(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "careful", false)
+
+	enrollment, clientError, err := s.BeginTwoFactorEnrollment(user)
+	if err != nil || clientError != nil {
+		t.Fatalf("expected enrollment to start, got %v %v", clientError, err)
+	}
+	if len(enrollment.QRCode) == 0 {
+		t.Error("expected a QR code")
+	}
+
+	again, _, err := s.BeginTwoFactorEnrollment(user)
+	if err != nil || again.Secret != enrollment.Secret {
+		t.Fatalf("expected enrollment to keep its secret, got %v %v", again, err)
+	}
+
+	_, clientError, err = s.EnableTwoFactor(user, "12345x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil {
+		t.Fatal("expected a wrong code to be rejected")
+	}
+
+	// the code from the previous step is still accepted, leaving the
+	// current one for logging in below
+	recoveryCodes, clientError, err := s.EnableTwoFactor(user, currentTOTPCode(t, enrollment.Secret, time.Now().Add(-totpPeriod)))
+	if err != nil || clientError != nil {
+		t.Fatalf("expected two factor to be enabled, got %v %v", clientError, err)
+	}
+	if len(recoveryCodes) != RecoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", RecoveryCodeCount, len(recoveryCodes))
+	}
+
+	user, err = s.GetUserByID(user.ID)
+	if err != nil || !user.TwoFactorEnabled() {
+		t.Fatalf("expected two factor to be enabled, got %v", err)
+	}
+
+	code := currentTOTPCode(t, enrollment.Secret, time.Now())
+	if ok, err := s.VerifyTwoFactor(user.ID, code); err != nil || !ok {
+		t.Fatalf("expected the current code to be accepted, got %v %v", ok, err)
+	}
+	if ok, _ := s.VerifyTwoFactor(user.ID, code); ok {
+		t.Error("expected a code to only work once")
+	}
+
+	if ok, err := s.VerifyTwoFactor(user.ID, " "+recoveryCodes[0]+" "); err != nil || !ok {
+		t.Fatalf("expected a recovery code to be accepted, got %v %v", ok, err)
+	}
+	if ok, _ := s.VerifyTwoFactor(user.ID, recoveryCodes[0]); ok {
+		t.Error("expected a recovery code to only work once")
+	}
+
+	count, err := s.CountUnusedRecoveryCodes(user.ID)
+	if err != nil || count != RecoveryCodeCount-1 {
+		t.Errorf("expected %d unused recovery codes, got %d %v", RecoveryCodeCount-1, count, err)
+	}
+
+	clientError, err = s.DisableTwoFactor(user, recoveryCodes[1])
+	if err != nil || clientError != nil {
+		t.Fatalf("expected two factor to be disabled, got %v %v", clientError, err)
+	}
+
+	user, err = s.GetUserByID(user.ID)
+	if err != nil || user.TwoFactorEnabled() {
+		t.Fatalf("expected two factor to be disabled, got %v", err)
+	}
+}
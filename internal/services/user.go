@@ -6,57 +6,104 @@ import (
 	"html"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// bcrypt work factor for stored passwords
+const passwordHashCost = 14
+
 func isValidEmail(email string) bool {
 	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	return emailRegex.MatchString(email)
 }
 
+func isValidTimeZone(timeZone string) bool {
+	if timeZone == "" {
+		return false
+	}
+	_, err := time.LoadLocation(timeZone)
+	return err == nil
+}
+
 type userLoginErrors struct {
 	EmailErrors    []string
 	PasswordErrors []string
+	// LockedFor is how long logins are locked for, either because they
+	// already were or because this attempt was one too many
+	LockedFor time.Duration
 }
 
-func (s *Service) Login(email string, password string) (*models.User, *userLoginErrors, error) {
+// Login checks the user's email and password. Failed attempts are
+// throttled per email and per ip, and fail the same way whether or not
+// the account exists.
+func (s *Service) Login(email, password, ip string) (*models.User, *userLoginErrors, error) {
 	var user models.User
 	userLoginErrors := userLoginErrors{
 		PasswordErrors: []string{},
 		EmailErrors:    []string{},
 	}
 
+	email = strings.TrimSpace(email)
+
 	isValidEmail := isValidEmail(email)
 	if !isValidEmail {
 		userLoginErrors.EmailErrors = append(userLoginErrors.EmailErrors, "You've provided an invalid email.")
+		return nil, &userLoginErrors, nil
 	}
 
-	userRecord, err := s.repo.GetUserByEmail(email)
+	now := time.Now()
+	retryAfter, err := s.loginRetryAfter(email, ip, now)
 	if err != nil {
-		return nil, nil, fmt.Errorf("Could not get user by email. %w", err)
-	}
-
-	if userRecord == nil {
-		userLoginErrors.EmailErrors = append(userLoginErrors.EmailErrors, "Could not find user with that email")
+		return nil, nil, err
 	}
 
-	if len(userLoginErrors.EmailErrors) > 0 {
+	if retryAfter > 0 {
+		userLoginErrors.PasswordErrors = append(userLoginErrors.PasswordErrors, loginLockedMessage)
+		userLoginErrors.LockedFor = retryAfter
 		return nil, &userLoginErrors, nil
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(userRecord.Password), []byte(password))
-
+	userRecord, err := s.repo.GetUserByEmail(email)
 	if err != nil {
-		userLoginErrors.PasswordErrors = append(userLoginErrors.PasswordErrors, "Incorrect Password")
+		return nil, nil, fmt.Errorf("Could not get user by email. %w", err)
 	}
 
-	if len(userLoginErrors.PasswordErrors) > 0 {
+	if userRecord == nil {
+		compareDummyPassword(password)
+	} else {
+		err = bcrypt.CompareHashAndPassword([]byte(userRecord.Password), []byte(password))
+	}
+
+	if userRecord == nil || err != nil {
+		lockedFor, err := s.recordLoginFailure(email, ip, now)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		message := loginFailedMessage
+		if lockedFor > 0 {
+			message = loginLockedMessage
+		}
+		userLoginErrors.PasswordErrors = append(userLoginErrors.PasswordErrors, message)
+		userLoginErrors.LockedFor = lockedFor
 		return nil, &userLoginErrors, nil
 	}
 
+	// the ip is not cleared so one good account cannot reset an ip's count.
+	// Users with two factor keep theirs until VerifyTwoFactorLogin passes.
+	if userRecord.TwoFactorEnabledAt == nil {
+		err = s.repo.ClearLoginThrottle(models.LoginThrottleEmail, strings.ToLower(email))
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not clear login throttle. %w", err)
+		}
+	}
+
 	user = models.NewUser(userRecord.ID, userRecord.Name, userRecord.Email, "", userRecord.IsPaidUser, "")
+	user.TimeZone = userRecord.TimeZone
+	user.TwoFactorEnabledAt = userRecord.TwoFactorEnabledAt
 
 	return &user, nil, nil
 }
@@ -68,7 +115,7 @@ type userSignupErrors struct {
 }
 
 // sign up for a new account
-func (s *Service) NewUser(username, email, password string) (*models.User, *userSignupErrors, error) {
+func (s *Service) NewUser(username, email, password, timeZone string) (*models.User, *userSignupErrors, error) {
 	userSignupErrors := userSignupErrors{[]string{}, []string{}, []string{}}
 
 	// sanitize and  clean username, email & password
@@ -116,12 +163,15 @@ func (s *Service) NewUser(username, email, password string) (*models.User, *user
 		return nil, &userSignupErrors, nil
 	}
 
-	hashedpassword, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	hashedpassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	userToInsert := models.NewUser(id, username, email, string(hashedpassword), false, "")
+	if isValidTimeZone(timeZone) {
+		userToInsert.TimeZone = timeZone
+	}
 	err = s.repo.SaveUser(userToInsert)
 	if err != nil {
 		return nil, nil, err
@@ -135,21 +185,11 @@ func (s *Service) NewUser(username, email, password string) (*models.User, *user
 		userToInsert.IsPaidUser,
 		"",
 	)
+	user.TimeZone = userToInsert.TimeZone
 
 	return &user, nil, nil
 }
 
-func (s *Service) UserCanCreateNewTodo(user *models.User, list []*models.Todo) (bool, error) {
-	userIsPaidUser, internalErr := s.UserIsPaidUser(user.ID)
-	if internalErr != nil {
-		return false, fmt.Errorf("Could not determine payment status for user. %w", internalErr)
-	}
-
-	canCreateNewTodo := (!userIsPaidUser && len(list) < DefaultLimit) || userIsPaidUser
-
-	return canCreateNewTodo, nil
-}
-
 func (s *Service) AddStripeIDToUser(userID, stripeID string) error {
 	internalErr := s.repo.AddStripeIDToUser(userID, stripeID)
 	if internalErr != nil {
@@ -183,15 +223,6 @@ func (s *Service) GetUserByStripeID(customerStripeID string) (*models.User, erro
 
 }
 
-func (s *Service) UpdateUserPaymentStatus(userID string, isPaidUser bool) error {
-	internalErr := s.repo.UpdateUserPaymentStatus(userID, isPaidUser)
-	if internalErr != nil {
-		return fmt.Errorf("Could not update user payment status. %w", internalErr)
-	}
-
-	return nil
-}
-
 func (s *Service) UserIsPaidUser(userID string) (bool, error) {
 	isPaidUser, internalErr := s.repo.UserIsPaidUser(userID)
 	if internalErr != nil {
@@ -199,3 +230,16 @@ func (s *Service) UserIsPaidUser(userID string) (bool, error) {
 	}
 	return isPaidUser, nil
 }
+
+// UpdateUserTimeZone stores the IANA time zone reported by the user's
+// browser. Unknown zones are ignored so the user keeps their previous one.
+func (s *Service) UpdateUserTimeZone(userID, timeZone string) error {
+	if !isValidTimeZone(timeZone) {
+		return nil
+	}
+	internalErr := s.repo.UpdateUserTimeZone(userID, timeZone)
+	if internalErr != nil {
+		return fmt.Errorf("Could not update user time zone. %w", internalErr)
+	}
+	return nil
+}
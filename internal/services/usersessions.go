@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"go-todo/internal/models"
+	"net/http"
+	"time"
+)
+
+// last seen times are only written once per interval so browsing does not
+// cause a write on every request
+const userSessionLastSeenInterval = time.Minute
+
+const maxUserAgentLength = 255
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+// StartUserSession records a session the user just logged in with, and
+// forgets their sessions that have expired.
+func (s *Service) StartUserSession(session models.UserSession) error {
+	session.UserAgent = truncateUserAgent(session.UserAgent)
+
+	err := s.repo.DeleteExpiredUserSessions(session.UserID, time.Now())
+	if err != nil {
+		return fmt.Errorf("Could not delete expired user sessions. %w", err)
+	}
+
+	err = s.repo.SaveUserSession(&session)
+	if err != nil {
+		return fmt.Errorf("Could not save user session. %w", err)
+	}
+	return nil
+}
+
+// TouchUserSession records a request made with a logged in session. It
+// returns false when the session has no record, because it was signed out
+// or started before sessions were recorded, and should not be trusted.
+// Some session stores can not delete sessions, so the record is what
+// keeps a signed out session signed out.
+func (s *Service) TouchUserSession(sessionID, userID, ip string, expiresAt time.Time) (bool, error) {
+	session, err := s.repo.GetUserSession(sessionID)
+	if err != nil {
+		return false, fmt.Errorf("Could not get user session. %w", err)
+	}
+
+	if session == nil || session.UserID != userID {
+		return false, nil
+	}
+
+	now := time.Now().UTC()
+	if now.Sub(session.LastSeenAt) < userSessionLastSeenInterval && session.IP == ip && !expiresAt.After(session.ExpiresAt) {
+		return true, nil
+	}
+
+	err = s.repo.UpdateUserSessionLastSeen(sessionID, ip, now, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("Could not update user session last seen. %w", err)
+	}
+	return true, nil
+}
+
+func (s *Service) GetActiveUserSessions(userID string) ([]*models.UserSession, error) {
+	sessions, err := s.repo.GetActiveUserSessions(userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("Could not get user sessions. %w", err)
+	}
+	return sessions, nil
+}
+
+// EndUserSession forgets one of the user's sessions. The caller deletes the
+// session itself from the session store.
+func (s *Service) EndUserSession(userID, sessionID string) (clientError, error) {
+	deleted, err := s.repo.DeleteUserSession(userID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not delete user session. %w", err)
+	}
+
+	if !deleted {
+		return NewClientError("The session you tried to sign out does not exist", http.StatusNotFound), nil
+	}
+	return nil, nil
+}
+
+// EndAllUserSessions forgets all of the user's sessions.
+func (s *Service) EndAllUserSessions(userID string) error {
+	err := s.repo.DeleteUserSessions(userID)
+	if err != nil {
+		return fmt.Errorf("Could not delete user sessions. %w", err)
+	}
+	return nil
+}
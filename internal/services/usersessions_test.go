@@ -0,0 +1,116 @@
+package services
+
+import (
+	"go-todo/internal/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUserSessionLifecycle(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+	other := newTestUser(t, s, "other", false)
+
+	now := time.Now().UTC()
+	err := s.StartUserSession(models.NewUserSession("1", user.ID, "10.0.0.1", "Firefox", now, now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StartUserSession(models.NewUserSession("2", user.ID, "10.0.0.2", "Chrome", now, now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an expired session is not listed, and is forgotten on the next login
+	err = s.StartUserSession(models.NewUserSession("3", user.ID, "10.0.0.3", "Safari", now.Add(-2*time.Hour), now.Add(-time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := s.GetActiveUserSessions(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 active sessions, got %d", len(sessions))
+	}
+
+	// sessions without a record, or recorded for someone else, are refused
+	for _, test := range []struct{ sessionID, userID string }{{"9", user.ID}, {"2", other.ID}} {
+		active, err := s.TouchUserSession(test.sessionID, test.userID, "10.0.0.4", now.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active {
+			t.Errorf("expected session %s for %s to be refused", test.sessionID, test.userID)
+		}
+	}
+
+	clientError, err := s.EndUserSession(other.ID, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError == nil || clientError.Code != http.StatusNotFound {
+		t.Fatalf("expected users to only end their own sessions, got %v", clientError)
+	}
+
+	clientError, err = s.EndUserSession(user.ID, "1")
+	if err != nil || clientError != nil {
+		t.Fatalf("expected session to be ended, got %v %v", clientError, err)
+	}
+
+	err = s.EndAllUserSessions(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err = s.GetActiveUserSessions(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("expected no sessions after ending them all, got %d", len(sessions))
+	}
+}
+
+func TestTouchUserSessionOnlyWritesOncePerInterval(t *testing.T) {
+	s := newTestService(t)
+	user := newTestUser(t, s, "user", false)
+
+	now := time.Now().UTC()
+	err := s.StartUserSession(models.NewUserSession("1", user.ID, "10.0.0.1", "Firefox", now, now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := s.repo.GetUserSession("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := s.TouchUserSession("1", user.ID, "10.0.0.1", before.ExpiresAt)
+	if err != nil || !active {
+		t.Fatalf("expected session to be active, got %v %v", active, err)
+	}
+	after, err := s.repo.GetUserSession("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.LastSeenAt.Equal(before.LastSeenAt) {
+		t.Errorf("expected last seen to be left alone, was %s now %s", before.LastSeenAt, after.LastSeenAt)
+	}
+
+	// moving network is recorded straight away
+	active, err = s.TouchUserSession("1", user.ID, "10.0.0.9", before.ExpiresAt)
+	if err != nil || !active {
+		t.Fatalf("expected session to be active, got %v %v", active, err)
+	}
+	after, err = s.repo.GetUserSession("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.IP != "10.0.0.9" {
+		t.Errorf("expected ip to be updated, got %s", after.IP)
+	}
+}
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"bytes"
+	"go-todo/internal/billing"
+	"go-todo/internal/config"
+	database "go-todo/internal/db"
+	"go-todo/internal/handlers"
+	"go-todo/internal/logger"
+	"go-todo/internal/mailer"
+	"go-todo/internal/migrations"
+	"go-todo/internal/models"
+	"go-todo/internal/repositories"
+	"go-todo/internal/router"
+	"go-todo/internal/server/renderer"
+	"go-todo/internal/server/sessionstore"
+	"go-todo/internal/services"
+	"html/template"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestApp serves the whole app, billing through fake, and returns a
+// client that keeps cookies and does not follow redirects.
+func newTestApp(t *testing.T, fake *billing.Fake) (*httptest.Server, *http.Client, *services.Service) {
+	db, err := database.ConnectMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if !database.SupportsFTS5(db) {
+		t.Fatal("sqlite3 was built without FTS5, run tests with -tags sqlite_fts5")
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Defaults()
+	cfg.Domain = "http://localhost:8080"
+	cfg.SecretKey = "secret"
+	cfg.Sessions.Store = sessionstore.BackendMemory
+	cfg.UnverifiedEmailRestrictions = nil
+
+	store, err := sessionstore.New(cfg, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := services.NewService(repositories.NewRepository(db), mailer.NewFileMailer(t.TempDir()), fake, cfg)
+	tmpl := template.Must(template.ParseGlob("../web/templates/**/*.html"))
+	handler := handlers.NewHandler(service, store, renderer.NewRenderer(tmpl), logger.NewLogger(1), cfg)
+
+	server := httptest.NewServer(router.NewRouter(handler))
+	t.Cleanup(server.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return server, client, service
+}
+
+func postForm(t *testing.T, client *http.Client, url string, values url.Values) *http.Response {
+	resp, err := client.PostForm(url, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func get(t *testing.T, client *http.Client, url string) *http.Response {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func postWebhook(t *testing.T, serverURL string, payload []byte, signature string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, serverURL+"/webhook", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Stripe-Signature", signature)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestBillingFlow(t *testing.T) {
+	fake := billing.NewFake()
+	server, client, service := newTestApp(t, fake)
+
+	account := url.Values{"username": {"buyer"}, "email": {"buyer@email.com"}, "password": {"password123"}}
+	postForm(t, client, server.URL+"/signup", account)
+	if resp := postForm(t, client, server.URL+"/login", account); resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected login to redirect, got %d", resp.StatusCode)
+	}
+
+	resp := postForm(t, client, server.URL+"/create-checkout-session", url.Values{"plan": {"pro"}})
+	checkoutURL := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusSeeOther || !strings.HasPrefix(checkoutURL, "https://checkout.stripe.test/") {
+		t.Fatalf("expected to be sent to checkout, got %d %s", resp.StatusCode, checkoutURL)
+	}
+	checkoutSessionID := checkoutURL[strings.LastIndex(checkoutURL, "/")+1:]
+
+	checkoutSession, err := fake.PayCheckoutSession(checkoutSessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, signature, err := fake.SubscriptionEvent("customer.subscription.created", checkoutSession.SubscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := postWebhook(t, server.URL, payload, signature); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the webhook to be accepted, got %d", resp.StatusCode)
+	}
+
+	if resp := get(t, client, server.URL+"/success?session_id="+checkoutSessionID); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the success page, got %d", resp.StatusCode)
+	}
+
+	user, err := service.GetUserByEmail("buyer@email.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsPaidUser || user.StripeCustomerID != checkoutSession.CustomerID {
+		t.Fatalf("expected the buyer to be paid, got %+v", user)
+	}
+
+	resp = get(t, client, server.URL+"/manage-subscription")
+	if resp.StatusCode != http.StatusSeeOther || !strings.HasPrefix(resp.Header.Get("Location"), "https://billing.stripe.test/") {
+		t.Fatalf("expected to be sent to the customer portal, got %d %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	_, err = fake.UpdateSubscription(checkoutSession.SubscriptionID, func(s *models.Subscription) { s.Status = models.SubscriptionCanceled })
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, err = fake.SubscriptionEvent("customer.subscription.deleted", checkoutSession.SubscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := postWebhook(t, server.URL, payload, "t=1,v1=forged"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a forged webhook to be refused, got %d", resp.StatusCode)
+	}
+
+	if resp := postWebhook(t, server.URL, payload, signature); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the webhook to be accepted, got %d", resp.StatusCode)
+	}
+
+	// stripe delivers events at least once, the retry is acknowledged too
+	if resp := postWebhook(t, server.URL, payload, signature); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the redelivered webhook to be accepted, got %d", resp.StatusCode)
+	}
+
+	isPaidUser, err := service.UserIsPaidUser(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isPaidUser {
+		t.Error("expected the cancelled subscription to downgrade the buyer")
+	}
+
+	events, err := service.GetStripeEvents("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected both events to be stored once, got %+v", events)
+	}
+	for _, event := range events {
+		if event.Status != models.StripeEventProcessed || event.Attempts != 1 {
+			t.Errorf("expected the event to be processed once, got %+v", event)
+		}
+	}
+}